@@ -0,0 +1,163 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sonatype-nexus-community/the-cla/types"
+)
+
+const commandExport = "export"
+const commandImport = "import"
+
+// runCommand runs a command given on the command line, e.g. "the-cla export -format ndjson", using the database
+// configured for the server
+func runCommand(args []string, stdout io.Writer) error {
+	switch args[0] {
+	case commandExport:
+		return runExportCommand(args[1:], stdout)
+	case commandImport:
+		return runImportCommand(args[1:], stdout)
+	default:
+		return fmt.Errorf("unknown command: %s, the commands are: %s, %s", args[0], commandExport, commandImport)
+	}
+}
+
+// signatureFilterFlags are the flags of a command that filter signatures, like the query parameters of /info/signatures
+type signatureFilterFlags struct {
+	claVersion   *string
+	signedAfter  *string
+	signedBefore *string
+	loginPrefix  *string
+	emailDomain  *string
+}
+
+func addSignatureFilterFlags(flags *flag.FlagSet) *signatureFilterFlags {
+	return &signatureFilterFlags{
+		claVersion:   flags.String("cla-version", "", "only signatures of this CLA version"),
+		signedAfter:  flags.String("signed-after", "", "only signatures made at or after this time, e.g. 2022-01-01T00:00:00Z"),
+		signedBefore: flags.String("signed-before", "", "only signatures made before this time, e.g. 2022-04-01T00:00:00Z"),
+		loginPrefix:  flags.String("login-prefix", "", "only signers whose login starts with this prefix"),
+		emailDomain:  flags.String("email-domain", "", "only signers with an email of this domain"),
+	}
+}
+
+func (f *signatureFilterFlags) filter() (filter types.SignatureFilter, err error) {
+	filter = types.SignatureFilter{
+		CLAVersion:  *f.claVersion,
+		LoginPrefix: *f.loginPrefix,
+		EmailDomain: *f.emailDomain,
+	}
+	if filter.SignedAfter, err = parseOptionalTime(*f.signedAfter); err != nil {
+		return filter, fmt.Errorf("invalid -signed-after: %w", err)
+	}
+	if filter.SignedBefore, err = parseOptionalTime(*f.signedBefore); err != nil {
+		return filter, fmt.Errorf("invalid -signed-before: %w", err)
+	}
+	return
+}
+
+func runExportCommand(args []string, stdout io.Writer) (err error) {
+	flags := flag.NewFlagSet(commandExport, flag.ContinueOnError)
+	format := flags.String("format", exportFormatCSV, "the format of the export, csv or ndjson")
+	columns := flags.String("columns", "", "the comma separated columns to export, defaults to all columns")
+	excludeText := flags.Bool("exclude-text", false, "do not export the CLA text")
+	includeRevoked := flags.Bool("include-revoked", true, "export the revoked signatures too")
+	output := flags.String("output", "", "the file to write the export to, defaults to stdout")
+	filterFlags := addSignatureFilterFlags(flags)
+	if err = flags.Parse(args); err == flag.ErrHelp {
+		// the usage is printed already
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	filter, err := filterFlags.filter()
+	if err != nil {
+		return
+	}
+	filter.IncludeRevoked = *includeRevoked
+	export, err := newSignatureExport(*format, *columns, *excludeText)
+	if err != nil {
+		return
+	}
+
+	if *output == "" {
+		return exportSignatures(export, &filter, stdout)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return
+	}
+	if err = exportSignatures(export, &filter, file); err != nil {
+		_ = file.Close()
+		// do not leave an incomplete export behind
+		_ = os.Remove(*output)
+		return
+	}
+	return file.Close()
+}
+
+func runImportCommand(args []string, stdout io.Writer) (err error) {
+	flags := flag.NewFlagSet(commandImport, flag.ContinueOnError)
+	format := flags.String("format", exportFormatCSV, "the format of the import, csv, json or ndjson")
+	input := flags.String("input", "", "the file to import")
+	dryRun := flags.Bool("dry-run", false, "report what would be imported, without importing it")
+	batchSize := flags.Int("batch-size", defaultImportBatchSize, "the number of signatures imported in each transaction")
+	if err = flags.Parse(args); err == flag.ErrHelp {
+		// the usage is printed already
+		return nil
+	}
+	if err != nil {
+		return
+	}
+
+	if *input == "" {
+		return fmt.Errorf("missing -input")
+	}
+	if *batchSize < 1 || *batchSize > maxImportBatchSize {
+		return fmt.Errorf("invalid -batch-size: %d, must be between 1 and %d", *batchSize, maxImportBatchSize)
+	}
+	file, err := os.Open(*input)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	rows, err := readImport(*format, file)
+	if err != nil {
+		return
+	}
+
+	report, importErr := importSignatures(rows, *batchSize, *dryRun)
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		return
+	}
+	return importErr
+}
@@ -0,0 +1,157 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sonatype-nexus-community/the-cla/db"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+var exportRowColumns = []string{"Id", "LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText", "ClaDocumentId", "ClaTextSha256", "EmailVerified", "IpAddress", "UserAgent", "GitHubUserId", "RevokedAt"}
+
+func setupMockDBCommand(t *testing.T) (mock sqlmock.Sqlmock, closeDbFunc func()) {
+	logger = zaptest.NewLogger(t)
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	postgresDB = dbIF
+	return mock, closeDbFunc
+}
+
+func TestRunCommandUnknown(t *testing.T) {
+	assert.EqualError(t, runCommand([]string{"serve"}, &bytes.Buffer{}), "unknown command: serve, the commands are: export, import")
+}
+
+func TestRunExportCommand(t *testing.T) {
+	mock, closeDbFunc := setupMockDBCommand(t)
+	defer closeDbFunc()
+
+	signedAt := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlExportSignatures)).
+		WithArgs("2.0", signedAt, nil, "", "", false, false).
+		WillReturnRows(sqlmock.NewRows(exportRowColumns).
+			AddRow("id1", "john", "john@doe.tld", "John", signedAt, "2.0", "", "", 0, "", true, "", "", 0, nil).
+			AddRow("id2", "jane", "jane@doe.tld", "Jane", signedAt, "2.0", "", "", 0, "", true, "", "", 0, nil))
+
+	var out bytes.Buffer
+	assert.NoError(t, runCommand([]string{commandExport, "-format", "ndjson", "-columns", "login,claVersion,claText",
+		"-exclude-text", "-include-revoked=false", "-cla-version", "2.0", "-signed-after", "2022-01-02T03:04:05Z"}, &out))
+	assert.Equal(t, `{"login":"john","claVersion":"2.0"}`+"\n"+`{"login":"jane","claVersion":"2.0"}`+"\n", out.String())
+}
+
+func TestRunExportCommandInvalidTime(t *testing.T) {
+	err := runCommand([]string{commandExport, "-signed-before", "yesterday"}, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "invalid -signed-before")
+}
+
+func TestRunExportCommandInvalidFlag(t *testing.T) {
+	err := runCommand([]string{commandExport, "-password", "secret"}, &bytes.Buffer{})
+	assert.EqualError(t, err, "flag provided but not defined: -password")
+}
+
+func TestRunExportCommandHelp(t *testing.T) {
+	assert.NoError(t, runCommand([]string{commandExport, "-h"}, &bytes.Buffer{}))
+}
+
+func TestRunExportCommandOutputFile(t *testing.T) {
+	mock, closeDbFunc := setupMockDBCommand(t)
+	defer closeDbFunc()
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlExportSignatures)).
+		WithArgs("", nil, nil, "", "", true, true).
+		WillReturnRows(sqlmock.NewRows(exportRowColumns).
+			AddRow("id1", "john", "john@doe.tld", "John", time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC), "2.0", "", "myText", 0, "", true, "", "", 0, nil))
+
+	output := filepath.Join(t.TempDir(), "signatures.csv")
+	assert.NoError(t, runCommand([]string{commandExport, "-columns", "login,signedAt,claText", "-output", output}, &bytes.Buffer{}))
+	exported, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "login,signedAt,claText\njohn,2022-01-02T03:04:05Z,myText\n", string(exported))
+}
+
+func TestRunExportCommandOutputFileError(t *testing.T) {
+	mock, closeDbFunc := setupMockDBCommand(t)
+	defer closeDbFunc()
+
+	forcedError := fmt.Errorf("forced export error")
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlExportSignatures)).
+		WillReturnError(forcedError)
+
+	output := filepath.Join(t.TempDir(), "signatures.csv")
+	assert.EqualError(t, runCommand([]string{commandExport, "-output", output}, &bytes.Buffer{}), forcedError.Error())
+	_, err := os.Stat(output)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRunImportCommand(t *testing.T) {
+	mock, closeDbFunc := setupMockDBCommand(t)
+	defer closeDbFunc()
+
+	input := filepath.Join(t.TempDir(), "signatures.ndjson")
+	assert.NoError(t, os.WriteFile(input, []byte(`{"login":"john","claVersion":"2.0","signedAt":"2022-01-02T03:04:05Z"}`+"\n"), 0600))
+	mock.ExpectBegin()
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlImportSignature)).
+		WithArgs("john", "", "", time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC), "2.0", "", nil, nil, false, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	var out bytes.Buffer
+	assert.NoError(t, runCommand([]string{commandImport, "-format", "ndjson", "-input", input}, &out))
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, `{
+  "dryRun": false,
+  "rows": 1,
+  "imported": 1,
+  "conflicts": 0,
+  "invalid": 0,
+  "problems": []
+}
+`, out.String())
+}
+
+func TestRunImportCommandMissingInput(t *testing.T) {
+	assert.EqualError(t, runCommand([]string{commandImport, "-dry-run"}, &bytes.Buffer{}), "missing -input")
+}
+
+func TestRunImportCommandInvalidBatchSize(t *testing.T) {
+	err := runCommand([]string{commandImport, "-input", "signatures.csv", "-batch-size", "0"}, &bytes.Buffer{})
+	assert.EqualError(t, err, "invalid -batch-size: 0, must be between 1 and 5000")
+}
+
+func TestRunImportCommandError(t *testing.T) {
+	mock, closeDbFunc := setupMockDBCommand(t)
+	defer closeDbFunc()
+
+	input := filepath.Join(t.TempDir(), "signatures.csv")
+	assert.NoError(t, os.WriteFile(input, []byte("login,claVersion,signedAt\njohn,2.0,2022-01-02T03:04:05Z\n"), 0600))
+	forcedError := fmt.Errorf("forced begin error")
+	mock.ExpectBegin().WillReturnError(forcedError)
+
+	var out bytes.Buffer
+	assert.EqualError(t, runCommand([]string{commandImport, "-input", input}, &out), forcedError.Error())
+	assert.Contains(t, out.String(), `"error": "forced begin error"`)
+}
@@ -20,8 +20,14 @@
 package db
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -33,17 +39,48 @@ import (
 )
 
 const sqlInsertSignature = `INSERT INTO signatures
-		(LoginName, Email, GivenName, SignedAt, ClaVersion, ClaTextUrl, ClaText)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+		(LoginName, Email, GivenName, SignedAt, ClaVersion, ClaTextUrl, ClaDocumentId, ClaTextSha256, EmailVerified,
+		IpAddress, UserAgent, GitHubUserId)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
 
 const msgTemplateErrInsertSignatureDuplicate = "insert error. did user previously sign the cla? user: %+v, error: %+v"
 
 type IClaDB interface {
 	InsertSignature(u *types.UserSignature) error
-	HasAuthorSignedTheCla(login, claVersion string) (bool, *types.UserSignature, error)
+	HasAuthorSignedTheCla(login string, gitHubUserId int64, claVersion string) (bool, *types.UserSignature, error)
+	UpdateSignatureLogin(gitHubUserId int64, login string) error
+	GetAuthorSignatures(login string, gitHubUserId int64) ([]types.UserSignature, error)
+	HasEmailSignedTheCla(email, claVersion string) (bool, *types.UserSignature, error)
 	StorePRAuthorsMissingSignature(evalInfo *types.EvaluationInfo, checkedAt time.Time) error
 	GetPRsForUser(*types.UserSignature) ([]types.EvaluationInfo, error)
 	RemovePRsForUsers([]types.UserSignature, *types.EvaluationInfo) error
+	RemoveClosedPR(*types.EvaluationInfo) error
+	StorePRSignedAuthors(evalInfo *types.EvaluationInfo, usersSigned []types.UserSignature, checkedAt time.Time) error
+	GetPRsForSignedAuthor(login string) ([]types.EvaluationInfo, error)
+	InsertCorporateSignature(corp *types.CorporateSignature) error
+	GetCorporateSignature(company, claVersion string) (*types.CorporateSignature, error)
+	AddCorporateMember(corporateSignatureId string, member *types.CorporateMember) error
+	RemoveCorporateMember(corporateSignatureId string, member *types.CorporateMember) error
+	SetCorporateSignatureActive(corporateSignatureId string, active bool) error
+	HasCorporateSignedTheCla(login, email, claVersion string) (bool, *types.CorporateSignature, error)
+	RevokeSignature(revocation *types.SignatureRevocation) error
+	SetCLAVersionExpiry(expiry *types.CLAVersionExpiry) error
+	UpsertCLADocument(doc *types.CLADocument) error
+	GetCLADocuments() ([]types.CLADocument, error)
+	GetCLADocumentsByVersion(claVersion string) ([]types.CLADocument, error)
+	GetCLADocumentForRepo(owner, repo string, at time.Time) (*types.CLADocument, error)
+	StoreCLADocument(stored *types.StoredCLADocument) error
+	GetStoredCLADocumentByUrl(claVersion, claTextUrl string) (*types.StoredCLADocument, error)
+	SetCLADocumentMapping(mapping *types.CLADocumentMapping) error
+	RemoveCLADocumentMapping(mapping *types.CLADocumentMapping) error
+	InsertResignCampaign(campaign *types.ResignCampaign) error
+	GetResignCandidates(documentId, claVersion string, activeSince time.Time) ([]types.ResignContact, error)
+	UpdateResignContact(campaignId string, contact *types.ResignContact) error
+	GetResignCampaign(campaignId string) (*types.ResignCampaign, error)
+	ListSignatures(query *types.SignatureQuery) (*types.SignaturePage, error)
+	CountSignatures(filter *types.SignatureFilter) (int64, error)
+	ExportSignatures(filter *types.SignatureFilter, includeText bool, export func(signature *types.UserSignature) error) error
+	ImportSignatures(signatures []types.UserSignature, dryRun bool) (conflicts []int, err error)
 	MigrateDB(migrateSourceURL string) error
 }
 
@@ -60,7 +97,13 @@ func New(db *sql.DB, logger *zap.Logger) *ClaDB {
 }
 
 func (p *ClaDB) InsertSignature(user *types.UserSignature) error {
-	result, err := p.db.Exec(sqlInsertSignature, user.User.Login, user.User.Email, user.User.GivenName, user.TimeSigned, user.CLAVersion, user.CLATextUrl, user.CLAText)
+	evidence := user.Evidence
+	if evidence == nil {
+		evidence = &types.SigningEvidence{}
+	}
+	result, err := p.db.Exec(sqlInsertSignature, user.User.Login, user.User.Email, user.User.GivenName, user.TimeSigned,
+		user.CLAVersion, user.CLATextUrl, nullableDocumentId(user.CLADocumentId), nullableSha256(user.CLATextSha256),
+		user.EmailVerified, evidence.IPAddress, evidence.UserAgent, nullableGitHubUserId(evidence.GitHubUserId))
 	if err != nil {
 		return fmt.Errorf(msgTemplateErrInsertSignatureDuplicate, user.User, err)
 	}
@@ -71,26 +114,61 @@ func (p *ClaDB) InsertSignature(user *types.UserSignature) error {
 	return nil
 }
 
-const SqlSelectUserSignature = `SELECT 
-		LoginName, Email, GivenName, SignedAt, ClaVersion, ClaTextUrl, ClaText
-		FROM signatures		
-		WHERE LoginName = $1
-		AND ClaVersion = $2`
+// nullableDocumentId stores signatures without a stored CLA document (e.g. the text could not be fetched) as NULL
+func nullableDocumentId(claDocumentId int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: claDocumentId, Valid: claDocumentId != 0}
+}
+
+func nullableSha256(claTextSha256 string) sql.NullString {
+	return sql.NullString{String: claTextSha256, Valid: claTextSha256 != ""}
+}
+
+// nullableGitHubUserId stores signatures made without the GitHub user id of the signer as NULL
+func nullableGitHubUserId(gitHubUserId int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: gitHubUserId, Valid: gitHubUserId != 0}
+}
+
+// sqlSignatureColumns are the columns read into a types.UserSignature, the text is read from the signed cla document
+const sqlSignatureColumns = `LoginName, Email, GivenName, SignedAt, signatures.ClaVersion, signatures.ClaTextUrl,
+		COALESCE(cla_documents.ClaText, ''), COALESCE(signatures.ClaDocumentId, 0), COALESCE(signatures.ClaTextSha256, ''),
+		EmailVerified, IpAddress, UserAgent, COALESCE(GitHubUserId, 0)
+		FROM signatures
+		LEFT JOIN cla_documents ON cla_documents.Id = signatures.ClaDocumentId`
 
-func (p *ClaDB) HasAuthorSignedTheCla(login, claVersion string) (isSigned bool, foundUserSignature *types.UserSignature, err error) {
+// SqlSelectUserSignature ignores revoked signatures, and signatures of a CLA version that has expired.
+// A signature is found by the GitHub user id ($3) first. Only a signature without a user id (or any signature, if
+// the user id is not known) is found by login, so a new account with the old login of a renamed account does not
+// inherit its signature. Like GitHub, logins are matched case-insensitively.
+const SqlSelectUserSignature = `SELECT ` + sqlSignatureColumns + `
+		WHERE (GitHubUserId = $3 OR (lower(LoginName) = lower($1) AND ($3 = 0 OR GitHubUserId IS NULL)))
+		AND signatures.ClaVersion = $2
+		AND RevokedAt IS NULL
+		AND NOT EXISTS (SELECT 1 FROM cla_version_expiry
+			WHERE cla_version_expiry.ClaVersion = signatures.ClaVersion
+			AND cla_version_expiry.ExpiresAt <= now())
+		ORDER BY GitHubUserId = $3 DESC NULLS LAST
+		LIMIT 1`
+
+// HasAuthorSignedTheCla finds the signature of the GitHub user with the given id, or with the given login. Use 0 if
+// the id of the user is not known.
+func (p *ClaDB) HasAuthorSignedTheCla(login string, gitHubUserId int64, claVersion string) (isSigned bool, foundUserSignature *types.UserSignature, err error) {
 	p.logger.Debug("did author sign the CLA",
 		zap.String("login", login),
+		zap.Int64("gitHubUserId", gitHubUserId),
 		zap.String("claVersion", claVersion),
 	)
 
-	rows, err := p.db.Query(SqlSelectUserSignature, login, claVersion)
+	rows, err := p.db.Query(SqlSelectUserSignature, login, claVersion, gitHubUserId)
 	if err != nil {
 		return
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
 	for rows.Next() {
 		isSigned = true
-		foundUserSignature = &types.UserSignature{}
+		foundUserSignature = &types.UserSignature{Evidence: &types.SigningEvidence{}}
 		err = rows.Scan(
 			&foundUserSignature.User.Login,
 			&foundUserSignature.User.Email,
@@ -99,6 +177,12 @@ func (p *ClaDB) HasAuthorSignedTheCla(login, claVersion string) (isSigned bool,
 			&foundUserSignature.CLAVersion,
 			&foundUserSignature.CLATextUrl,
 			&foundUserSignature.CLAText,
+			&foundUserSignature.CLADocumentId,
+			&foundUserSignature.CLATextSha256,
+			&foundUserSignature.EmailVerified,
+			&foundUserSignature.Evidence.IPAddress,
+			&foundUserSignature.Evidence.UserAgent,
+			&foundUserSignature.Evidence.GitHubUserId,
 		)
 		if err != nil {
 			return
@@ -113,6 +197,130 @@ func (p *ClaDB) HasAuthorSignedTheCla(login, claVersion string) (isSigned bool,
 	return
 }
 
+// sqlUpdateSignatureLogin renames the signatures of a GitHub user, and sets the user id of signatures of the login
+// that were made before the user id was recorded
+const sqlUpdateSignatureLogin = `UPDATE signatures
+		SET LoginName = $2, GitHubUserId = $1
+		WHERE (GitHubUserId = $1 AND LoginName <> $2)
+		OR (lower(LoginName) = lower($2) AND GitHubUserId IS NULL)`
+
+// UpdateSignatureLogin updates the signatures of a GitHub user to the current login of the user, e.g. after a rename
+func (p *ClaDB) UpdateSignatureLogin(gitHubUserId int64, login string) (err error) {
+	if gitHubUserId == 0 {
+		return fmt.Errorf("missing github user id of login: %s", login)
+	}
+	result, err := p.db.Exec(sqlUpdateSignatureLogin, gitHubUserId, login)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	p.logger.Info("updated signature login",
+		zap.Int64("gitHubUserId", gitHubUserId),
+		zap.String("login", login),
+		zap.Int64("rowsAffected", rowsAffected),
+	)
+	return
+}
+
+// SqlSelectAuthorSignatures finds the signatures of all CLA versions of a GitHub user, matched like
+// SqlSelectUserSignature. The most recent signature comes first.
+const SqlSelectAuthorSignatures = `SELECT ` + sqlSignatureColumns + `
+		WHERE (GitHubUserId = $2 OR (lower(LoginName) = lower($1) AND ($2 = 0 OR GitHubUserId IS NULL)))
+		AND RevokedAt IS NULL
+		AND NOT EXISTS (SELECT 1 FROM cla_version_expiry
+			WHERE cla_version_expiry.ClaVersion = signatures.ClaVersion
+			AND cla_version_expiry.ExpiresAt <= now())
+		ORDER BY SignedAt DESC`
+
+// GetAuthorSignatures finds the signatures of any CLA version of the GitHub user with the given id, or with the given
+// login. Use 0 if the id of the user is not known.
+func (p *ClaDB) GetAuthorSignatures(login string, gitHubUserId int64) (signatures []types.UserSignature, err error) {
+	rows, err := p.db.Query(SqlSelectAuthorSignatures, login, gitHubUserId)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		signature := types.UserSignature{Evidence: &types.SigningEvidence{}}
+		err = rows.Scan(
+			&signature.User.Login,
+			&signature.User.Email,
+			&signature.User.GivenName,
+			&signature.TimeSigned,
+			&signature.CLAVersion,
+			&signature.CLATextUrl,
+			&signature.CLAText,
+			&signature.CLADocumentId,
+			&signature.CLATextSha256,
+			&signature.EmailVerified,
+			&signature.Evidence.IPAddress,
+			&signature.Evidence.UserAgent,
+			&signature.Evidence.GitHubUserId,
+		)
+		if err != nil {
+			return
+		}
+		signatures = append(signatures, signature)
+	}
+	return
+}
+
+// SqlSelectEmailSignature finds a signature by the verified email of the signer, for commits that are not linked to
+// a GitHub account. Like SqlSelectUserSignature, it ignores revoked and expired signatures. EmailVerified is only set
+// when the CLA is signed with an email GitHub verified at the OAuth login, so older signatures never match.
+const SqlSelectEmailSignature = `SELECT ` + sqlSignatureColumns + `
+		WHERE lower(Email) = lower($1)
+		AND EmailVerified
+		AND signatures.ClaVersion = $2
+		AND RevokedAt IS NULL
+		AND NOT EXISTS (SELECT 1 FROM cla_version_expiry
+			WHERE cla_version_expiry.ClaVersion = signatures.ClaVersion
+			AND cla_version_expiry.ExpiresAt <= now())
+		ORDER BY SignedAt
+		LIMIT 1`
+
+func (p *ClaDB) HasEmailSignedTheCla(email, claVersion string) (isSigned bool, foundUserSignature *types.UserSignature, err error) {
+	p.logger.Debug("did email sign the CLA",
+		zap.String("email", email),
+		zap.String("claVersion", claVersion),
+	)
+
+	if email == "" {
+		return
+	}
+
+	foundUserSignature = &types.UserSignature{Evidence: &types.SigningEvidence{}}
+	err = p.db.QueryRow(SqlSelectEmailSignature, email, claVersion).Scan(
+		&foundUserSignature.User.Login,
+		&foundUserSignature.User.Email,
+		&foundUserSignature.User.GivenName,
+		&foundUserSignature.TimeSigned,
+		&foundUserSignature.CLAVersion,
+		&foundUserSignature.CLATextUrl,
+		&foundUserSignature.CLAText,
+		&foundUserSignature.CLADocumentId,
+		&foundUserSignature.CLATextSha256,
+		&foundUserSignature.EmailVerified,
+		&foundUserSignature.Evidence.IPAddress,
+		&foundUserSignature.Evidence.UserAgent,
+		&foundUserSignature.Evidence.GitHubUserId,
+	)
+	if err == sql.ErrNoRows {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	isSigned = true
+	return
+}
+
 func (p *ClaDB) MigrateDB(migrateSourceURL string) (err error) {
 	driver, err := postgres.WithInstance(p.db, &postgres.Config{})
 	if err != nil {
@@ -138,10 +346,10 @@ func (p *ClaDB) MigrateDB(migrateSourceURL string) (err error) {
 const sqlInsertPRMissing = `INSERT INTO unsigned_pr
 		(RepoOwner, RepoName, sha, PRNumber, AppID, InstallID)
 		VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING RETURNING id`
-const msgTemplateErrInsertPRMissing = "insert error tracking missing PR CLA. repo: %s, PR: %d, error: %+v"
+const msgTemplateErrInsertPRMissing = "insert error tracking missing PR CLA. owner: %s, repo: %s, PR: %d, error: %+v"
 
 const errMsgInsertedRowExists = "sql: no rows in result set"
-const sqlSelectPR = `SELECT Id from unsigned_pr WHERE RepoName = $1 AND PRNumber = $2`
+const sqlSelectPR = `SELECT Id from unsigned_pr WHERE RepoOwner = $1 AND RepoName = $2 AND PRNumber = $3`
 
 const sqlInsertUserMissing = `INSERT INTO unsigned_user
 		(UnsignedPRID, LoginName, Email, GivenName, ClaVersion, CheckedAt)
@@ -156,20 +364,21 @@ func (p *ClaDB) StorePRAuthorsMissingSignature(evalInfo *types.EvaluationInfo, c
 	if err != nil {
 		if errMsgInsertedRowExists == err.Error() {
 			p.logger.Info("special case, try to read the UUID of the existing parent",
+				zap.String("repoOwner", evalInfo.RepoOwner),
 				zap.String("repoName", evalInfo.RepoName),
 				zap.Int64("PRNumber", evalInfo.PRNumber),
 			)
-			err = p.db.QueryRow(sqlSelectPR, evalInfo.RepoName, evalInfo.PRNumber).Scan(&parentUUID)
+			err = p.db.QueryRow(sqlSelectPR, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).Scan(&parentUUID)
 			if err != nil {
-				return fmt.Errorf(msgTemplateErrInsertPRMissing, evalInfo.RepoName, evalInfo.PRNumber, err)
+				return fmt.Errorf(msgTemplateErrInsertPRMissing, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber, err)
 			}
 		} else {
-			return fmt.Errorf(msgTemplateErrInsertPRMissing, evalInfo.RepoName, evalInfo.PRNumber, err)
+			return fmt.Errorf(msgTemplateErrInsertPRMissing, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber, err)
 		}
 	}
 	if parentUUID == "" {
 		// we can not ignore an empty parentId, so fail loudly
-		return fmt.Errorf(msgTemplateErrInsertPRMissing, evalInfo.RepoName, evalInfo.PRNumber, fmt.Errorf("empty parentUUID"))
+		return fmt.Errorf(msgTemplateErrInsertPRMissing, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber, fmt.Errorf("empty parentUUID"))
 	}
 
 	for _, missingAuthor := range evalInfo.UserSignatures {
@@ -195,7 +404,7 @@ func (p *ClaDB) StorePRAuthorsMissingSignature(evalInfo *types.EvaluationInfo, c
 }
 
 const sqlSelectPRsForUser = `SELECT DISTINCT unsigned_pr.* from unsigned_pr, unsigned_user 
-WHERE unsigned_pr.Id = unsigned_user.UnsignedPRID AND LoginName = $1 AND ClaVersion = $2`
+WHERE unsigned_pr.Id = unsigned_user.UnsignedPRID AND lower(LoginName) = lower($1) AND ClaVersion = $2`
 
 func (p *ClaDB) GetPRsForUser(user *types.UserSignature) (evalInfos []types.EvaluationInfo, err error) {
 	var rows *sql.Rows
@@ -230,7 +439,7 @@ func (p *ClaDB) GetPRsForUser(user *types.UserSignature) (evalInfos []types.Eval
 }
 
 const sqlDeleteUnsignedUser = `DELETE FROM unsigned_user 
-WHERE UnsignedPRID = $1 AND LoginName = $2 AND ClaVersion = $3`
+WHERE UnsignedPRID = $1 AND lower(LoginName) = lower($2) AND ClaVersion = $3`
 
 const SqlSelectUnsignedUsersForPR = `SELECT count(*) from unsigned_pr, unsigned_user
 WHERE unsigned_pr.Id = unsigned_user.UnsignedPRID AND unsigned_pr.Id = $1`
@@ -266,3 +475,1030 @@ func (p *ClaDB) RemovePRsForUsers(usersSigned []types.UserSignature, evalInfo *t
 	}
 	return
 }
+
+const sqlDeleteUnsignedUsersForClosedPR = `DELETE FROM unsigned_user
+WHERE UnsignedPRID IN (SELECT Id FROM unsigned_pr WHERE RepoOwner = $1 AND RepoName = $2 AND PRNumber = $3)`
+
+const sqlDeleteClosedPR = `DELETE FROM unsigned_pr
+WHERE RepoOwner = $1 AND RepoName = $2 AND PRNumber = $3`
+
+// RemoveClosedPR removes the tracking rows of a PR that was closed (or merged), so the PR is no longer re-evaluated
+// when one of its authors signs the CLA. It is not an error if the PR is not tracked.
+func (p *ClaDB) RemoveClosedPR(evalInfo *types.EvaluationInfo) (err error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(sqlDeleteUnsignedUsersForClosedPR, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber); err != nil {
+		return
+	}
+	if _, err = tx.Exec(sqlDeleteClosedPR, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber); err != nil {
+		return
+	}
+	if _, err = tx.Exec(SqlDeleteSignedPRUsers, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber); err != nil {
+		return
+	}
+
+	err = tx.Commit()
+	return
+}
+
+const SqlDeleteSignedPRUsers = `DELETE FROM signed_pr_user
+WHERE RepoOwner = $1 AND RepoName = $2 AND PRNumber = $3`
+
+const SqlInsertSignedPRUser = `INSERT INTO signed_pr_user
+		(RepoOwner, RepoName, sha, PRNumber, AppID, InstallID, LoginName, CheckedAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (RepoOwner, RepoName, PRNumber, lower(LoginName)) DO NOTHING`
+
+// StorePRSignedAuthors replaces the signed authors of a PR, so the PR is re-evaluated if one of their signatures is
+// revoked, see GetPRsForSignedAuthor
+func (p *ClaDB) StorePRSignedAuthors(evalInfo *types.EvaluationInfo, usersSigned []types.UserSignature, checkedAt time.Time) (err error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.Exec(SqlDeleteSignedPRUsers, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber); err != nil {
+		return
+	}
+	for _, user := range usersSigned {
+		_, err = tx.Exec(SqlInsertSignedPRUser, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.Sha, evalInfo.PRNumber,
+			evalInfo.AppId, evalInfo.InstallId, user.User.Login, checkedAt)
+		if err != nil {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	return
+}
+
+const SqlSelectPRsForSignedAuthor = `SELECT RepoOwner, RepoName, sha, PRNumber, AppID, InstallID FROM signed_pr_user
+WHERE lower(LoginName) = lower($1)`
+
+// GetPRsForSignedAuthor reads the open PRs that passed with a signature of the login, including PRs of others that
+// contain commits of the login
+func (p *ClaDB) GetPRsForSignedAuthor(login string) (evalInfos []types.EvaluationInfo, err error) {
+	rows, err := p.db.Query(SqlSelectPRsForSignedAuthor, login)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		var evalInfo types.EvaluationInfo
+		err = rows.Scan(
+			&evalInfo.RepoOwner,
+			&evalInfo.RepoName,
+			&evalInfo.Sha,
+			&evalInfo.PRNumber,
+			&evalInfo.AppId,
+			&evalInfo.InstallId,
+		)
+		if err != nil {
+			return
+		}
+		evalInfos = append(evalInfos, evalInfo)
+	}
+	return
+}
+
+const sqlInsertCorporateSignature = `INSERT INTO corporate_signatures
+		(CompanyName, SignatoryLogin, SignatoryEmail, SignatoryName, SignedAt, ClaVersion, ClaTextUrl, ClaDocumentId, ClaTextSha256)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING Id`
+
+const msgTemplateErrInsertCorporateSignatureDuplicate = "insert error. did company previously sign the cla? company: %s, claVersion: %s, error: %+v"
+
+const sqlInsertCorporateMember = `INSERT INTO corporate_members
+		(CorporateSignatureID, MemberType, MemberValue)
+		VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+const msgTemplateErrInvalidCorporateMemberType = "invalid corporate member type: %s"
+const msgTemplateErrEmptyCorporateMember = "empty corporate member value, type: %s"
+
+// InsertCorporateSignature stores the corporate signature along with its allow-list of members, all within a
+// single transaction. The Id of the new row is set on the given corporate signature.
+func (p *ClaDB) InsertCorporateSignature(corp *types.CorporateSignature) (err error) {
+	for i := range corp.Members {
+		if err = normalizeCorporateMember(&corp.Members[i]); err != nil {
+			return
+		}
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.QueryRow(sqlInsertCorporateSignature, corp.Company, corp.Signatory.Login, corp.Signatory.Email,
+		corp.Signatory.GivenName, corp.TimeSigned, corp.CLAVersion, corp.CLATextUrl,
+		nullableDocumentId(corp.CLADocumentId), nullableSha256(corp.CLATextSha256)).
+		Scan(&corp.Id)
+	if err != nil {
+		return fmt.Errorf(msgTemplateErrInsertCorporateSignatureDuplicate, corp.Company, corp.CLAVersion, err)
+	}
+
+	for _, member := range corp.Members {
+		if _, err = tx.Exec(sqlInsertCorporateMember, corp.Id, member.Type, member.Value); err != nil {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	return
+}
+
+// normalizeCorporateMember validates the member type and lower cases logins and domains, so they match consistently.
+// A domain may be given with a leading "@". An empty value is rejected, as it would match authors without an email.
+func normalizeCorporateMember(member *types.CorporateMember) error {
+	value := strings.ToLower(strings.TrimSpace(member.Value))
+	switch member.Type {
+	case types.CorporateMemberTypeLogin:
+	case types.CorporateMemberTypeDomain:
+		value = strings.TrimPrefix(value, "@")
+	default:
+		return fmt.Errorf(msgTemplateErrInvalidCorporateMemberType, member.Type)
+	}
+	if value == "" {
+		return fmt.Errorf(msgTemplateErrEmptyCorporateMember, member.Type)
+	}
+	member.Value = value
+	return nil
+}
+
+const SqlSelectCorporateSignature = `SELECT
+		corporate_signatures.Id, CompanyName, SignatoryLogin, SignatoryEmail, SignatoryName, SignedAt,
+		corporate_signatures.ClaVersion, corporate_signatures.ClaTextUrl, COALESCE(cla_documents.ClaText, ''),
+		COALESCE(corporate_signatures.ClaDocumentId, 0), COALESCE(corporate_signatures.ClaTextSha256, ''), Active
+		FROM corporate_signatures
+		LEFT JOIN cla_documents ON cla_documents.Id = corporate_signatures.ClaDocumentId
+		WHERE CompanyName = $1
+		AND corporate_signatures.ClaVersion = $2`
+
+const sqlSelectCorporateMembers = `SELECT MemberType, MemberValue
+		FROM corporate_members
+		WHERE CorporateSignatureID = $1
+		ORDER BY MemberType, MemberValue`
+
+// GetCorporateSignature returns the corporate signature (including members) of the given company, or nil if the
+// company has not signed the given CLA version.
+func (p *ClaDB) GetCorporateSignature(company, claVersion string) (corp *types.CorporateSignature, err error) {
+	found := &types.CorporateSignature{}
+	err = p.db.QueryRow(SqlSelectCorporateSignature, company, claVersion).Scan(
+		&found.Id,
+		&found.Company,
+		&found.Signatory.Login,
+		&found.Signatory.Email,
+		&found.Signatory.GivenName,
+		&found.TimeSigned,
+		&found.CLAVersion,
+		&found.CLATextUrl,
+		&found.CLAText,
+		&found.CLADocumentId,
+		&found.CLATextSha256,
+		&found.Active,
+	)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	rows, err := p.db.Query(sqlSelectCorporateMembers, found.Id)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		member := types.CorporateMember{}
+		if err = rows.Scan(&member.Type, &member.Value); err != nil {
+			return
+		}
+		found.Members = append(found.Members, member)
+	}
+
+	corp = found
+	return
+}
+
+func (p *ClaDB) AddCorporateMember(corporateSignatureId string, member *types.CorporateMember) (err error) {
+	if err = normalizeCorporateMember(member); err != nil {
+		return
+	}
+	_, err = p.db.Exec(sqlInsertCorporateMember, corporateSignatureId, member.Type, member.Value)
+	return
+}
+
+const sqlDeleteCorporateMember = `DELETE FROM corporate_members
+		WHERE CorporateSignatureID = $1 AND MemberType = $2 AND MemberValue = $3`
+
+func (p *ClaDB) RemoveCorporateMember(corporateSignatureId string, member *types.CorporateMember) (err error) {
+	if err = normalizeCorporateMember(member); err != nil {
+		return
+	}
+	_, err = p.db.Exec(sqlDeleteCorporateMember, corporateSignatureId, member.Type, member.Value)
+	return
+}
+
+const sqlUpdateCorporateSignatureActive = `UPDATE corporate_signatures SET Active = $2 WHERE Id = $1`
+
+const msgTemplateErrCorporateSignatureNotFound = "corporate signature not found: %s"
+
+func (p *ClaDB) SetCorporateSignatureActive(corporateSignatureId string, active bool) (err error) {
+	result, err := p.db.Exec(sqlUpdateCorporateSignatureActive, corporateSignatureId, active)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected == 0 {
+		err = fmt.Errorf(msgTemplateErrCorporateSignatureNotFound, corporateSignatureId)
+	}
+	return
+}
+
+const SqlSelectCorporateCoverage = `SELECT
+		corporate_signatures.Id, CompanyName, SignatoryLogin, SignatoryEmail, SignatoryName, SignedAt, ClaVersion, ClaTextUrl, Active
+		FROM corporate_signatures, corporate_members
+		WHERE corporate_signatures.Id = corporate_members.CorporateSignatureID
+		AND Active
+		AND ClaVersion = $1
+		AND ((MemberType = 'login' AND $2 <> '' AND MemberValue = lower($2))
+			OR (MemberType = 'domain' AND $3 <> '' AND MemberValue = $3))
+		AND NOT EXISTS (SELECT 1 FROM cla_version_expiry
+			WHERE cla_version_expiry.ClaVersion = corporate_signatures.ClaVersion
+			AND cla_version_expiry.ExpiresAt <= now())
+		ORDER BY SignedAt
+		LIMIT 1`
+
+// HasCorporateSignedTheCla checks if the given author is covered by an active corporate signature, either because
+// the login is on the allow-list, or because the domain of the email is on the allow-list.
+// The CLA text is not loaded, to keep this check cheap.
+func (p *ClaDB) HasCorporateSignedTheCla(login, email, claVersion string) (isSigned bool, foundCorporateSignature *types.CorporateSignature, err error) {
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = strings.ToLower(email[at+1:])
+	}
+	p.logger.Debug("is author covered by a corporate CLA",
+		zap.String("login", login),
+		zap.String("domain", domain),
+		zap.String("claVersion", claVersion),
+	)
+
+	rows, err := p.db.Query(SqlSelectCorporateCoverage, claVersion, login, domain)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		isSigned = true
+		foundCorporateSignature = &types.CorporateSignature{}
+		err = rows.Scan(
+			&foundCorporateSignature.Id,
+			&foundCorporateSignature.Company,
+			&foundCorporateSignature.Signatory.Login,
+			&foundCorporateSignature.Signatory.Email,
+			&foundCorporateSignature.Signatory.GivenName,
+			&foundCorporateSignature.TimeSigned,
+			&foundCorporateSignature.CLAVersion,
+			&foundCorporateSignature.CLATextUrl,
+			&foundCorporateSignature.Active,
+		)
+		if err != nil {
+			return
+		}
+		p.logger.Debug("found corporate signature covering author",
+			zap.String("login", login),
+			zap.String("company", foundCorporateSignature.Company),
+			zap.String("claVersion", foundCorporateSignature.CLAVersion),
+		)
+	}
+	return
+}
+
+const sqlRevokeSignature = `UPDATE signatures
+		SET RevokedAt = $3, RevokedBy = $4, RevokedReason = $5
+		WHERE lower(LoginName) = lower($1)
+		AND ClaVersion = $2
+		AND RevokedAt IS NULL`
+
+const msgTemplateErrRevokeSignatureNotFound = "no active signature to revoke. login: %s, claVersion: %s"
+
+// RevokeSignature marks the (not yet revoked) signature of the login for the CLA version as revoked. The row is
+// kept, so we still know the CLA was signed at one time.
+func (p *ClaDB) RevokeSignature(revocation *types.SignatureRevocation) (err error) {
+	result, err := p.db.Exec(sqlRevokeSignature, revocation.Login, revocation.CLAVersion, revocation.RevokedAt,
+		revocation.RevokedBy, revocation.Reason)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected == 0 {
+		err = fmt.Errorf(msgTemplateErrRevokeSignatureNotFound, revocation.Login, revocation.CLAVersion)
+		return
+	}
+	p.logger.Info("revoked signature",
+		zap.String("login", revocation.Login),
+		zap.String("claVersion", revocation.CLAVersion),
+		zap.String("revokedBy", revocation.RevokedBy),
+	)
+	return
+}
+
+const sqlUpsertCLAVersionExpiry = `INSERT INTO cla_version_expiry
+		(ClaVersion, ExpiresAt)
+		VALUES ($1, $2)
+		ON CONFLICT (ClaVersion) DO UPDATE SET ExpiresAt = EXCLUDED.ExpiresAt`
+
+const sqlDeleteCLAVersionExpiry = `DELETE FROM cla_version_expiry WHERE ClaVersion = $1`
+
+// SetCLAVersionExpiry sets (or clears, if ExpiresAt is nil) the expiry date of the CLA version
+func (p *ClaDB) SetCLAVersionExpiry(expiry *types.CLAVersionExpiry) (err error) {
+	if expiry.ExpiresAt == nil {
+		_, err = p.db.Exec(sqlDeleteCLAVersionExpiry, expiry.CLAVersion)
+		return
+	}
+	_, err = p.db.Exec(sqlUpsertCLAVersionExpiry, expiry.CLAVersion, *expiry.ExpiresAt)
+	return
+}
+
+const sqlUpsertStoredCLADocument = `INSERT INTO cla_documents
+		(ClaVersion, ClaTextSha256, ClaTextUrl, ClaText, CreatedAt)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (ClaVersion, ClaTextSha256) DO UPDATE SET ClaVersion = EXCLUDED.ClaVersion
+		RETURNING Id, ClaTextUrl, CreatedAt`
+
+// StoreCLADocument stores the text of a CLA version, unless the same text was stored before. The Id (and the hash)
+// of the stored document is set on the given document, which is updated to match an already stored text.
+func (p *ClaDB) StoreCLADocument(stored *types.StoredCLADocument) (err error) {
+	stored.CLATextSha256 = sha256Hex(stored.CLAText)
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	err = p.db.QueryRow(sqlUpsertStoredCLADocument, stored.CLAVersion, stored.CLATextSha256, stored.CLATextUrl,
+		stored.CLAText, stored.CreatedAt).
+		Scan(&stored.Id, &stored.CLATextUrl, &stored.CreatedAt)
+	return
+}
+
+func sha256Hex(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
+
+const SqlSelectStoredCLADocumentByUrl = `SELECT
+		Id, ClaVersion, ClaTextSha256, ClaTextUrl, ClaText, CreatedAt
+		FROM cla_documents
+		WHERE ClaVersion = $1
+		AND ClaTextUrl = $2
+		ORDER BY CreatedAt DESC
+		LIMIT 1`
+
+// GetStoredCLADocumentByUrl returns the text of the CLA version that was fetched from the given url, or nil if the
+// text was not stored yet.
+func (p *ClaDB) GetStoredCLADocumentByUrl(claVersion, claTextUrl string) (stored *types.StoredCLADocument, err error) {
+	found := &types.StoredCLADocument{}
+	err = p.db.QueryRow(SqlSelectStoredCLADocumentByUrl, claVersion, claTextUrl).Scan(
+		&found.Id,
+		&found.CLAVersion,
+		&found.CLATextSha256,
+		&found.CLATextUrl,
+		&found.CLAText,
+		&found.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	stored = found
+	return
+}
+
+const sqlUpsertCLADocument = `INSERT INTO cla_catalog
+		(DocumentId, ClaVersion, ClaTextUrl, ClaDocumentId, EffectiveAt)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (DocumentId, ClaVersion) DO UPDATE SET
+		ClaTextUrl = EXCLUDED.ClaTextUrl, ClaDocumentId = EXCLUDED.ClaDocumentId, EffectiveAt = EXCLUDED.EffectiveAt`
+
+// UpsertCLADocument adds a (version of a) CLA document to the catalog, or updates it if it already exists. Text that
+// is given with the document is stored in cla_documents.
+func (p *ClaDB) UpsertCLADocument(doc *types.CLADocument) (err error) {
+	doc.StoredDocumentId = 0
+	doc.CLATextSha256 = ""
+	if doc.CLAText != "" {
+		stored := &types.StoredCLADocument{CLAVersion: doc.CLAVersion, CLATextUrl: doc.CLATextUrl, CLAText: doc.CLAText}
+		if err = p.StoreCLADocument(stored); err != nil {
+			return
+		}
+		doc.StoredDocumentId = stored.Id
+		doc.CLATextSha256 = stored.CLATextSha256
+	}
+
+	_, err = p.db.Exec(sqlUpsertCLADocument, doc.Id, doc.CLAVersion, doc.CLATextUrl,
+		nullableDocumentId(doc.StoredDocumentId), doc.EffectiveAt)
+	return
+}
+
+const sqlSelectCLADocuments = `SELECT
+		DocumentId, cla_catalog.ClaVersion, cla_catalog.ClaTextUrl, EffectiveAt,
+		COALESCE(cla_documents.Id, 0), COALESCE(cla_documents.ClaTextSha256, '')
+		FROM cla_catalog
+		LEFT JOIN cla_documents ON cla_documents.Id = cla_catalog.ClaDocumentId
+		ORDER BY DocumentId, EffectiveAt`
+
+// GetCLADocuments returns all versions of all documents in the catalog, without their text
+func (p *ClaDB) GetCLADocuments() (docs []types.CLADocument, err error) {
+	rows, err := p.db.Query(sqlSelectCLADocuments)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		doc := types.CLADocument{}
+		if err = rows.Scan(&doc.Id, &doc.CLAVersion, &doc.CLATextUrl, &doc.EffectiveAt, &doc.StoredDocumentId, &doc.CLATextSha256); err != nil {
+			return
+		}
+		docs = append(docs, doc)
+	}
+	err = rows.Err()
+	return
+}
+
+const SqlSelectCLADocumentsByVersion = `SELECT
+		DocumentId, cla_catalog.ClaVersion, cla_catalog.ClaTextUrl, EffectiveAt,
+		COALESCE(cla_documents.Id, 0), COALESCE(cla_documents.ClaTextSha256, ''), COALESCE(cla_documents.ClaText, '')
+		FROM cla_catalog
+		LEFT JOIN cla_documents ON cla_documents.Id = cla_catalog.ClaDocumentId
+		WHERE cla_catalog.ClaVersion = $1
+		ORDER BY DocumentId`
+
+// GetCLADocumentsByVersion returns the documents of the catalog (including their text) having the given CLA version
+func (p *ClaDB) GetCLADocumentsByVersion(claVersion string) (docs []types.CLADocument, err error) {
+	rows, err := p.db.Query(SqlSelectCLADocumentsByVersion, claVersion)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		doc := types.CLADocument{}
+		if err = rows.Scan(&doc.Id, &doc.CLAVersion, &doc.CLATextUrl, &doc.EffectiveAt, &doc.StoredDocumentId,
+			&doc.CLATextSha256, &doc.CLAText); err != nil {
+			return
+		}
+		docs = append(docs, doc)
+	}
+	err = rows.Err()
+	return
+}
+
+// SqlSelectCLADocumentForRepo prefers a mapping of the repo over a mapping of the whole owner (empty RepoName),
+// then the latest version of the mapped document that is effective.
+const SqlSelectCLADocumentForRepo = `SELECT
+		cla_catalog.DocumentId, cla_catalog.ClaVersion, cla_catalog.ClaTextUrl, EffectiveAt,
+		COALESCE(cla_documents.Id, 0), COALESCE(cla_documents.ClaTextSha256, ''), COALESCE(cla_documents.ClaText, '')
+		FROM cla_document_mappings
+		INNER JOIN cla_catalog ON cla_catalog.DocumentId = cla_document_mappings.DocumentId
+		LEFT JOIN cla_documents ON cla_documents.Id = cla_catalog.ClaDocumentId
+		WHERE RepoOwner = lower($1)
+		AND RepoName IN ('', lower($2))
+		AND EffectiveAt <= $3
+		ORDER BY RepoName DESC, EffectiveAt DESC
+		LIMIT 1`
+
+// GetCLADocumentForRepo returns the CLA document version in effect at the given time for the repo, or nil if
+// neither the repo nor its owner is mapped to a document.
+func (p *ClaDB) GetCLADocumentForRepo(owner, repo string, at time.Time) (doc *types.CLADocument, err error) {
+	found := &types.CLADocument{}
+	err = p.db.QueryRow(SqlSelectCLADocumentForRepo, owner, repo, at).Scan(
+		&found.Id,
+		&found.CLAVersion,
+		&found.CLATextUrl,
+		&found.EffectiveAt,
+		&found.StoredDocumentId,
+		&found.CLATextSha256,
+		&found.CLAText,
+	)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	doc = found
+	return
+}
+
+const sqlUpsertCLADocumentMapping = `INSERT INTO cla_document_mappings
+		(RepoOwner, RepoName, DocumentId)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (RepoOwner, RepoName) DO UPDATE SET DocumentId = EXCLUDED.DocumentId`
+
+const sqlDeleteCLADocumentMapping = `DELETE FROM cla_document_mappings WHERE RepoOwner = $1 AND RepoName = $2`
+
+const msgTemplateErrCLADocumentMappingNotFound = "cla document mapping not found. owner: %s, repo: %s"
+
+// SetCLADocumentMapping maps the owner, or the repo if RepoName is set, to a CLA document. GitHub names are not case
+// sensitive, so names are stored in lower case.
+func (p *ClaDB) SetCLADocumentMapping(mapping *types.CLADocumentMapping) (err error) {
+	normalizeCLADocumentMapping(mapping)
+	_, err = p.db.Exec(sqlUpsertCLADocumentMapping, mapping.RepoOwner, mapping.RepoName, mapping.DocumentId)
+	return
+}
+
+func (p *ClaDB) RemoveCLADocumentMapping(mapping *types.CLADocumentMapping) (err error) {
+	normalizeCLADocumentMapping(mapping)
+	result, err := p.db.Exec(sqlDeleteCLADocumentMapping, mapping.RepoOwner, mapping.RepoName)
+	if err != nil {
+		return
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return
+	}
+	if rowsAffected == 0 {
+		err = fmt.Errorf(msgTemplateErrCLADocumentMappingNotFound, mapping.RepoOwner, mapping.RepoName)
+	}
+	return
+}
+
+func normalizeCLADocumentMapping(mapping *types.CLADocumentMapping) {
+	mapping.RepoOwner = strings.ToLower(mapping.RepoOwner)
+	mapping.RepoName = strings.ToLower(mapping.RepoName)
+}
+
+const sqlInsertResignCampaign = `INSERT INTO resign_campaigns
+		(DocumentId, ClaVersion, ActiveSince, StartedBy, StartedAt)
+		VALUES ($1, $2, $3, $4, $5) RETURNING Id`
+
+const sqlInsertResignContact = `INSERT INTO resign_campaign_contacts
+		(CampaignID, LoginName, GitHubUserId, Email, SignedClaVersion, ContactedAt, Error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+// InsertResignCampaign stores the campaign along with its contacts, so the emails can be sent after the campaign is
+// stored
+func (p *ClaDB) InsertResignCampaign(campaign *types.ResignCampaign) (err error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	err = tx.QueryRow(sqlInsertResignCampaign, campaign.DocumentId, campaign.CLAVersion, campaign.ActiveSince,
+		campaign.StartedBy, campaign.StartedAt).Scan(&campaign.Id)
+	if err != nil {
+		return
+	}
+	for _, contact := range campaign.Contacts {
+		_, err = tx.Exec(sqlInsertResignContact, campaign.Id, contact.Login, nullableGitHubUserId(contact.GitHubUserId),
+			contact.Email, contact.SignedCLAVersion, contact.ContactedAt, contact.Error)
+		if err != nil {
+			return
+		}
+	}
+	err = tx.Commit()
+	return
+}
+
+// SqlSelectResignCandidates finds the active signatures of other versions of CLA document $3 (or of the default
+// document, if $3 is empty) by signers who did not sign CLA version $1 yet, the most recent signature of each signer
+// first. Only signers that signed since $2, or have an open PR waiting for a signature, are candidates. Signers that
+// were contacted for (or are waiting for an email of) a campaign of the same document and version are skipped.
+const SqlSelectResignCandidates = `SELECT prior.LoginName, prior.Email, COALESCE(prior.GitHubUserId, 0), prior.ClaVersion
+		FROM signatures AS prior
+		WHERE prior.ClaVersion <> $1
+		AND (($3 = '' AND NOT EXISTS (SELECT 1 FROM cla_catalog WHERE cla_catalog.ClaVersion = prior.ClaVersion))
+			OR EXISTS (SELECT 1 FROM cla_catalog
+				WHERE cla_catalog.DocumentId = $3
+				AND cla_catalog.ClaVersion = prior.ClaVersion))
+		AND prior.RevokedAt IS NULL
+		AND COALESCE(prior.Email, '') <> ''
+		AND (prior.SignedAt >= $2
+			OR EXISTS (SELECT 1 FROM unsigned_user WHERE lower(unsigned_user.LoginName) = lower(prior.LoginName)))
+		AND NOT EXISTS (SELECT 1 FROM signatures AS resigned
+			WHERE resigned.ClaVersion = $1
+			AND resigned.RevokedAt IS NULL
+			AND (resigned.GitHubUserId = prior.GitHubUserId OR lower(resigned.LoginName) = lower(prior.LoginName)))
+		AND NOT EXISTS (SELECT 1 FROM resign_campaign_contacts
+			JOIN resign_campaigns ON resign_campaigns.Id = resign_campaign_contacts.CampaignID
+			WHERE resign_campaigns.ClaVersion = $1
+			AND resign_campaigns.DocumentId = $3
+			AND (resign_campaign_contacts.ContactedAt IS NOT NULL OR resign_campaign_contacts.Error = '')
+			AND lower(resign_campaign_contacts.LoginName) = lower(prior.LoginName))
+		ORDER BY lower(prior.LoginName), prior.SignedAt DESC`
+
+// GetResignCandidates finds the signatures of other versions of the CLA document by signers who did not sign the
+// given CLA version. Versions are not ordered here, so the caller must skip versions newer than the given one.
+func (p *ClaDB) GetResignCandidates(documentId, claVersion string, activeSince time.Time) (candidates []types.ResignContact, err error) {
+	rows, err := p.db.Query(SqlSelectResignCandidates, claVersion, activeSince, documentId)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		candidate := types.ResignContact{}
+		if err = rows.Scan(&candidate.Login, &candidate.Email, &candidate.GitHubUserId, &candidate.SignedCLAVersion); err != nil {
+			return
+		}
+		candidates = append(candidates, candidate)
+	}
+	err = rows.Err()
+	return
+}
+
+const sqlUpdateResignContact = `UPDATE resign_campaign_contacts
+		SET ContactedAt = $3, Error = $4
+		WHERE CampaignID = $1 AND lower(LoginName) = lower($2)`
+
+// UpdateResignContact records if the email of the campaign was sent to the contact
+func (p *ClaDB) UpdateResignContact(campaignId string, contact *types.ResignContact) (err error) {
+	_, err = p.db.Exec(sqlUpdateResignContact, campaignId, contact.Login, contact.ContactedAt, contact.Error)
+	return
+}
+
+const SqlSelectResignCampaign = `SELECT Id, DocumentId, ClaVersion, ActiveSince, StartedBy, StartedAt
+		FROM resign_campaigns
+		WHERE Id = $1`
+
+// SqlSelectResignContacts reads the contacts of a campaign, with the time each contact signed the CLA version of the
+// campaign, if they did
+const SqlSelectResignContacts = `SELECT LoginName, Email, COALESCE(resign_campaign_contacts.GitHubUserId, 0),
+		SignedClaVersion, ContactedAt, Error,
+		(SELECT min(signatures.SignedAt) FROM signatures
+			WHERE signatures.ClaVersion = resign_campaigns.ClaVersion
+			AND signatures.RevokedAt IS NULL
+			AND (signatures.GitHubUserId = resign_campaign_contacts.GitHubUserId
+				OR lower(signatures.LoginName) = lower(resign_campaign_contacts.LoginName)))
+		FROM resign_campaign_contacts
+		JOIN resign_campaigns ON resign_campaigns.Id = resign_campaign_contacts.CampaignID
+		WHERE CampaignID = $1
+		ORDER BY lower(LoginName)`
+
+// GetResignCampaign reads the campaign with its contacts, or returns nil if there is no campaign with the given id
+func (p *ClaDB) GetResignCampaign(campaignId string) (campaign *types.ResignCampaign, err error) {
+	found := &types.ResignCampaign{}
+	err = p.db.QueryRow(SqlSelectResignCampaign, campaignId).Scan(
+		&found.Id,
+		&found.DocumentId,
+		&found.CLAVersion,
+		&found.ActiveSince,
+		&found.StartedBy,
+		&found.StartedAt,
+	)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+
+	rows, err := p.db.Query(SqlSelectResignContacts, found.Id)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		contact := types.ResignContact{}
+		err = rows.Scan(
+			&contact.Login,
+			&contact.Email,
+			&contact.GitHubUserId,
+			&contact.SignedCLAVersion,
+			&contact.ContactedAt,
+			&contact.Error,
+			&contact.ResignedAt,
+		)
+		if err != nil {
+			return
+		}
+		found.Contacts = append(found.Contacts, contact)
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	campaign = found
+	return
+}
+
+// ErrInvalidCursor is returned for a cursor that is malformed, or that was made for another sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// sqlSignatureListColumns are the columns of a listed signature. Unlike sqlSignatureColumns, the CLA text and the
+// evidence are not read.
+const sqlSignatureListColumns = `signatures.Id, LoginName, COALESCE(Email, ''), COALESCE(GivenName, ''), SignedAt,
+		COALESCE(ClaVersion, ''), ClaTextUrl, COALESCE(ClaDocumentId, 0), COALESCE(ClaTextSha256, ''), EmailVerified
+		FROM signatures`
+
+// sqlSignatureFilter matches the fields of a types.SignatureFilter, an empty (or NULL) argument does not filter
+const sqlSignatureFilter = `
+		WHERE ($6 OR RevokedAt IS NULL)
+		AND ($1 = '' OR signatures.ClaVersion = $1)
+		AND ($2::timestamp IS NULL OR SignedAt >= $2)
+		AND ($3::timestamp IS NULL OR SignedAt < $3)
+		AND ($4 = '' OR lower(LoginName) LIKE $4 || '%')
+		AND ($5 = '' OR lower(Email) LIKE '%@' || $5)`
+
+const SqlCountSignatures = `SELECT COUNT(*) FROM signatures` + sqlSignatureFilter
+
+// signatureSortColumns are the columns a list of signatures can be sorted by
+var signatureSortColumns = map[string]string{
+	types.SignatureSortSignedAt:   "SignedAt",
+	types.SignatureSortLogin:      "lower(LoginName)",
+	types.SignatureSortCLAVersion: "COALESCE(ClaVersion, '')",
+}
+
+// signatureListSql orders by the sort column, and then by id to make the order stable. A cursor continues after the
+// last signature of the previous page, so no signature is skipped or repeated while signatures are added.
+func signatureListSql(sortColumn string, descending, withCursor bool) string {
+	direction, compare := "ASC", ">"
+	if descending {
+		direction, compare = "DESC", "<"
+	}
+	query := `SELECT ` + sqlSignatureListColumns + sqlSignatureFilter
+	limitArg := "$7"
+	if withCursor {
+		query += fmt.Sprintf(`
+		AND (%s, signatures.Id) %s ($7, $8)`, sortColumn, compare)
+		limitArg = "$9"
+	}
+	return query + fmt.Sprintf(`
+		ORDER BY %s %s, signatures.Id %s
+		LIMIT %s`, sortColumn, direction, direction, limitArg)
+}
+
+// escapeLike makes LIKE match the value literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func signatureFilterArgs(filter *types.SignatureFilter) []interface{} {
+	return []interface{}{
+		filter.CLAVersion,
+		filter.SignedAfter,
+		filter.SignedBefore,
+		escapeLike(strings.ToLower(filter.LoginPrefix)),
+		escapeLike(strings.TrimPrefix(strings.ToLower(filter.EmailDomain), "@")),
+		filter.IncludeRevoked,
+	}
+}
+
+// signatureCursor is the position of the last signature of a page in the sort order
+type signatureCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	Id         string `json:"i"`
+}
+
+func encodeSignatureCursor(query *types.SignatureQuery, last *types.UserSignature) string {
+	cursor := signatureCursor{SortBy: query.SortBy, Descending: query.Descending, Id: last.Id}
+	switch query.SortBy {
+	case types.SignatureSortSignedAt:
+		cursor.Value = last.TimeSigned.Format(time.RFC3339Nano)
+	case types.SignatureSortLogin:
+		cursor.Value = strings.ToLower(last.User.Login)
+	case types.SignatureSortCLAVersion:
+		cursor.Value = last.CLAVersion
+	}
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeSignatureCursor returns the sort value and the id of the last signature of the previous page
+func decodeSignatureCursor(query *types.SignatureQuery) (value interface{}, id string, err error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return nil, "", ErrInvalidCursor
+	}
+	cursor := signatureCursor{}
+	if err = json.Unmarshal(decoded, &cursor); err != nil || cursor.Id == "" {
+		return nil, "", ErrInvalidCursor
+	}
+	if cursor.SortBy != query.SortBy || cursor.Descending != query.Descending {
+		return nil, "", ErrInvalidCursor
+	}
+	if cursor.SortBy == types.SignatureSortSignedAt {
+		var signedAt time.Time
+		if signedAt, err = time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return nil, "", ErrInvalidCursor
+		}
+		return signedAt, cursor.Id, nil
+	}
+	return cursor.Value, cursor.Id, nil
+}
+
+// ListSignatures reads a page of the active signatures matching the query. It does not count the signatures, see
+// CountSignatures.
+func (p *ClaDB) ListSignatures(query *types.SignatureQuery) (page *types.SignaturePage, err error) {
+	sortColumn, ok := signatureSortColumns[query.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort: %s", query.SortBy)
+	}
+	if query.Limit < 1 {
+		return nil, fmt.Errorf("invalid limit: %d", query.Limit)
+	}
+
+	args := signatureFilterArgs(&query.SignatureFilter)
+	if query.Cursor != "" {
+		var value interface{}
+		var id string
+		if value, id, err = decodeSignatureCursor(query); err != nil {
+			return
+		}
+		args = append(args, value, id)
+	}
+	// read one more signature to tell if there is a next page
+	args = append(args, query.Limit+1)
+
+	rows, err := p.db.Query(signatureListSql(sortColumn, query.Descending, query.Cursor != ""), args...)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	found := &types.SignaturePage{Signatures: []types.UserSignature{}}
+	for rows.Next() {
+		signature := types.UserSignature{}
+		err = rows.Scan(
+			&signature.Id,
+			&signature.User.Login,
+			&signature.User.Email,
+			&signature.User.GivenName,
+			&signature.TimeSigned,
+			&signature.CLAVersion,
+			&signature.CLATextUrl,
+			&signature.CLADocumentId,
+			&signature.CLATextSha256,
+			&signature.EmailVerified,
+		)
+		if err != nil {
+			return
+		}
+		found.Signatures = append(found.Signatures, signature)
+	}
+
+	if len(found.Signatures) > query.Limit {
+		found.Signatures = found.Signatures[:query.Limit]
+		found.NextCursor = encodeSignatureCursor(query, &found.Signatures[query.Limit-1])
+	}
+	page = found
+	return
+}
+
+// CountSignatures counts the active signatures matching the filter
+func (p *ClaDB) CountSignatures(filter *types.SignatureFilter) (count int64, err error) {
+	err = p.db.QueryRow(SqlCountSignatures, signatureFilterArgs(filter)...).Scan(&count)
+	return
+}
+
+// SqlExportSignatures reads the signatures matching the filter oldest first, and then by id, so the order of an
+// export is stable. The CLA text is only read if $7 is true.
+const SqlExportSignatures = `SELECT signatures.Id, LoginName, COALESCE(Email, ''), COALESCE(GivenName, ''), SignedAt,
+		COALESCE(signatures.ClaVersion, ''), signatures.ClaTextUrl,
+		CASE WHEN $7 THEN COALESCE(cla_documents.ClaText, '') ELSE '' END,
+		COALESCE(signatures.ClaDocumentId, 0), COALESCE(signatures.ClaTextSha256, ''),
+		EmailVerified, IpAddress, UserAgent, COALESCE(GitHubUserId, 0), RevokedAt
+		FROM signatures
+		LEFT JOIN cla_documents ON cla_documents.Id = signatures.ClaDocumentId` + sqlSignatureFilter + `
+		ORDER BY SignedAt, signatures.Id`
+
+// ExportSignatures calls export for each signature matching the filter, without reading all signatures into memory. An error returned by export stops the export.
+func (p *ClaDB) ExportSignatures(filter *types.SignatureFilter, includeText bool, export func(signature *types.UserSignature) error) (err error) {
+	rows, err := p.db.Query(SqlExportSignatures, append(signatureFilterArgs(filter), includeText)...)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	for rows.Next() {
+		signature := types.UserSignature{Evidence: &types.SigningEvidence{}}
+		err = rows.Scan(
+			&signature.Id,
+			&signature.User.Login,
+			&signature.User.Email,
+			&signature.User.GivenName,
+			&signature.TimeSigned,
+			&signature.CLAVersion,
+			&signature.CLATextUrl,
+			&signature.CLAText,
+			&signature.CLADocumentId,
+			&signature.CLATextSha256,
+			&signature.EmailVerified,
+			&signature.Evidence.IPAddress,
+			&signature.Evidence.UserAgent,
+			&signature.Evidence.GitHubUserId,
+			&signature.RevokedAt,
+		)
+		if err != nil {
+			return
+		}
+		if err = export(&signature); err != nil {
+			return
+		}
+	}
+	// a connection lost while reading must not end the export silently
+	return rows.Err()
+}
+
+// SqlImportSignature inserts an imported signature, unless the login already has an active signature of the CLA
+// version
+const SqlImportSignature = sqlInsertSignature + `
+		ON CONFLICT (lower(LoginName), ClaVersion) WHERE RevokedAt IS NULL DO NOTHING`
+
+// ImportSignatures inserts a batch of imported signatures within a single transaction, and returns the indexes of the
+// signatures that were not inserted because the login already signed the CLA version. A dry run inserts the
+// signatures the same way, and then rolls back the transaction.
+func (p *ClaDB) ImportSignatures(signatures []types.UserSignature, dryRun bool) (conflicts []int, err error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil || dryRun {
+			_ = tx.Rollback()
+		}
+	}()
+
+	for i, user := range signatures {
+		evidence := user.Evidence
+		if evidence == nil {
+			evidence = &types.SigningEvidence{}
+		}
+		var result sql.Result
+		result, err = tx.Exec(SqlImportSignature, user.User.Login, user.User.Email, user.User.GivenName, user.TimeSigned,
+			user.CLAVersion, user.CLATextUrl, nullableDocumentId(user.CLADocumentId), nullableSha256(user.CLATextSha256),
+			user.EmailVerified, evidence.IPAddress, evidence.UserAgent, nullableGitHubUserId(evidence.GitHubUserId))
+		if err != nil {
+			return nil, fmt.Errorf("failed to import signature of user: %s, claVersion: %s, error: %w", user.User.Login, user.CLAVersion, err)
+		}
+		var rowsAffected int64
+		if rowsAffected, err = result.RowsAffected(); err != nil {
+			return nil, err
+		}
+		if rowsAffected == 0 {
+			conflicts = append(conflicts, i)
+		}
+	}
+
+	if !dryRun {
+		err = tx.Commit()
+	}
+	return
+}
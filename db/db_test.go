@@ -20,9 +20,15 @@
 package db
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,6 +74,48 @@ func TestInsertSignatureErrorDuplicateSignature(t *testing.T) {
 	assert.Error(t, db.InsertSignature(&user), forcedError.Error())
 }
 
+func TestInsertSignatureEmailVerified(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	user := types.UserSignature{
+		User:          types.User{Login: "myUserId", Email: "myEmail", GivenName: "myGivenName"},
+		CLAVersion:    mockCLAVersion,
+		CLATextUrl:    mockCLATextUrl,
+		CLADocumentId: mockCLADocumentId,
+		CLATextSha256: mockCLATextSha256,
+		EmailVerified: true,
+	}
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertSignature)).
+		WithArgs(user.User.Login, user.User.Email, user.User.GivenName, AnyTime{}, user.CLAVersion, user.CLATextUrl,
+			user.CLADocumentId, user.CLATextSha256, true, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, db.InsertSignature(&user))
+}
+
+func TestInsertSignatureEvidence(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	user := types.UserSignature{
+		User:          types.User{Login: "myUserId", Email: "myEmail", GivenName: "myGivenName"},
+		CLAVersion:    mockCLAVersion,
+		CLATextUrl:    mockCLATextUrl,
+		CLADocumentId: mockCLADocumentId,
+		CLATextSha256: mockCLATextSha256,
+		Evidence:      &types.SigningEvidence{IPAddress: "10.0.0.1", UserAgent: "myUserAgent", GitHubUserId: 42},
+	}
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertSignature)).
+		WithArgs(user.User.Login, user.User.Email, user.User.GivenName, AnyTime{}, user.CLAVersion, user.CLATextUrl,
+			user.CLADocumentId, user.CLATextSha256, false, "10.0.0.1", "myUserAgent", 42).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, db.InsertSignature(&user))
+}
+
 // exclude parent 'db' directory for tests
 const testMigrateSourceURL = "file://migrations"
 
@@ -122,7 +170,7 @@ func TestHasAuthorSignedTheClaQueryError(t *testing.T) {
 	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectUserSignature)).
 		WillReturnError(forcedError)
 
-	hasSigned, _, err := db.HasAuthorSignedTheCla("", "")
+	hasSigned, _, err := db.HasAuthorSignedTheCla("", 0, "")
 	assert.EqualError(t, err, forcedError.Error())
 	assert.False(t, hasSigned)
 }
@@ -130,6 +178,10 @@ func TestHasAuthorSignedTheClaQueryError(t *testing.T) {
 const mockCLAVersion = "myClaVersion"
 const mockCLATextUrl = "https://my.url/cla.text"
 const mockCLAText = "This is a CLA"
+const mockCLADocumentId = 42
+const mockCLATextSha256 = "7ea74886463d1fb5ef51849160704bb773434f34fcbfa4157b95cd2cbd1d94f4"
+
+var signatureColumns = []string{"LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText", "ClaDocumentId", "ClaTextSha256", "EmailVerified", "IpAddress", "UserAgent", "GitHubUserId"}
 
 func TestHasAuthorSignedTheClaReadRowError(t *testing.T) {
 	mock, db, closeDbFunc := SetupMockDB(t)
@@ -137,11 +189,11 @@ func TestHasAuthorSignedTheClaReadRowError(t *testing.T) {
 
 	loginName := "myLoginName"
 	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectUserSignature)).
-		WithArgs(loginName, mockCLAVersion).
-		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText"}).
-			FromCSVString(`myLoginName,myEmail,myGivenName,INVALID_TIME_VALUE_TO_CAUSE_ROW_READ_ERROR,` + mockCLAVersion + `,` + mockCLATextUrl + `,` + mockCLAText))
+		WithArgs(loginName, mockCLAVersion, 42).
+		WillReturnRows(sqlmock.NewRows(signatureColumns).
+			FromCSVString(`myLoginName,myEmail,myGivenName,INVALID_TIME_VALUE_TO_CAUSE_ROW_READ_ERROR,` + mockCLAVersion + `,` + mockCLATextUrl + `,` + mockCLAText + `,42,` + mockCLATextSha256 + `,false,,,0`))
 
-	hasSigned, foundSignature, err := db.HasAuthorSignedTheCla(loginName, mockCLAVersion)
+	hasSigned, foundSignature, err := db.HasAuthorSignedTheCla(loginName, 42, mockCLAVersion)
 	assert.EqualError(t, err, "sql: Scan error on column index 3, name \"SignedAt\": unsupported Scan, storing driver.Value type []uint8 into type *time.Time")
 	assert.True(t, hasSigned)
 	assert.NotNil(t, foundSignature)
@@ -151,20 +203,20 @@ func TestHasAuthorSignedTheClaTrue(t *testing.T) {
 	mock, db, closeDbFunc := SetupMockDB(t)
 	defer closeDbFunc()
 
-	rs := sqlmock.NewRows([]string{"LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText"})
+	rs := sqlmock.NewRows(signatureColumns)
 	loginName := "myLoginName"
 	email := "myEmail"
 	givenName := "myGivenName"
 	now := time.Now()
 	claVersion := "myCLAVersion"
-	rs.AddRow(loginName, email, givenName, now, claVersion, mockCLATextUrl, mockCLAText)
+	rs.AddRow(loginName, email, givenName, now, claVersion, mockCLATextUrl, mockCLAText, mockCLADocumentId, mockCLATextSha256, false, "10.0.0.1", "myUserAgent", 42)
 	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectUserSignature)).
-		WithArgs(loginName, mockCLAVersion).
+		WithArgs(loginName, mockCLAVersion, 42).
 		WillReturnRows(rs)
 
 	committer := github.User{}
 	committer.Login = &loginName
-	hasSigned, foundSignature, err := db.HasAuthorSignedTheCla(loginName, mockCLAVersion)
+	hasSigned, foundSignature, err := db.HasAuthorSignedTheCla(loginName, 42, mockCLAVersion)
 	assert.NoError(t, err)
 	assert.True(t, hasSigned)
 	assert.Equal(t, loginName, foundSignature.User.Login)
@@ -174,6 +226,130 @@ func TestHasAuthorSignedTheClaTrue(t *testing.T) {
 	assert.Equal(t, claVersion, foundSignature.CLAVersion)
 	assert.Equal(t, mockCLATextUrl, foundSignature.CLATextUrl)
 	assert.Equal(t, mockCLAText, foundSignature.CLAText)
+	assert.Equal(t, int64(mockCLADocumentId), foundSignature.CLADocumentId)
+	assert.Equal(t, mockCLATextSha256, foundSignature.CLATextSha256)
+	assert.Equal(t, &types.SigningEvidence{IPAddress: "10.0.0.1", UserAgent: "myUserAgent", GitHubUserId: 42}, foundSignature.Evidence)
+}
+
+func TestUpdateSignatureLogin(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlUpdateSignatureLogin)).
+		WithArgs(42, "myNewLogin").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.UpdateSignatureLogin(42, "myNewLogin"))
+}
+
+func TestUpdateSignatureLoginMissingUserId(t *testing.T) {
+	_, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	assert.EqualError(t, db.UpdateSignatureLogin(0, "myNewLogin"), "missing github user id of login: myNewLogin")
+}
+
+func TestUpdateSignatureLoginError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced update error")
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlUpdateSignatureLogin)).
+		WithArgs(42, "myNewLogin").
+		WillReturnError(forcedError)
+
+	assert.EqualError(t, db.UpdateSignatureLogin(42, "myNewLogin"), forcedError.Error())
+}
+
+func TestGetAuthorSignatures(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	rs := sqlmock.NewRows(signatureColumns).
+		AddRow("myLoginName", "myEmail", "myGivenName", now, "2.0", mockCLATextUrl, mockCLAText, mockCLADocumentId, mockCLATextSha256, true, "", "", 42).
+		AddRow("myLoginName", "myEmail", "myGivenName", now.Add(-time.Hour), "1.0", mockCLATextUrl, mockCLAText, 0, "", false, "", "", 0)
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectAuthorSignatures)).
+		WithArgs("myLoginName", 42).
+		WillReturnRows(rs)
+
+	signatures, err := db.GetAuthorSignatures("myLoginName", 42)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(signatures))
+	assert.Equal(t, "2.0", signatures[0].CLAVersion)
+	assert.Equal(t, "1.0", signatures[1].CLAVersion)
+	assert.Equal(t, now.Add(-time.Hour), signatures[1].TimeSigned)
+}
+
+func TestGetAuthorSignaturesQueryError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced SQL query error")
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectAuthorSignatures)).
+		WithArgs("myLoginName", 0).
+		WillReturnError(forcedError)
+
+	signatures, err := db.GetAuthorSignatures("myLoginName", 0)
+	assert.EqualError(t, err, forcedError.Error())
+	assert.Nil(t, signatures)
+}
+
+func TestHasEmailSignedTheClaEmptyEmail(t *testing.T) {
+	_, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	hasSigned, foundSignature, err := db.HasEmailSignedTheCla("", mockCLAVersion)
+	assert.NoError(t, err)
+	assert.False(t, hasSigned)
+	assert.Nil(t, foundSignature)
+}
+
+func TestHasEmailSignedTheClaNotFound(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	email := "me@somewhere.tld"
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectEmailSignature)).
+		WithArgs(email, mockCLAVersion).
+		WillReturnRows(sqlmock.NewRows(signatureColumns))
+
+	hasSigned, foundSignature, err := db.HasEmailSignedTheCla(email, mockCLAVersion)
+	assert.NoError(t, err)
+	assert.False(t, hasSigned)
+	assert.Nil(t, foundSignature)
+}
+
+func TestHasEmailSignedTheClaQueryError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced SQL query error")
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectEmailSignature)).
+		WillReturnError(forcedError)
+
+	hasSigned, _, err := db.HasEmailSignedTheCla("me@somewhere.tld", mockCLAVersion)
+	assert.EqualError(t, err, forcedError.Error())
+	assert.False(t, hasSigned)
+}
+
+func TestHasEmailSignedTheClaTrue(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	loginName := "myLoginName"
+	email := "Me@Somewhere.tld"
+	now := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectEmailSignature)).
+		WithArgs(email, mockCLAVersion).
+		WillReturnRows(sqlmock.NewRows(signatureColumns).
+			AddRow(loginName, "me@somewhere.tld", "myGivenName", now, mockCLAVersion, mockCLATextUrl, mockCLAText, mockCLADocumentId, mockCLATextSha256, true, "", "", 0))
+
+	hasSigned, foundSignature, err := db.HasEmailSignedTheCla(email, mockCLAVersion)
+	assert.NoError(t, err)
+	assert.True(t, hasSigned)
+	assert.Equal(t, loginName, foundSignature.User.Login)
+	assert.Equal(t, now, foundSignature.TimeSigned)
 }
 
 func TestStorePRAuthorsMissingSignatureInsertError(t *testing.T) {
@@ -215,7 +391,7 @@ func TestStorePRAuthorsMissingSignatureInsertError(t *testing.T) {
 		WillReturnError(forcedError)
 
 	assert.EqualError(t, db.StorePRAuthorsMissingSignature(&evalInfo, time.Now()),
-		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoName, pullRequestID, forcedError))
+		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoOwner, repoName, pullRequestID, forcedError))
 }
 
 func TestStorePRAuthorsMissingSignatureInsertErrorRowExists(t *testing.T) {
@@ -258,11 +434,11 @@ func TestStorePRAuthorsMissingSignatureInsertErrorRowExists(t *testing.T) {
 
 	forcedError := errors.New("forced insert error")
 	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlSelectPR)).
-		WithArgs(evalInfo.RepoName, evalInfo.PRNumber).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
 		WillReturnError(forcedError)
 
 	assert.EqualError(t, db.StorePRAuthorsMissingSignature(&evalInfo, time.Now()),
-		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoName, pullRequestID, forcedError))
+		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoOwner, repoName, pullRequestID, forcedError))
 }
 
 func TestStorePRAuthorsMissingSignatureQueryParentPRError(t *testing.T) {
@@ -304,11 +480,11 @@ func TestStorePRAuthorsMissingSignatureQueryParentPRError(t *testing.T) {
 		WillReturnError(forcedRowExistsError)
 
 	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlSelectPR)).
-		WithArgs(evalInfo.RepoName, evalInfo.PRNumber).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
 	assert.EqualError(t, db.StorePRAuthorsMissingSignature(&evalInfo, time.Now()),
-		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoName, pullRequestID, errors.New(errMsgInsertedRowExists)))
+		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoOwner, repoName, pullRequestID, errors.New(errMsgInsertedRowExists)))
 }
 
 func TestStorePRAuthorsMissingSignatureInsertEmptyParentUUID(t *testing.T) {
@@ -351,11 +527,11 @@ func TestStorePRAuthorsMissingSignatureInsertEmptyParentUUID(t *testing.T) {
 
 	parentUUID := ""
 	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlSelectPR)).
-		WithArgs(evalInfo.RepoName, evalInfo.PRNumber).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(parentUUID))
 
 	assert.EqualError(t, db.StorePRAuthorsMissingSignature(&evalInfo, time.Now()),
-		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoName, pullRequestID, errors.New("empty parentUUID")))
+		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoOwner, repoName, pullRequestID, errors.New("empty parentUUID")))
 }
 
 func TestStorePRAuthorsMissingSignatureParentInsertError(t *testing.T) {
@@ -397,7 +573,7 @@ func TestStorePRAuthorsMissingSignatureParentInsertError(t *testing.T) {
 		WillReturnError(forcedError)
 
 	assert.EqualError(t, db.StorePRAuthorsMissingSignature(&evalInfo, time.Now()),
-		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoName, pullRequestID, forcedError))
+		fmt.Sprintf(msgTemplateErrInsertPRMissing, repoOwner, repoName, pullRequestID, forcedError))
 }
 
 func TestStorePRAuthorsMissingSignatureUserInsertError(t *testing.T) {
@@ -751,3 +927,837 @@ func TestRemovePRsForUsersEmptyPRUUID(t *testing.T) {
 
 	assert.NoError(t, db.RemovePRsForUsers(nil, &types.EvaluationInfo{}))
 }
+
+func TestRemoveClosedPRRemoveUsersError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	evalInfo := types.EvaluationInfo{RepoOwner: "myOwner", RepoName: "myRepo", PRNumber: 5}
+	forcedError := errors.New("forced delete unsigned users db error")
+	mock.ExpectBegin()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlDeleteUnsignedUsersForClosedPR)).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
+		WillReturnError(forcedError)
+	mock.ExpectRollback()
+
+	assert.EqualError(t, db.RemoveClosedPR(&evalInfo), forcedError.Error())
+}
+
+func TestRemoveClosedPR(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	evalInfo := types.EvaluationInfo{RepoOwner: "myOwner", RepoName: "myRepo", PRNumber: 5}
+	mock.ExpectBegin()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlDeleteUnsignedUsersForClosedPR)).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlDeleteClosedPR)).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlDeleteSignedPRUsers)).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, db.RemoveClosedPR(&evalInfo))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorePRSignedAuthors(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	evalInfo := types.EvaluationInfo{RepoOwner: "myOwner", RepoName: "myRepo", Sha: "mySha", PRNumber: 5, AppId: 1, InstallId: 2}
+	checkedAt := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlDeleteSignedPRUsers)).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlInsertSignedPRUser)).
+		WithArgs(evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.Sha, evalInfo.PRNumber, evalInfo.AppId, evalInfo.InstallId, "john", checkedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, db.StorePRSignedAuthors(&evalInfo, []types.UserSignature{{User: types.User{Login: "john"}}}, checkedAt))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStorePRSignedAuthorsError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced insert error")
+	mock.ExpectBegin()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlDeleteSignedPRUsers)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlInsertSignedPRUser)).
+		WillReturnError(forcedError)
+	mock.ExpectRollback()
+
+	err := db.StorePRSignedAuthors(&types.EvaluationInfo{}, []types.UserSignature{{User: types.User{Login: "john"}}}, time.Now())
+	assert.EqualError(t, err, forcedError.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetPRsForSignedAuthor(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectPRsForSignedAuthor)).
+		WithArgs("John").
+		WillReturnRows(sqlmock.NewRows([]string{"RepoOwner", "RepoName", "sha", "PRNumber", "AppID", "InstallID"}).
+			AddRow("myOwner", "myRepo", "mySha", 5, 1, 2))
+
+	evalInfos, err := db.GetPRsForSignedAuthor("John")
+	assert.NoError(t, err)
+	assert.Equal(t, []types.EvaluationInfo{{RepoOwner: "myOwner", RepoName: "myRepo", Sha: "mySha", PRNumber: 5, AppId: 1, InstallId: 2}}, evalInfos)
+}
+
+func TestInsertCorporateSignatureInvalidMemberType(t *testing.T) {
+	_, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	corp := types.CorporateSignature{
+		Members: []types.CorporateMember{{Type: "bogus", Value: "myValue"}},
+	}
+	assert.EqualError(t, db.InsertCorporateSignature(&corp), fmt.Sprintf(msgTemplateErrInvalidCorporateMemberType, "bogus"))
+}
+
+func TestInsertCorporateSignatureInsertError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	corp := types.CorporateSignature{
+		Company:       "myCompany",
+		Signatory:     types.User{Login: "myLogin", Email: "myEmail", GivenName: "myGivenName"},
+		CLAVersion:    mockCLAVersion,
+		CLATextUrl:    mockCLATextUrl,
+		CLAText:       mockCLAText,
+		CLADocumentId: mockCLADocumentId,
+		CLATextSha256: mockCLATextSha256,
+	}
+
+	forcedError := errors.New("forced SQL insert error")
+	mock.ExpectBegin()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlInsertCorporateSignature)).
+		WithArgs(corp.Company, corp.Signatory.Login, corp.Signatory.Email, corp.Signatory.GivenName, AnyTime{}, corp.CLAVersion, corp.CLATextUrl, corp.CLADocumentId, corp.CLATextSha256).
+		WillReturnError(forcedError)
+	mock.ExpectRollback()
+
+	assert.EqualError(t, db.InsertCorporateSignature(&corp),
+		fmt.Sprintf(msgTemplateErrInsertCorporateSignatureDuplicate, corp.Company, corp.CLAVersion, forcedError))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertCorporateSignature(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	corp := types.CorporateSignature{
+		Company:       "myCompany",
+		Signatory:     types.User{Login: "myLogin", Email: "myEmail", GivenName: "myGivenName"},
+		CLAVersion:    mockCLAVersion,
+		CLATextUrl:    mockCLATextUrl,
+		CLAText:       mockCLAText,
+		CLADocumentId: mockCLADocumentId,
+		CLATextSha256: mockCLATextSha256,
+		Members: []types.CorporateMember{
+			{Type: types.CorporateMemberTypeLogin, Value: "myMemberLogin"},
+			{Type: types.CorporateMemberTypeDomain, Value: "ACME.tld"},
+		},
+	}
+
+	corpUUID := "myCorpUUID"
+	mock.ExpectBegin()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlInsertCorporateSignature)).
+		WithArgs(corp.Company, corp.Signatory.Login, corp.Signatory.Email, corp.Signatory.GivenName, AnyTime{}, corp.CLAVersion, corp.CLATextUrl, corp.CLADocumentId, corp.CLATextSha256).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}).AddRow(corpUUID))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertCorporateMember)).
+		WithArgs(corpUUID, types.CorporateMemberTypeLogin, "mymemberlogin").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertCorporateMember)).
+		WithArgs(corpUUID, types.CorporateMemberTypeDomain, "acme.tld").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, db.InsertCorporateSignature(&corp))
+	assert.Equal(t, corpUUID, corp.Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var corporateSignatureColumns = []string{"Id", "CompanyName", "SignatoryLogin", "SignatoryEmail", "SignatoryName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText", "ClaDocumentId", "ClaTextSha256", "Active"}
+
+func TestGetCorporateSignatureNotFound(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectCorporateSignature)).
+		WithArgs("myCompany", mockCLAVersion).
+		WillReturnRows(sqlmock.NewRows(corporateSignatureColumns))
+
+	corp, err := db.GetCorporateSignature("myCompany", mockCLAVersion)
+	assert.NoError(t, err)
+	assert.Nil(t, corp)
+}
+
+func TestGetCorporateSignature(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectCorporateSignature)).
+		WithArgs("myCompany", mockCLAVersion).
+		WillReturnRows(sqlmock.NewRows(corporateSignatureColumns).
+			AddRow("myCorpUUID", "myCompany", "myLogin", "myEmail", "myGivenName", now, mockCLAVersion, mockCLATextUrl, mockCLAText, mockCLADocumentId, mockCLATextSha256, true))
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlSelectCorporateMembers)).
+		WithArgs("myCorpUUID").
+		WillReturnRows(sqlmock.NewRows([]string{"MemberType", "MemberValue"}).
+			AddRow(types.CorporateMemberTypeDomain, "acme.tld").
+			AddRow(types.CorporateMemberTypeLogin, "myMemberLogin"))
+
+	corp, err := db.GetCorporateSignature("myCompany", mockCLAVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, &types.CorporateSignature{
+		Id:            "myCorpUUID",
+		Company:       "myCompany",
+		Signatory:     types.User{Login: "myLogin", Email: "myEmail", GivenName: "myGivenName"},
+		CLAVersion:    mockCLAVersion,
+		TimeSigned:    now,
+		CLATextUrl:    mockCLATextUrl,
+		CLAText:       mockCLAText,
+		CLADocumentId: mockCLADocumentId,
+		CLATextSha256: mockCLATextSha256,
+		Active:        true,
+		Members: []types.CorporateMember{
+			{Type: types.CorporateMemberTypeDomain, Value: "acme.tld"},
+			{Type: types.CorporateMemberTypeLogin, Value: "myMemberLogin"},
+		},
+	}, corp)
+}
+
+func TestAddCorporateMemberLowerCasesDomain(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertCorporateMember)).
+		WithArgs("myCorpUUID", types.CorporateMemberTypeDomain, "acme.tld").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.AddCorporateMember("myCorpUUID", &types.CorporateMember{Type: types.CorporateMemberTypeDomain, Value: "Acme.TLD"}))
+}
+
+func TestNormalizeCorporateMember(t *testing.T) {
+	member := types.CorporateMember{Type: types.CorporateMemberTypeDomain, Value: " @Acme.TLD"}
+	assert.NoError(t, normalizeCorporateMember(&member))
+	assert.Equal(t, "acme.tld", member.Value)
+
+	member = types.CorporateMember{Type: types.CorporateMemberTypeLogin, Value: "myMemberLogin"}
+	assert.NoError(t, normalizeCorporateMember(&member))
+	assert.Equal(t, "mymemberlogin", member.Value)
+
+	for _, empty := range []types.CorporateMember{
+		{Type: types.CorporateMemberTypeDomain, Value: ""},
+		{Type: types.CorporateMemberTypeDomain, Value: "@"},
+		{Type: types.CorporateMemberTypeLogin, Value: " "},
+	} {
+		assert.EqualError(t, normalizeCorporateMember(&empty), fmt.Sprintf(msgTemplateErrEmptyCorporateMember, empty.Type))
+	}
+}
+
+func TestInsertCorporateSignatureEmptyMember(t *testing.T) {
+	_, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	corp := types.CorporateSignature{
+		Members: []types.CorporateMember{{Type: types.CorporateMemberTypeDomain, Value: ""}},
+	}
+	assert.EqualError(t, db.InsertCorporateSignature(&corp), fmt.Sprintf(msgTemplateErrEmptyCorporateMember, types.CorporateMemberTypeDomain))
+}
+
+func TestRemoveCorporateMemberInvalidMemberType(t *testing.T) {
+	_, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	assert.EqualError(t, db.RemoveCorporateMember("myCorpUUID", &types.CorporateMember{Type: "bogus"}),
+		fmt.Sprintf(msgTemplateErrInvalidCorporateMemberType, "bogus"))
+}
+
+func TestRemoveCorporateMember(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlDeleteCorporateMember)).
+		WithArgs("myCorpUUID", types.CorporateMemberTypeLogin, "mymemberlogin").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.RemoveCorporateMember("myCorpUUID", &types.CorporateMember{Type: types.CorporateMemberTypeLogin, Value: "myMemberLogin"}))
+}
+
+func TestSetCorporateSignatureActiveNotFound(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlUpdateCorporateSignatureActive)).
+		WithArgs("myCorpUUID", false).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.EqualError(t, db.SetCorporateSignatureActive("myCorpUUID", false),
+		fmt.Sprintf(msgTemplateErrCorporateSignatureNotFound, "myCorpUUID"))
+}
+
+func TestHasCorporateSignedTheClaQueryError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced SQL query error")
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectCorporateCoverage)).
+		WillReturnError(forcedError)
+
+	isCovered, _, err := db.HasCorporateSignedTheCla("", "", "")
+	assert.EqualError(t, err, forcedError.Error())
+	assert.False(t, isCovered)
+}
+
+func TestHasCorporateSignedTheClaByDomain(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectCorporateCoverage)).
+		WithArgs(mockCLAVersion, "myLogin", "acme.tld").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "CompanyName", "SignatoryLogin", "SignatoryEmail", "SignatoryName", "SignedAt", "ClaVersion", "ClaTextUrl", "Active"}).
+			AddRow("myCorpUUID", "myCompany", "mySignatory", "myEmail", "myGivenName", now, mockCLAVersion, mockCLATextUrl, true))
+
+	isCovered, corp, err := db.HasCorporateSignedTheCla("myLogin", "me@Acme.tld", mockCLAVersion)
+	assert.NoError(t, err)
+	assert.True(t, isCovered)
+	assert.Equal(t, "myCompany", corp.Company)
+	assert.Equal(t, now, corp.TimeSigned)
+}
+
+func TestRevokeSignatureNotFound(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	revocation := types.SignatureRevocation{Login: "myLogin", CLAVersion: mockCLAVersion, Reason: "myReason", RevokedBy: "myAdmin", RevokedAt: time.Now()}
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlRevokeSignature)).
+		WithArgs(revocation.Login, revocation.CLAVersion, revocation.RevokedAt, revocation.RevokedBy, revocation.Reason).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.EqualError(t, db.RevokeSignature(&revocation),
+		fmt.Sprintf(msgTemplateErrRevokeSignatureNotFound, revocation.Login, revocation.CLAVersion))
+}
+
+func TestRevokeSignature(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	revocation := types.SignatureRevocation{Login: "myLogin", CLAVersion: mockCLAVersion, Reason: "myReason", RevokedBy: "myAdmin", RevokedAt: time.Now()}
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlRevokeSignature)).
+		WithArgs(revocation.Login, revocation.CLAVersion, revocation.RevokedAt, revocation.RevokedBy, revocation.Reason).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.RevokeSignature(&revocation))
+}
+
+func TestInsertSignatureAfterRevocation(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	revocation := types.SignatureRevocation{Login: "myLogin", CLAVersion: mockCLAVersion, Reason: "myReason", RevokedBy: "myAdmin", RevokedAt: time.Now()}
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlRevokeSignature)).
+		WithArgs(revocation.Login, revocation.CLAVersion, revocation.RevokedAt, revocation.RevokedBy, revocation.Reason).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertSignature)).
+		WithArgs("myLogin", "", "", AnyTime{}, mockCLAVersion, mockCLATextUrl, mockCLADocumentId, mockCLATextSha256, false, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(t, db.RevokeSignature(&revocation))
+	assert.NoError(t, db.InsertSignature(&types.UserSignature{
+		User:          types.User{Login: "myLogin"},
+		CLAVersion:    mockCLAVersion,
+		CLATextUrl:    mockCLATextUrl,
+		CLADocumentId: mockCLADocumentId,
+		CLATextSha256: mockCLATextSha256,
+		TimeSigned:    time.Now(),
+	}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestMigrationsSignatureKeyIgnoresRevoked checks the unique key of signatures, as created by the latest migration,
+// does not cover revoked signatures, so a signer can sign a CLA version again after a revocation
+func TestMigrationsSignatureKeyIgnoresRevoked(t *testing.T) {
+	upMigrations, err := filepath.Glob("migrations/*.up.sql")
+	assert.NoError(t, err)
+	sort.Strings(upMigrations)
+
+	createIndex := regexp.MustCompile(`CREATE UNIQUE INDEX \w+ ON signatures ([^;]*);`)
+	signatureKey := ""
+	for _, migration := range upMigrations {
+		content, err := os.ReadFile(migration)
+		assert.NoError(t, err)
+		for _, match := range createIndex.FindAllStringSubmatch(string(content), -1) {
+			signatureKey = match[1]
+		}
+	}
+	assert.Equal(t, "(lower(LoginName), ClaVersion)\n    WHERE RevokedAt IS NULL", signatureKey)
+	assert.Contains(t, SqlImportSignature, "ON CONFLICT (lower(LoginName), ClaVersion) WHERE RevokedAt IS NULL")
+}
+
+func TestSetCLAVersionExpiry(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	expiresAt := time.Now()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlUpsertCLAVersionExpiry)).
+		WithArgs(mockCLAVersion, expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.SetCLAVersionExpiry(&types.CLAVersionExpiry{CLAVersion: mockCLAVersion, ExpiresAt: &expiresAt}))
+}
+
+func TestSetCLAVersionExpiryClear(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlDeleteCLAVersionExpiry)).
+		WithArgs(mockCLAVersion).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.SetCLAVersionExpiry(&types.CLAVersionExpiry{CLAVersion: mockCLAVersion}))
+}
+
+var catalogColumns = []string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "ClaDocumentId", "ClaTextSha256", "ClaText"}
+
+func TestGetCLADocumentForRepoNotMapped(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectCLADocumentForRepo)).
+		WithArgs("myOwner", "myRepo", now).
+		WillReturnRows(sqlmock.NewRows(catalogColumns))
+
+	doc, err := db.GetCLADocumentForRepo("myOwner", "myRepo", now)
+	assert.NoError(t, err)
+	assert.Nil(t, doc)
+}
+
+func TestGetCLADocumentForRepo(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	effectiveAt := now.Add(-time.Hour)
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectCLADocumentForRepo)).
+		WithArgs("myOwner", "myRepo", now).
+		WillReturnRows(sqlmock.NewRows(catalogColumns).
+			AddRow("icla", mockCLAVersion, mockCLATextUrl, effectiveAt, 0, "", ""))
+
+	doc, err := db.GetCLADocumentForRepo("myOwner", "myRepo", now)
+	assert.NoError(t, err)
+	assert.Equal(t, &types.CLADocument{Id: "icla", CLAVersion: mockCLAVersion, CLATextUrl: mockCLATextUrl, EffectiveAt: effectiveAt}, doc)
+}
+
+func TestSetCLADocumentMappingLowerCase(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlUpsertCLADocumentMapping)).
+		WithArgs("myowner", "", "icla").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.SetCLADocumentMapping(&types.CLADocumentMapping{RepoOwner: "MyOwner", DocumentId: "icla"}))
+}
+
+func TestStoreCLADocument(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	createdAt := time.Now().Add(-time.Hour)
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlUpsertStoredCLADocument)).
+		WithArgs(mockCLAVersion, mockCLATextSha256, mockCLATextUrl, mockCLAText, AnyTime{}).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "ClaTextUrl", "CreatedAt"}).
+			AddRow(mockCLADocumentId, "https://first.url/cla.text", createdAt))
+
+	stored := &types.StoredCLADocument{CLAVersion: mockCLAVersion, CLATextUrl: mockCLATextUrl, CLAText: mockCLAText}
+	assert.NoError(t, db.StoreCLADocument(stored))
+	// the same text was stored before, from another url
+	assert.Equal(t, &types.StoredCLADocument{
+		Id:            mockCLADocumentId,
+		CLAVersion:    mockCLAVersion,
+		CLATextSha256: mockCLATextSha256,
+		CLATextUrl:    "https://first.url/cla.text",
+		CLAText:       mockCLAText,
+		CreatedAt:     createdAt,
+	}, stored)
+}
+
+func TestGetStoredCLADocumentByUrlNotStored(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectStoredCLADocumentByUrl)).
+		WithArgs(mockCLAVersion, mockCLATextUrl).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}))
+
+	stored, err := db.GetStoredCLADocumentByUrl(mockCLAVersion, mockCLATextUrl)
+	assert.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestUpsertCLADocumentStoresText(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	effectiveAt := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlUpsertStoredCLADocument)).
+		WithArgs(mockCLAVersion, mockCLATextSha256, "", mockCLAText, AnyTime{}).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "ClaTextUrl", "CreatedAt"}).AddRow(mockCLADocumentId, "", effectiveAt))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlUpsertCLADocument)).
+		WithArgs("icla", mockCLAVersion, "", mockCLADocumentId, effectiveAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	doc := &types.CLADocument{Id: "icla", CLAVersion: mockCLAVersion, CLAText: mockCLAText, EffectiveAt: effectiveAt}
+	assert.NoError(t, db.UpsertCLADocument(doc))
+	assert.Equal(t, int64(mockCLADocumentId), doc.StoredDocumentId)
+	assert.Equal(t, mockCLATextSha256, doc.CLATextSha256)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCLADocumentsByVersion(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	effectiveAt := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectCLADocumentsByVersion)).
+		WithArgs(mockCLAVersion).
+		WillReturnRows(sqlmock.NewRows(catalogColumns).
+			AddRow("icla", mockCLAVersion, "", effectiveAt, mockCLADocumentId, mockCLATextSha256, mockCLAText))
+
+	docs, err := db.GetCLADocumentsByVersion(mockCLAVersion)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.CLADocument{{
+		Id:               "icla",
+		CLAVersion:       mockCLAVersion,
+		CLAText:          mockCLAText,
+		EffectiveAt:      effectiveAt,
+		StoredDocumentId: mockCLADocumentId,
+		CLATextSha256:    mockCLATextSha256,
+	}}, docs)
+}
+
+func TestInsertResignCampaign(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	campaign := &types.ResignCampaign{DocumentId: "icla", CLAVersion: "2.0", ActiveSince: now, StartedBy: "myInfoUser", StartedAt: now,
+		Contacts: []types.ResignContact{{Login: "john", Email: "john@doe.tld", GitHubUserId: 42, SignedCLAVersion: "1.0"}}}
+	mock.ExpectBegin()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlInsertResignCampaign)).
+		WithArgs("icla", "2.0", now, "myInfoUser", now).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}).AddRow("myCampaignId"))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertResignContact)).
+		WithArgs("myCampaignId", "john", 42, "john@doe.tld", "1.0", nil, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, db.InsertResignCampaign(campaign))
+	assert.Equal(t, "myCampaignId", campaign.Id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertResignCampaignContactError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	campaign := &types.ResignCampaign{CLAVersion: "2.0", ActiveSince: now, StartedBy: "myInfoUser", StartedAt: now,
+		Contacts: []types.ResignContact{{Login: "john", Email: "john@doe.tld", SignedCLAVersion: "1.0"}}}
+	mock.ExpectBegin()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(sqlInsertResignCampaign)).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}).AddRow("myCampaignId"))
+	forcedError := errors.New("forced SQL insert error")
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlInsertResignContact)).
+		WillReturnError(forcedError)
+	mock.ExpectRollback()
+
+	assert.EqualError(t, db.InsertResignCampaign(campaign), forcedError.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetResignCandidates(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectResignCandidates)).
+		WithArgs("2.0", now, "icla").
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "ClaVersion"}).
+			AddRow("john", "john@doe.tld", 42, "1.0"))
+
+	candidates, err := db.GetResignCandidates("icla", "2.0", now)
+	assert.NoError(t, err)
+	assert.Equal(t, []types.ResignContact{{Login: "john", Email: "john@doe.tld", GitHubUserId: 42, SignedCLAVersion: "1.0"}}, candidates)
+}
+
+func TestGetResignCandidatesQueryError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced SQL query error")
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectResignCandidates)).
+		WillReturnError(forcedError)
+
+	candidates, err := db.GetResignCandidates("", "2.0", time.Now())
+	assert.EqualError(t, err, forcedError.Error())
+	assert.Nil(t, candidates)
+}
+
+func TestGetResignCandidatesRowsError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced SQL rows error")
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectResignCandidates)).
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "ClaVersion"}).
+			AddRow("john", "john@doe.tld", 42, "1.0").
+			RowError(0, forcedError))
+
+	_, err := db.GetResignCandidates("", "2.0", time.Now())
+	assert.EqualError(t, err, forcedError.Error())
+}
+
+func TestUpdateResignContact(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(sqlUpdateResignContact)).
+		WithArgs("myCampaignId", "john", now, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, db.UpdateResignContact("myCampaignId", &types.ResignContact{
+		Login: "john", Email: "john@doe.tld", SignedCLAVersion: "1.0", ContactedAt: &now,
+	}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetResignCampaignNotFound(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectResignCampaign)).
+		WithArgs("myCampaignId").
+		WillReturnError(sql.ErrNoRows)
+
+	campaign, err := db.GetResignCampaign("myCampaignId")
+	assert.NoError(t, err)
+	assert.Nil(t, campaign)
+}
+
+func TestGetResignCampaignContactsError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectResignCampaign)).
+		WithArgs("myCampaignId").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "DocumentId", "ClaVersion", "ActiveSince", "StartedBy", "StartedAt"}).
+			AddRow("myCampaignId", "", "2.0", now, "myInfoUser", now))
+	forcedError := errors.New("forced SQL query error")
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlSelectResignContacts)).
+		WithArgs("myCampaignId").
+		WillReturnError(forcedError)
+
+	campaign, err := db.GetResignCampaign("myCampaignId")
+	assert.EqualError(t, err, forcedError.Error())
+	assert.Nil(t, campaign)
+}
+
+var signatureListColumns = []string{"Id", "LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaDocumentId", "ClaTextSha256", "EmailVerified"}
+
+func TestSignatureListSql(t *testing.T) {
+	assert.True(t, strings.HasSuffix(signatureListSql("SignedAt", false, false), `
+		ORDER BY SignedAt ASC, signatures.Id ASC
+		LIMIT $7`))
+	assert.True(t, strings.HasSuffix(signatureListSql("lower(LoginName)", true, true), `
+		AND (lower(LoginName), signatures.Id) < ($7, $8)
+		ORDER BY lower(LoginName) DESC, signatures.Id DESC
+		LIMIT $9`))
+}
+
+func TestListSignaturesPages(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	signedAfter := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	signedAt := time.Date(2022, 2, 1, 12, 30, 0, 123456000, time.UTC)
+	query := &types.SignatureQuery{
+		SignatureFilter: types.SignatureFilter{
+			CLAVersion:  "2.0",
+			SignedAfter: &signedAfter,
+			LoginPrefix: "John_",
+			EmailDomain: "@Doe.tld",
+		},
+		SortBy: types.SignatureSortSignedAt,
+		Limit:  1,
+	}
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(signatureListSql("SignedAt", false, false))).
+		WithArgs("2.0", signedAfter, nil, `john\_`, "doe.tld", false, 2).
+		WillReturnRows(sqlmock.NewRows(signatureListColumns).
+			AddRow("id1", "john_a", "a@doe.tld", "A", signedAt, "2.0", mockCLATextUrl, 0, "", true).
+			AddRow("id2", "john_b", "b@doe.tld", "B", signedAt, "2.0", mockCLATextUrl, 0, "", true))
+
+	page, err := db.ListSignatures(query)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(page.Signatures))
+	assert.Equal(t, "id1", page.Signatures[0].Id)
+	assert.NotEmpty(t, page.NextCursor)
+
+	query.Cursor = page.NextCursor
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(signatureListSql("SignedAt", false, true))).
+		WithArgs("2.0", signedAfter, nil, `john\_`, "doe.tld", false, signedAt, "id1", 2).
+		WillReturnRows(sqlmock.NewRows(signatureListColumns).
+			AddRow("id2", "john_b", "b@doe.tld", "B", signedAt, "2.0", mockCLATextUrl, 0, "", true))
+
+	page, err = db.ListSignatures(query)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(page.Signatures))
+	assert.Equal(t, "id2", page.Signatures[0].Id)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestListSignaturesInvalidCursor(t *testing.T) {
+	_, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	query := &types.SignatureQuery{SortBy: types.SignatureSortLogin, Limit: 1, Cursor: "not a cursor"}
+	_, err := db.ListSignatures(query)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	// a cursor is only valid for the sort order it was made for
+	query.Cursor = encodeSignatureCursor(&types.SignatureQuery{SortBy: types.SignatureSortCLAVersion}, &types.UserSignature{Id: "id1"})
+	_, err = db.ListSignatures(query)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestListSignaturesInvalidSort(t *testing.T) {
+	_, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	_, err := db.ListSignatures(&types.SignatureQuery{SortBy: "Email; DROP TABLE signatures", Limit: 1})
+	assert.EqualError(t, err, "invalid sort: Email; DROP TABLE signatures")
+}
+
+func TestListSignaturesQueryError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced SQL query error")
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(signatureListSql("lower(LoginName)", true, false))).
+		WillReturnError(forcedError)
+
+	page, err := db.ListSignatures(&types.SignatureQuery{SortBy: types.SignatureSortLogin, Descending: true, Limit: 10})
+	assert.EqualError(t, err, forcedError.Error())
+	assert.Nil(t, page)
+}
+
+func TestCountSignatures(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlCountSignatures)).
+		WithArgs("", nil, nil, "", "", false).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+	count, err := db.CountSignatures(&types.SignatureFilter{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), count)
+}
+
+func TestExportSignaturesStops(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	now := time.Now()
+	mock.ExpectQuery(ConvertSqlToDbMockExpect(SqlExportSignatures)).
+		WithArgs("", nil, nil, "", "", true, true).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText", "ClaDocumentId", "ClaTextSha256", "EmailVerified", "IpAddress", "UserAgent", "GitHubUserId", "RevokedAt"}).
+			AddRow("id1", "john", "john@doe.tld", "John", now, "2.0", mockCLATextUrl, mockCLAText, mockCLADocumentId, mockCLATextSha256, true, "10.0.0.1", "myUserAgent", 42, now).
+			AddRow("id2", "jane", "jane@doe.tld", "Jane", now, "2.0", mockCLATextUrl, mockCLAText, mockCLADocumentId, mockCLATextSha256, true, "", "", 0, nil))
+
+	forcedError := errors.New("forced write error")
+	var exported []types.UserSignature
+	err := db.ExportSignatures(&types.SignatureFilter{IncludeRevoked: true}, true, func(signature *types.UserSignature) error {
+		exported = append(exported, *signature)
+		return forcedError
+	})
+	assert.EqualError(t, err, forcedError.Error())
+	assert.Equal(t, 1, len(exported))
+	assert.Equal(t, mockCLAText, exported[0].CLAText)
+	assert.Equal(t, &types.SigningEvidence{IPAddress: "10.0.0.1", UserAgent: "myUserAgent", GitHubUserId: 42}, exported[0].Evidence)
+	assert.Equal(t, &now, exported[0].RevokedAt)
+}
+
+func TestImportSignatures(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	signedAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	signatures := []types.UserSignature{
+		{User: types.User{Login: "john", Email: "john@doe.tld", GivenName: "John"}, CLAVersion: "1.0", TimeSigned: signedAt},
+		{User: types.User{Login: "jane"}, CLAVersion: "1.0", TimeSigned: signedAt, CLATextSha256: mockCLATextSha256,
+			Evidence: &types.SigningEvidence{GitHubUserId: 42}},
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlImportSignature)).
+		WithArgs("john", "john@doe.tld", "John", signedAt, "1.0", "", nil, nil, false, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlImportSignature)).
+		WithArgs("jane", "", "", signedAt, "1.0", "", nil, mockCLATextSha256, false, "", "", 42).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	conflicts, err := db.ImportSignatures(signatures, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, conflicts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportSignaturesDryRun(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlImportSignature)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+
+	conflicts, err := db.ImportSignatures([]types.UserSignature{{User: types.User{Login: "john"}, CLAVersion: "1.0"}}, true)
+	assert.NoError(t, err)
+	assert.Nil(t, conflicts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportSignaturesError(t *testing.T) {
+	mock, db, closeDbFunc := SetupMockDB(t)
+	defer closeDbFunc()
+
+	forcedError := errors.New("forced SQL insert error")
+	mock.ExpectBegin()
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlImportSignature)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(ConvertSqlToDbMockExpect(SqlImportSignature)).
+		WillReturnError(forcedError)
+	mock.ExpectRollback()
+
+	conflicts, err := db.ImportSignatures([]types.UserSignature{
+		{User: types.User{Login: "john"}, CLAVersion: "1.0"},
+		{User: types.User{Login: "jane"}, CLAVersion: "1.0"},
+	}, false)
+	assert.EqualError(t, err, "failed to import signature of user: jane, claVersion: 1.0, error: "+forcedError.Error())
+	assert.Nil(t, conflicts)
+}
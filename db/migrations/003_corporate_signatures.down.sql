@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS corporate_members;
+DROP TABLE IF EXISTS corporate_signatures;
+
+COMMIT;
@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE corporate_signatures
+(
+    Id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    CompanyName    varchar(250) NOT NULL,
+    SignatoryLogin varchar(250) NOT NULL,
+    SignatoryEmail varchar(250),
+    SignatoryName  varchar(250),
+    SignedAt       timestamp    NOT NULL,
+    ClaVersion     varchar(10)  NOT NULL,
+    ClaTextUrl     varchar(250) NOT NULL,
+    ClaText        TEXT         NOT NULL,
+    Active         boolean      NOT NULL DEFAULT TRUE,
+    UNIQUE (CompanyName, ClaVersion)
+);
+
+CREATE TABLE corporate_members
+(
+    Id                   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    CorporateSignatureID UUID         NOT NULL,
+    MemberType           varchar(10)  NOT NULL,
+    MemberValue          varchar(250) NOT NULL,
+    UNIQUE (CorporateSignatureID, MemberType, MemberValue),
+    FOREIGN KEY (CorporateSignatureID) REFERENCES corporate_signatures (Id) ON DELETE CASCADE
+);
+
+COMMIT;
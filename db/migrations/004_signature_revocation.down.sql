@@ -0,0 +1,22 @@
+BEGIN;
+
+DROP TABLE IF EXISTS cla_version_expiry;
+
+-- only one signature of a login and CLA version is kept: the active one, or else the one signed last
+DROP INDEX IF EXISTS signatures_loginname_claversion_key;
+DELETE
+FROM signatures
+    USING signatures AS other
+WHERE signatures.LoginName = other.LoginName
+  AND signatures.ClaVersion = other.ClaVersion
+  AND signatures.RevokedAt IS NOT NULL
+  AND (other.RevokedAt IS NULL OR (other.SignedAt, other.Id) > (signatures.SignedAt, signatures.Id));
+ALTER TABLE signatures
+    ADD CONSTRAINT signatures_loginname_claversion_key UNIQUE (LoginName, ClaVersion);
+
+ALTER TABLE signatures
+    DROP COLUMN RevokedAt,
+    DROP COLUMN RevokedBy,
+    DROP COLUMN RevokedReason;
+
+COMMIT;
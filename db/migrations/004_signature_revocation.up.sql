@@ -0,0 +1,19 @@
+BEGIN;
+
+ALTER TABLE signatures
+    ADD COLUMN RevokedAt     timestamp,
+    ADD COLUMN RevokedBy     varchar(250),
+    ADD COLUMN RevokedReason TEXT;
+
+-- a revoked signature is kept as a record, and does not keep the signer from signing the CLA version again
+ALTER TABLE signatures
+    DROP CONSTRAINT signatures_loginname_claversion_key;
+CREATE UNIQUE INDEX signatures_loginname_claversion_key ON signatures (LoginName, ClaVersion) WHERE RevokedAt IS NULL;
+
+CREATE TABLE cla_version_expiry
+(
+    ClaVersion varchar(10) PRIMARY KEY,
+    ExpiresAt  timestamp NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE unsigned_pr
+    DROP CONSTRAINT unsigned_pr_repoowner_reponame_prnumber_key,
+    ADD CONSTRAINT unsigned_pr_reponame_prnumber_key UNIQUE (RepoName, PRNumber);
+
+COMMIT;
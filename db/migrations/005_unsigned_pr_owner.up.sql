@@ -0,0 +1,17 @@
+BEGIN;
+
+-- Repositories with the same name in different orgs must not share tracking rows. Under the old key, the authors of a
+-- PR were attached to the row of a PR with the same repository name and number in another org, if that PR was tracked
+-- already, and so the PR of the authors was never evaluated again once they signed. Such rows can not be told apart
+-- from the others, so the rows tracked under the old key are removed. The authors of an open PR are tracked again the
+-- next time the PR is evaluated, e.g. on a push, or when the CLA check is re-run.
+DELETE
+FROM unsigned_user;
+DELETE
+FROM unsigned_pr;
+
+ALTER TABLE unsigned_pr
+    DROP CONSTRAINT unsigned_pr_reponame_prnumber_key,
+    ADD CONSTRAINT unsigned_pr_repoowner_reponame_prnumber_key UNIQUE (RepoOwner, RepoName, PRNumber);
+
+COMMIT;
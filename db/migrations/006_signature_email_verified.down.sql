@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP INDEX IF EXISTS signatures_lower_email_idx;
+
+ALTER TABLE signatures
+    DROP COLUMN EmailVerified;
+
+COMMIT;
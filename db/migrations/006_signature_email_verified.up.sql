@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Only an email verified by GitHub may identify the signer of a commit that is not linked to a GitHub account
+ALTER TABLE signatures
+    ADD COLUMN EmailVerified boolean NOT NULL DEFAULT FALSE;
+
+CREATE INDEX signatures_lower_email_idx ON signatures (lower(Email));
+
+COMMIT;
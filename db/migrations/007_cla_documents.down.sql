@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS cla_document_mappings;
+
+DROP TABLE IF EXISTS cla_catalog;
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+-- A CLA document (e.g. the individual CLA) may have many versions, the latest effective version applies.
+-- Signatures are matched on the CLA version, so a version belongs to a single document.
+CREATE TABLE cla_catalog
+(
+    DocumentId  varchar(50)  NOT NULL,
+    ClaVersion  varchar(10)  NOT NULL,
+    ClaTextUrl  varchar(250) NOT NULL DEFAULT '',
+    ClaText     TEXT         NOT NULL DEFAULT '',
+    EffectiveAt timestamp    NOT NULL,
+    PRIMARY KEY (DocumentId, ClaVersion),
+    UNIQUE (ClaVersion)
+);
+
+-- RepoName is empty when the mapping applies to every repo of the owner (organization)
+CREATE TABLE cla_document_mappings
+(
+    RepoOwner  varchar(250) NOT NULL,
+    RepoName   varchar(250) NOT NULL DEFAULT '',
+    DocumentId varchar(50)  NOT NULL,
+    PRIMARY KEY (RepoOwner, RepoName)
+);
+
+COMMIT;
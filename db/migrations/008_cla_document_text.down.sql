@@ -0,0 +1,42 @@
+BEGIN;
+
+ALTER TABLE cla_catalog
+    ADD COLUMN ClaText TEXT NOT NULL DEFAULT '';
+
+UPDATE cla_catalog
+SET ClaText = cla_documents.ClaText
+FROM cla_documents
+WHERE cla_documents.Id = cla_catalog.ClaDocumentId;
+
+ALTER TABLE cla_catalog
+    DROP COLUMN ClaDocumentId;
+
+ALTER TABLE corporate_signatures
+    ADD COLUMN ClaText TEXT NOT NULL DEFAULT '';
+
+UPDATE corporate_signatures
+SET ClaText = cla_documents.ClaText
+FROM cla_documents
+WHERE cla_documents.Id = corporate_signatures.ClaDocumentId;
+
+ALTER TABLE corporate_signatures
+    ALTER COLUMN ClaText DROP DEFAULT,
+    DROP COLUMN ClaDocumentId,
+    DROP COLUMN ClaTextSha256;
+
+ALTER TABLE signatures
+    ADD COLUMN ClaText TEXT NOT NULL DEFAULT '';
+
+UPDATE signatures
+SET ClaText = cla_documents.ClaText
+FROM cla_documents
+WHERE cla_documents.Id = signatures.ClaDocumentId;
+
+ALTER TABLE signatures
+    ALTER COLUMN ClaText DROP DEFAULT,
+    DROP COLUMN ClaDocumentId,
+    DROP COLUMN ClaTextSha256;
+
+DROP TABLE cla_documents;
+
+COMMIT;
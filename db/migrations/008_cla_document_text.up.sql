@@ -0,0 +1,70 @@
+BEGIN;
+
+-- the catalog of (named) CLA documents keeps its url, but stored text moves to cla_documents.
+-- the text of each CLA version is stored once, identified by the SHA-256 hash of the text
+CREATE TABLE cla_documents
+(
+    Id            serial PRIMARY KEY,
+    ClaVersion    varchar(10)  NOT NULL,
+    ClaTextSha256 char(64)     NOT NULL,
+    ClaTextUrl    varchar(250) NOT NULL DEFAULT '',
+    ClaText       TEXT         NOT NULL,
+    CreatedAt     timestamp    NOT NULL DEFAULT now(),
+    UNIQUE (ClaVersion, ClaTextSha256)
+);
+
+INSERT INTO cla_documents (ClaVersion, ClaTextSha256, ClaTextUrl, ClaText, CreatedAt)
+SELECT ClaVersion, encode(digest(ClaText, 'sha256'), 'hex'), min(ClaTextUrl), ClaText, min(SignedAt)
+FROM (SELECT ClaVersion, ClaTextUrl, ClaText, SignedAt
+      FROM signatures
+      UNION ALL
+      SELECT ClaVersion, ClaTextUrl, ClaText, SignedAt
+      FROM corporate_signatures
+      UNION ALL
+      SELECT ClaVersion, ClaTextUrl, ClaText, EffectiveAt
+      FROM cla_catalog) AS texts
+WHERE ClaVersion IS NOT NULL
+  AND ClaText <> ''
+GROUP BY ClaVersion, ClaText;
+
+ALTER TABLE signatures
+    ADD COLUMN ClaDocumentId integer REFERENCES cla_documents (Id),
+    ADD COLUMN ClaTextSha256 char(64);
+
+UPDATE signatures
+SET ClaDocumentId = cla_documents.Id,
+    ClaTextSha256 = cla_documents.ClaTextSha256
+FROM cla_documents
+WHERE cla_documents.ClaVersion = signatures.ClaVersion
+  AND cla_documents.ClaTextSha256 = encode(digest(signatures.ClaText, 'sha256'), 'hex');
+
+ALTER TABLE signatures
+    DROP COLUMN ClaText;
+
+ALTER TABLE corporate_signatures
+    ADD COLUMN ClaDocumentId integer REFERENCES cla_documents (Id),
+    ADD COLUMN ClaTextSha256 char(64);
+
+UPDATE corporate_signatures
+SET ClaDocumentId = cla_documents.Id,
+    ClaTextSha256 = cla_documents.ClaTextSha256
+FROM cla_documents
+WHERE cla_documents.ClaVersion = corporate_signatures.ClaVersion
+  AND cla_documents.ClaTextSha256 = encode(digest(corporate_signatures.ClaText, 'sha256'), 'hex');
+
+ALTER TABLE corporate_signatures
+    DROP COLUMN ClaText;
+
+ALTER TABLE cla_catalog
+    ADD COLUMN ClaDocumentId integer REFERENCES cla_documents (Id);
+
+UPDATE cla_catalog
+SET ClaDocumentId = cla_documents.Id
+FROM cla_documents
+WHERE cla_documents.ClaVersion = cla_catalog.ClaVersion
+  AND cla_documents.ClaTextSha256 = encode(digest(cla_catalog.ClaText, 'sha256'), 'hex');
+
+ALTER TABLE cla_catalog
+    DROP COLUMN ClaText;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE signatures
+    DROP COLUMN IpAddress,
+    DROP COLUMN UserAgent,
+    DROP COLUMN GitHubUserId;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Evidence of how a signature was made, in case a signature is disputed. Signatures made before this migration have
+-- no evidence.
+ALTER TABLE signatures
+    ADD COLUMN IpAddress    varchar(45) NOT NULL DEFAULT '',
+    ADD COLUMN UserAgent    TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN GitHubUserId bigint;
+
+COMMIT;
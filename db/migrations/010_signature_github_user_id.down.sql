@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP INDEX IF EXISTS signatures_github_user_id_idx;
+
+COMMIT;
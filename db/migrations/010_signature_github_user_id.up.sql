@@ -0,0 +1,7 @@
+BEGIN;
+
+-- A signature is found by the immutable GitHub user id of the signer first, so it survives a rename of the login.
+-- Signatures made before the id was recorded get their id when the signer is next seen in a pull request.
+CREATE INDEX signatures_github_user_id_idx ON signatures (GitHubUserId);
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+-- login members stay in lower case, which still matches the logins of GitHub users
+
+DROP INDEX IF EXISTS unsigned_user_unsignedprid_lower_loginname_claversion_key;
+ALTER TABLE unsigned_user
+    ADD CONSTRAINT unsigned_user_unsignedprid_loginname_claversion_key UNIQUE (UnsignedPRID, LoginName, ClaVersion);
+
+DROP INDEX IF EXISTS signatures_lower_loginname_claversion_key;
+CREATE UNIQUE INDEX signatures_loginname_claversion_key ON signatures (LoginName, ClaVersion) WHERE RevokedAt IS NULL;
+
+INSERT INTO signatures (Id, LoginName, Email, GivenName, SignedAt, ClaVersion, ClaTextUrl, RevokedAt, RevokedBy,
+                        RevokedReason, EmailVerified, ClaDocumentId, ClaTextSha256, IpAddress, UserAgent,
+                        GitHubUserId)
+SELECT Id,
+       LoginName,
+       Email,
+       GivenName,
+       SignedAt,
+       ClaVersion,
+       ClaTextUrl,
+       RevokedAt,
+       RevokedBy,
+       RevokedReason,
+       EmailVerified,
+       ClaDocumentId,
+       ClaTextSha256,
+       IpAddress,
+       UserAgent,
+       GitHubUserId
+FROM merged_signatures;
+
+DROP TABLE merged_signatures;
+
+COMMIT;
@@ -0,0 +1,77 @@
+BEGIN;
+
+-- GitHub logins are case-insensitive, so JohnDoe and johndoe are the same signer. Active signatures of a CLA version
+-- whose logins only differ by case are merged: the first signature is kept, and the others move to merged_signatures,
+-- which reports each merged signature and the signature it was merged into. Revoked signatures are kept as they are.
+CREATE TABLE merged_signatures
+(
+    LIKE signatures,
+    MergedIntoId UUID      NOT NULL,
+    MergedAt     timestamp NOT NULL DEFAULT now()
+);
+
+CREATE TEMPORARY TABLE signature_merges ON COMMIT DROP AS
+SELECT Id,
+       first_value(Id) OVER (PARTITION BY lower(LoginName), ClaVersion
+           ORDER BY SignedAt, Id) AS MergedIntoId
+FROM signatures
+WHERE ClaVersion IS NOT NULL
+  AND RevokedAt IS NULL;
+
+DELETE
+FROM signature_merges
+WHERE Id = MergedIntoId;
+
+INSERT INTO merged_signatures
+SELECT signatures.*, signature_merges.MergedIntoId
+FROM signatures
+         JOIN signature_merges ON signature_merges.Id = signatures.Id;
+
+-- the kept signature takes the GitHub user id of a merged signature, if it has none
+UPDATE signatures
+SET GitHubUserId = merged.GitHubUserId
+FROM (SELECT DISTINCT ON (MergedIntoId) MergedIntoId, GitHubUserId
+      FROM merged_signatures
+      WHERE GitHubUserId IS NOT NULL
+      ORDER BY MergedIntoId, SignedAt) AS merged
+WHERE signatures.Id = merged.MergedIntoId
+  AND signatures.GitHubUserId IS NULL;
+
+DELETE
+FROM signatures
+    USING signature_merges
+WHERE signatures.Id = signature_merges.Id;
+
+DROP INDEX signatures_loginname_claversion_key;
+CREATE UNIQUE INDEX signatures_lower_loginname_claversion_key ON signatures (lower(LoginName), ClaVersion)
+    WHERE RevokedAt IS NULL;
+
+-- authors that still need to sign are tracked once per pull request, the latest check is kept
+DELETE
+FROM unsigned_user
+    USING unsigned_user AS later
+WHERE unsigned_user.UnsignedPRID = later.UnsignedPRID
+  AND lower(unsigned_user.LoginName) = lower(later.LoginName)
+  AND unsigned_user.ClaVersion = later.ClaVersion
+  AND (unsigned_user.CheckedAt, unsigned_user.Id) < (later.CheckedAt, later.Id);
+
+ALTER TABLE unsigned_user
+    DROP CONSTRAINT unsigned_user_unsignedprid_loginname_claversion_key;
+CREATE UNIQUE INDEX unsigned_user_unsignedprid_lower_loginname_claversion_key
+    ON unsigned_user (UnsignedPRID, lower(LoginName), ClaVersion);
+
+-- like domains, login members of corporate signatures are stored in lower case
+DELETE
+FROM corporate_members
+    USING corporate_members AS other
+WHERE corporate_members.MemberType = 'login'
+  AND other.MemberType = 'login'
+  AND corporate_members.CorporateSignatureID = other.CorporateSignatureID
+  AND lower(corporate_members.MemberValue) = lower(other.MemberValue)
+  AND corporate_members.Id > other.Id;
+
+UPDATE corporate_members
+SET MemberValue = lower(MemberValue)
+WHERE MemberType = 'login';
+
+COMMIT;
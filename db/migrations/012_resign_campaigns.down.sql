@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS resign_campaign_contacts;
+DROP TABLE IF EXISTS resign_campaigns;
+
+COMMIT;
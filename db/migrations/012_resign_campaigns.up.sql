@@ -0,0 +1,33 @@
+BEGIN;
+
+CREATE TABLE resign_campaigns
+(
+    Id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- the document of the catalog, empty for the default CLA document
+    DocumentId  varchar(50)  NOT NULL DEFAULT '',
+    ClaVersion  varchar(10)  NOT NULL,
+    ActiveSince timestamp    NOT NULL,
+    StartedBy   varchar(250) NOT NULL,
+    StartedAt   timestamp    NOT NULL
+);
+
+-- the signers a campaign asks to sign again, ContactedAt and Error are set once the email is sent (or failed).
+-- Whether they signed again is read from the signatures.
+CREATE TABLE resign_campaign_contacts
+(
+    Id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    CampaignID       UUID         NOT NULL,
+    LoginName        varchar(250) NOT NULL,
+    GitHubUserId     bigint,
+    Email            varchar(250) NOT NULL,
+    SignedClaVersion varchar(10)  NOT NULL,
+    ContactedAt      timestamp,
+    Error            TEXT         NOT NULL DEFAULT '',
+    FOREIGN KEY (CampaignID) REFERENCES resign_campaigns (Id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX resign_campaign_contacts_campaignid_lower_loginname_key
+    ON resign_campaign_contacts (CampaignID, lower(LoginName));
+CREATE INDEX resign_campaign_contacts_lower_loginname_idx ON resign_campaign_contacts (lower(LoginName));
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE IF EXISTS signed_pr_user;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- The signed authors of open PRs, so the PRs are re-evaluated when the signature of an author is revoked. A PR that
+-- passed has no unsigned_pr row, so the PR is identified here like in unsigned_pr.
+CREATE TABLE signed_pr_user
+(
+    Id        UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    RepoOwner varchar(250) NOT NULL,
+    RepoName  varchar(250) NOT NULL,
+    sha       varchar(250) NOT NULL,
+    PRNumber  int          NOT NULL,
+    AppID     int          NOT NULL,
+    InstallID int          NOT NULL,
+    LoginName varchar(250) NOT NULL,
+    CheckedAt timestamp    NOT NULL
+);
+
+CREATE UNIQUE INDEX signed_pr_user_pr_lower_loginname_key ON signed_pr_user (RepoOwner, RepoName, PRNumber, lower(LoginName));
+CREATE INDEX signed_pr_user_lower_loginname_idx ON signed_pr_user (lower(LoginName));
+
+COMMIT;
@@ -0,0 +1,203 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sonatype-nexus-community/the-cla/types"
+)
+
+const exportFormatCSV = "csv"
+const exportFormatNDJSON = "ndjson"
+const exportColumnCLAText = "claText"
+const exportColumnRevokedAt = "revokedAt"
+
+// exportColumn is a column of a signature export
+type exportColumn struct {
+	name  string
+	value func(signature *types.UserSignature) interface{}
+}
+
+// exportColumns are the columns of a signature export, in their default order
+var exportColumns = []exportColumn{
+	{"id", func(s *types.UserSignature) interface{} { return s.Id }},
+	{"login", func(s *types.UserSignature) interface{} { return s.User.Login }},
+	{"email", func(s *types.UserSignature) interface{} { return s.User.Email }},
+	{"name", func(s *types.UserSignature) interface{} { return s.User.GivenName }},
+	{"claVersion", func(s *types.UserSignature) interface{} { return s.CLAVersion }},
+	{"signedAt", func(s *types.UserSignature) interface{} { return s.TimeSigned.UTC().Format(time.RFC3339Nano) }},
+	{"claTextUrl", func(s *types.UserSignature) interface{} { return s.CLATextUrl }},
+	{"claDocumentId", func(s *types.UserSignature) interface{} { return s.CLADocumentId }},
+	{"claTextSha256", func(s *types.UserSignature) interface{} { return s.CLATextSha256 }},
+	{"emailVerified", func(s *types.UserSignature) interface{} { return s.EmailVerified }},
+	{"ipAddress", func(s *types.UserSignature) interface{} { return s.Evidence.IPAddress }},
+	{"userAgent", func(s *types.UserSignature) interface{} { return s.Evidence.UserAgent }},
+	{"gitHubUserId", func(s *types.UserSignature) interface{} { return s.Evidence.GitHubUserId }},
+	{exportColumnRevokedAt, func(s *types.UserSignature) interface{} {
+		if s.RevokedAt == nil {
+			return ""
+		}
+		return s.RevokedAt.UTC().Format(time.RFC3339Nano)
+	}},
+	{exportColumnCLAText, func(s *types.UserSignature) interface{} { return s.CLAText }},
+}
+
+// signatureExport writes signatures in the format and with the columns chosen for an export
+type signatureExport struct {
+	format  string
+	columns []exportColumn
+}
+
+// newSignatureExport checks the format, and the comma separated names of the columns to export. Without names, all
+// columns are exported.
+func newSignatureExport(format, columnNames string, excludeText bool) (export *signatureExport, err error) {
+	export = &signatureExport{format: format}
+	switch format {
+	case "":
+		export.format = exportFormatCSV
+	case exportFormatCSV, exportFormatNDJSON:
+	default:
+		return nil, fmt.Errorf("invalid export format: %s", format)
+	}
+
+	if columnNames == "" {
+		export.columns = exportColumns
+	} else {
+		for _, name := range strings.Split(columnNames, ",") {
+			column, ok := findExportColumn(strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("invalid export column: %s", name)
+			}
+			export.columns = append(export.columns, column)
+		}
+	}
+
+	if excludeText {
+		var columns []exportColumn
+		for _, column := range export.columns {
+			if column.name != exportColumnCLAText {
+				columns = append(columns, column)
+			}
+		}
+		export.columns = columns
+	}
+	if len(export.columns) == 0 {
+		return nil, fmt.Errorf("no columns to export")
+	}
+	return
+}
+
+func findExportColumn(name string) (column exportColumn, ok bool) {
+	for _, column = range exportColumns {
+		if column.name == name {
+			return column, true
+		}
+	}
+	return
+}
+
+// includesText tells if the (large) CLA text needs to be read for the export
+func (e *signatureExport) includesText() bool {
+	for _, column := range e.columns {
+		if column.name == exportColumnCLAText {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *signatureExport) contentType() string {
+	if e.format == exportFormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// start writes the header of the export, and returns the function writing a signature, and the function to call
+// after the last signature
+func (e *signatureExport) start(w io.Writer) (write func(signature *types.UserSignature) error, finish func() error, err error) {
+	if e.format == exportFormatNDJSON {
+		write = func(signature *types.UserSignature) error {
+			return e.writeJSON(w, signature)
+		}
+		return write, func() error { return nil }, nil
+	}
+
+	csvWriter := csv.NewWriter(w)
+	var header []string
+	for _, column := range e.columns {
+		header = append(header, column.name)
+	}
+	if err = csvWriter.Write(header); err != nil {
+		return
+	}
+	write = func(signature *types.UserSignature) error {
+		record := make([]string, len(e.columns))
+		for i, column := range e.columns {
+			record[i] = escapeCSVFormula(fmt.Sprint(column.value(signature)))
+		}
+		return csvWriter.Write(record)
+	}
+	finish = func() error {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return
+}
+
+// writeJSON writes the signature as a JSON object on a single line, with the keys in the order of the columns
+func (e *signatureExport) writeJSON(w io.Writer, signature *types.UserSignature) error {
+	var line bytes.Buffer
+	line.WriteByte('{')
+	for i, column := range e.columns {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		name, err := json.Marshal(column.name)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(column.value(signature))
+		if err != nil {
+			return err
+		}
+		line.Write(name)
+		line.WriteByte(':')
+		line.Write(value)
+	}
+	line.WriteString("}\n")
+	_, err := w.Write(line.Bytes())
+	return err
+}
+
+// escapeCSVFormula keeps spreadsheets from running a value entered by a signer (e.g. their name) as a formula
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,117 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sonatype-nexus-community/the-cla/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func exportTestSignature() *types.UserSignature {
+	return &types.UserSignature{
+		Id:            "myId",
+		User:          types.User{Login: "john", Email: "john@doe.tld", GivenName: "=HYPERLINK(\"evil\")"},
+		CLAVersion:    "2.0",
+		TimeSigned:    time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		CLATextUrl:    "https://cla.example.com/cla.txt",
+		CLAText:       "The CLA, with a \"quote\"",
+		CLADocumentId: 42,
+		CLATextSha256: "mySha",
+		EmailVerified: true,
+		Evidence:      &types.SigningEvidence{IPAddress: "10.0.0.1", UserAgent: "myUserAgent", GitHubUserId: 7},
+	}
+}
+
+func TestNewSignatureExportDefaults(t *testing.T) {
+	export, err := newSignatureExport("", "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, exportFormatCSV, export.format)
+	assert.Equal(t, len(exportColumns), len(export.columns))
+	assert.True(t, export.includesText())
+}
+
+func TestNewSignatureExportInvalid(t *testing.T) {
+	_, err := newSignatureExport("xml", "", false)
+	assert.EqualError(t, err, "invalid export format: xml")
+
+	_, err = newSignatureExport(exportFormatCSV, "login,password", false)
+	assert.EqualError(t, err, "invalid export column: password")
+
+	_, err = newSignatureExport(exportFormatCSV, "claText", true)
+	assert.EqualError(t, err, "no columns to export")
+}
+
+func TestSignatureExportCSV(t *testing.T) {
+	export, err := newSignatureExport(exportFormatCSV, "login, name,signedAt,claText", false)
+	assert.NoError(t, err)
+
+	var out bytes.Buffer
+	write, finish, err := export.start(&out)
+	assert.NoError(t, err)
+	assert.NoError(t, write(exportTestSignature()))
+	assert.NoError(t, finish())
+	assert.Equal(t, "login,name,signedAt,claText\n"+
+		`john,"'=HYPERLINK(""evil"")",2022-01-02T03:04:05Z,"The CLA, with a ""quote"""`+"\n", out.String())
+}
+
+func TestSignatureExportNDJSONExcludeText(t *testing.T) {
+	export, err := newSignatureExport(exportFormatNDJSON, "", true)
+	assert.NoError(t, err)
+	assert.False(t, export.includesText())
+
+	var out bytes.Buffer
+	write, finish, err := export.start(&out)
+	assert.NoError(t, err)
+	assert.NoError(t, write(exportTestSignature()))
+	assert.NoError(t, write(exportTestSignature()))
+	assert.NoError(t, finish())
+	line := `{"id":"myId","login":"john","email":"john@doe.tld","name":"=HYPERLINK(\"evil\")","claVersion":"2.0",` +
+		`"signedAt":"2022-01-02T03:04:05Z","claTextUrl":"https://cla.example.com/cla.txt","claDocumentId":42,` +
+		`"claTextSha256":"mySha","emailVerified":true,"ipAddress":"10.0.0.1","userAgent":"myUserAgent","gitHubUserId":7,"revokedAt":""}` + "\n"
+	assert.Equal(t, line+line, out.String())
+}
+
+func TestSignatureExportRevokedAt(t *testing.T) {
+	export, err := newSignatureExport(exportFormatCSV, "login,revokedAt", false)
+	assert.NoError(t, err)
+
+	signature := exportTestSignature()
+	revokedAt := time.Date(2022, 3, 4, 5, 6, 7, 0, time.FixedZone("EST", -5*60*60))
+	signature.RevokedAt = &revokedAt
+
+	var out bytes.Buffer
+	write, finish, err := export.start(&out)
+	assert.NoError(t, err)
+	assert.NoError(t, write(exportTestSignature()))
+	assert.NoError(t, write(signature))
+	assert.NoError(t, finish())
+	assert.Equal(t, "login,revokedAt\njohn,\njohn,2022-03-04T10:06:07Z\n", out.String())
+}
+
+func TestEscapeCSVFormula(t *testing.T) {
+	assert.Equal(t, "", escapeCSVFormula(""))
+	assert.Equal(t, "john", escapeCSVFormula("john"))
+	assert.Equal(t, "'+1", escapeCSVFormula("+1"))
+	assert.Equal(t, "'@SUM(A1)", escapeCSVFormula("@SUM(A1)"))
+}
@@ -23,7 +23,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -35,6 +38,7 @@ import (
 	"github.com/sonatype-nexus-community/the-cla/db"
 	"github.com/sonatype-nexus-community/the-cla/types"
 	webhook "gopkg.in/go-playground/webhooks.v5/github"
+	"gopkg.in/yaml.v3"
 )
 
 func getpemlocation() string {
@@ -49,6 +53,273 @@ var FilenameTheClaPem = getpemlocation()
 
 const EnvGhAppId = "GH_APP_ID"
 
+// EnvStatusMode selects how the evaluation result is published on a PR. Check runs are used unless the mode is
+// StatusModeStatuses, which is meant for repos whose branch protection still requires the legacy commit status.
+const EnvStatusMode = "CLA_STATUS_MODE"
+const StatusModeChecks = "checks"
+const StatusModeStatuses = "statuses"
+
+// EnvAllowListLogins and EnvAllowListTypes hold comma separated lists of authors that need not sign the CLA, e.g.
+// "dependabot[bot],renovate[bot],*-ci" and "Bot". A login is matched exactly (ignoring case), or as a glob pattern
+// as supported by path.Match.
+const EnvAllowListLogins = "CLA_ALLOW_LIST_LOGINS"
+const EnvAllowListTypes = "CLA_ALLOW_LIST_TYPES"
+
+// AllowList exempts authors, e.g. bots, from signing the CLA
+type AllowList struct {
+	Logins       []string `yaml:"logins"`
+	AccountTypes []string `yaml:"accountTypes"`
+}
+
+func getAllowList() AllowList {
+	return AllowList{
+		Logins:       splitEnvList(os.Getenv(EnvAllowListLogins)),
+		AccountTypes: splitEnvList(os.Getenv(EnvAllowListTypes)),
+	}
+}
+
+func splitEnvList(value string) (values []string) {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+// allowedReason returns why the author need not sign the CLA, or an empty string if the author is not allow-listed
+func (a AllowList) allowedReason(login, accountType string) string {
+	for _, allowedType := range a.AccountTypes {
+		if accountType != "" && strings.EqualFold(allowedType, accountType) {
+			return "allow-listed account type " + accountType
+		}
+	}
+	for _, allowedLogin := range a.Logins {
+		if strings.EqualFold(allowedLogin, login) {
+			return "allow-listed login"
+		}
+		// bad patterns never match, so they are ignored
+		if matched, _ := path.Match(strings.ToLower(allowedLogin), strings.ToLower(login)); matched {
+			return "allow-listed by pattern " + allowedLogin
+		}
+	}
+	return ""
+}
+
+// ConfigPath is the path of the optional CLA configuration file of a repo. The file may also be added to the
+// ".github" repo of the owner, to configure all repos of the owner.
+const ConfigPath = ".github/cla.yml"
+const ownerConfigRepo = ".github"
+
+const msgTemplateCLAVersionNotPublished = "claVersion %s of %s is not a CLA version published by the server"
+
+const defaultCommentTemplate = "Thanks for the contribution. Before we can merge this, we need {users} to [sign the Contributor License Agreement]({signUrl})"
+
+type LabelConfig struct {
+	Name  string `yaml:"name"`
+	Color string `yaml:"color"`
+}
+
+// RepoConfig is the CLA configuration of a repo, read from ConfigPath. Fields that are not set fall back to the
+// configuration of the owner, and then to the defaults.
+type RepoConfig struct {
+	// the CLA version contributors must have signed
+	CLAVersion string `yaml:"claVersion"`
+	// added to the allow-list of the environment
+	AllowList      AllowList   `yaml:"allowList"`
+	LabelNotSigned LabelConfig `yaml:"labelNotSigned"`
+	LabelSigned    LabelConfig `yaml:"labelSigned"`
+	// posted when authors need to sign, "{users}" and "{signUrl}" are replaced by the authors and the signing page
+	CommentTemplate     string        `yaml:"commentTemplate"`
+	CollaboratorsExempt *bool         `yaml:"collaboratorsExempt"`
+	VersionPolicy       VersionPolicy `yaml:"versionPolicy"`
+}
+
+const (
+	// VersionPolicyExact only accepts signatures of the required CLA version
+	VersionPolicyExact = "exact"
+	// VersionPolicySameMajor also accepts signatures of an older version with the same major version, e.g. 1.0 for 1.2
+	VersionPolicySameMajor = "sameMajor"
+	// VersionPolicyMinimum also accepts signatures of an older version, down to the minimum version
+	VersionPolicyMinimum = "minimum"
+)
+
+// VersionPolicy decides if a signature of an older CLA version satisfies the required CLA version, so bumping the
+// CLA version does not require every contributor to sign again
+type VersionPolicy struct {
+	Accept         string `yaml:"accept"`
+	MinimumVersion string `yaml:"minimumVersion"`
+	// signatures of older versions made before this time are not accepted anymore, e.g. "2022-06-01T00:00:00Z"
+	ResignAfter *time.Time `yaml:"resignAfter"`
+}
+
+// accepts tells if a signature of an older CLA version satisfies the required CLA version at the given time
+func (p VersionPolicy) accepts(signature *types.UserSignature, claVersion string, now time.Time) bool {
+	if p.ResignAfter != nil && !now.Before(*p.ResignAfter) && signature.TimeSigned.Before(*p.ResignAfter) {
+		return false
+	}
+	signed, ok := parseVersion(signature.CLAVersion)
+	if !ok {
+		return false
+	}
+	required, ok := parseVersion(claVersion)
+	if !ok || compareVersions(signed, required) >= 0 {
+		return false
+	}
+	switch p.Accept {
+	case VersionPolicySameMajor:
+		return signed[0] == required[0]
+	case VersionPolicyMinimum:
+		minimum, ok := parseVersion(p.MinimumVersion)
+		return ok && compareVersions(signed, minimum) >= 0
+	default:
+		return false
+	}
+}
+
+// parseVersion splits a CLA version like "1.2" or "v1.2.3" into its numbers
+func parseVersion(version string) (numbers []int, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	for _, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, true
+}
+
+// compareVersions returns a negative number if a is older than b, 0 if they are the same, and a positive number if a
+// is newer than b. Missing numbers count as 0, so "1" and "1.0" are the same version.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var na, nb int
+		if i < len(a) {
+			na = a[i]
+		}
+		if i < len(b) {
+			nb = b[i]
+		}
+		if na != nb {
+			return na - nb
+		}
+	}
+	return 0
+}
+
+// IsPriorVersion returns true if the signed CLA version is a prior version of the required CLA version: any other
+// version, unless both versions are numbers and the signed version is newer.
+func IsPriorVersion(signed, required string) bool {
+	if signed == required {
+		return false
+	}
+	signedNumbers, signedOk := parseVersion(signed)
+	requiredNumbers, requiredOk := parseVersion(required)
+	return !signedOk || !requiredOk || compareVersions(signedNumbers, requiredNumbers) <= 0
+}
+
+func defaultRepoConfig() RepoConfig {
+	return RepoConfig{
+		AllowList:           getAllowList(),
+		LabelNotSigned:      LabelConfig{Name: labelNameCLANotSigned, Color: "ff3333"},
+		LabelSigned:         LabelConfig{Name: labelNameCLASigned, Color: "66CC00"},
+		CommentTemplate:     defaultCommentTemplate,
+		CollaboratorsExempt: github.Bool(true),
+		VersionPolicy:       VersionPolicy{Accept: VersionPolicyExact},
+	}
+}
+
+func (c *RepoConfig) merge(override *RepoConfig) {
+	if override.CLAVersion != "" {
+		c.CLAVersion = override.CLAVersion
+	}
+	c.AllowList.Logins = append(c.AllowList.Logins, override.AllowList.Logins...)
+	c.AllowList.AccountTypes = append(c.AllowList.AccountTypes, override.AllowList.AccountTypes...)
+	if override.LabelNotSigned.Name != "" {
+		c.LabelNotSigned.Name = override.LabelNotSigned.Name
+	}
+	if override.LabelNotSigned.Color != "" {
+		c.LabelNotSigned.Color = override.LabelNotSigned.Color
+	}
+	if override.LabelSigned.Name != "" {
+		c.LabelSigned.Name = override.LabelSigned.Name
+	}
+	if override.LabelSigned.Color != "" {
+		c.LabelSigned.Color = override.LabelSigned.Color
+	}
+	if override.CommentTemplate != "" {
+		c.CommentTemplate = override.CommentTemplate
+	}
+	if override.CollaboratorsExempt != nil {
+		c.CollaboratorsExempt = override.CollaboratorsExempt
+	}
+	if override.VersionPolicy.Accept != "" {
+		c.VersionPolicy.Accept = override.VersionPolicy.Accept
+	}
+	if override.VersionPolicy.MinimumVersion != "" {
+		c.VersionPolicy.MinimumVersion = override.VersionPolicy.MinimumVersion
+	}
+	if override.VersionPolicy.ResignAfter != nil {
+		c.VersionPolicy.ResignAfter = override.VersionPolicy.ResignAfter
+	}
+}
+
+// LoadRepoConfig merges the configuration files of the owner and the repo over the defaults
+func LoadRepoConfig(logger *zap.Logger, repositoryService RepositoriesService, owner, repo string) (config RepoConfig, err error) {
+	config = defaultRepoConfig()
+
+	configRepos := []string{ownerConfigRepo}
+	if repo != ownerConfigRepo {
+		configRepos = append(configRepos, repo)
+	}
+	for _, configRepo := range configRepos {
+		var fileConfig *RepoConfig
+		if fileConfig, err = readConfigFile(logger, repositoryService, owner, configRepo); err != nil {
+			return
+		}
+		if fileConfig != nil {
+			logger.Debug("found config", zap.String("owner", owner), zap.String("repo", configRepo), zap.Any("config", fileConfig))
+			config.merge(fileConfig)
+		}
+	}
+	return
+}
+
+func readConfigFile(logger *zap.Logger, repositoryService RepositoriesService, owner, repo string) (config *RepoConfig, err error) {
+	fileContent, _, resp, err := repositoryService.GetContents(context.Background(), owner, repo, ConfigPath, nil)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		// the config file is optional
+		return nil, nil
+	}
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		// installations made before the app asked for the Contents permission can not read the config file
+		logger.Warn("no permission to read config, using defaults",
+			zap.String("owner", owner), zap.String("repo", repo), zap.Error(err))
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	content, err := fileContent.GetContent()
+	if err != nil {
+		return
+	}
+	config = &RepoConfig{}
+	if err = yaml.Unmarshal([]byte(content), config); err != nil {
+		return nil, fmt.Errorf("invalid config file %s in %s/%s: %w", ConfigPath, owner, repo, err)
+	}
+	return
+}
+
+func getStatusMode() string {
+	if os.Getenv(EnvStatusMode) == StatusModeStatuses {
+		return StatusModeStatuses
+	}
+	return StatusModeChecks
+}
+
 // RepositoriesService handles communication with the repository related methods
 // of the GitHub API.
 // https://godoc.org/github.com/google/go-github/github#RepositoriesService
@@ -57,6 +328,7 @@ type RepositoriesService interface {
 	ListStatuses(ctx context.Context, owner, repo, ref string, opts *github.ListOptions) ([]*github.RepoStatus, *github.Response, error)
 	CreateStatus(ctx context.Context, owner, repo, ref string, status *github.RepoStatus) (*github.RepoStatus, *github.Response, error)
 	IsCollaborator(ctx context.Context, owner, repo, user string) (bool, *github.Response, error)
+	GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error)
 }
 
 // UsersService handles communication with the user related methods
@@ -64,6 +336,7 @@ type RepositoriesService interface {
 // https://godoc.org/github.com/google/go-github/github#UsersService
 type UsersService interface {
 	Get(context.Context, string) (*github.User, *github.Response, error)
+	ListEmails(ctx context.Context, opts *github.ListOptions) ([]*github.UserEmail, *github.Response, error)
 }
 
 // PullRequestsService handles communication with the pull request related
@@ -71,6 +344,7 @@ type UsersService interface {
 //
 // GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/pulls/
 type PullRequestsService interface {
+	Get(ctx context.Context, owner string, repo string, number int) (*github.PullRequest, *github.Response, error)
 	ListCommits(ctx context.Context, owner string, repo string, number int, opts *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error)
 }
 
@@ -88,6 +362,24 @@ type IssuesService interface {
 	ListComments(ctx context.Context, owner string, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error)
 }
 
+// ChecksService provides access to the Checks API related functions
+// in the GitHub API.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/checks/
+type ChecksService interface {
+	CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+	UpdateCheckRun(ctx context.Context, owner, repo string, checkRunID int64, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error)
+}
+
+// SearchService provides access to the search related functions
+// in the GitHub API.
+//
+// GitHub API docs: https://docs.github.com/en/free-pro-team@latest/rest/reference/search/
+type SearchService interface {
+	Issues(ctx context.Context, query string, opts *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error)
+	Users(ctx context.Context, query string, opts *github.SearchOptions) (*github.UsersSearchResult, *github.Response, error)
+}
+
 // AppsService provides access to the installation related functions
 // in the GitHub API.
 //
@@ -97,6 +389,19 @@ type AppsService interface {
 	// the authenticated GitHub App.
 	Get(ctx context.Context, appSlug string) (*github.App, *github.Response, error)
 	GetInstallation(ctx context.Context, id int64) (*github.Installation, *github.Response, error)
+	ListInstallations(ctx context.Context, opts *github.ListOptions) ([]*github.Installation, *github.Response, error)
+	FindRepositoryInstallation(ctx context.Context, owner, repo string) (*github.Installation, *github.Response, error)
+}
+
+// listPageSize is the maximum page size GitHub allows for list calls
+const listPageSize = 100
+
+// nextPage returns the page to request after the given response, or 0 if there are no more pages
+func nextPage(resp *github.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.NextPage
 }
 
 func GetAppId() (appId int64, err error) {
@@ -107,6 +412,8 @@ func GetAppId() (appId int64, err error) {
 type IGitHubJWTClient interface {
 	Get() (*github.App, error)
 	GetInstallInfo() (*github.Installation, error)
+	ListInstallations() ([]*github.Installation, error)
+	FindRepositoryInstallation(owner, repo string) (*github.Installation, error)
 }
 
 type GHJWTClient struct {
@@ -133,6 +440,26 @@ func (ghj *GHJWTClient) GetInstallInfo() (install *github.Installation, err erro
 	return
 }
 
+func (ghj *GHJWTClient) ListInstallations() (installs []*github.Installation, err error) {
+	opts := &github.ListOptions{PerPage: listPageSize}
+	for {
+		var page []*github.Installation
+		var resp *github.Response
+		if page, resp, err = ghj.apps.ListInstallations(context.Background(), opts); err != nil {
+			return
+		}
+		installs = append(installs, page...)
+		if opts.Page = nextPage(resp); opts.Page == 0 {
+			return
+		}
+	}
+}
+
+func (ghj *GHJWTClient) FindRepositoryInstallation(owner, repo string) (install *github.Installation, err error) {
+	install, _, err = ghj.apps.FindRepositoryInstallation(context.Background(), owner, repo)
+	return
+}
+
 type GHJWTInterface interface {
 	NewJWTClient(httpClient *http.Client, installID int64) IGitHubJWTClient
 }
@@ -153,6 +480,8 @@ type GHClient struct {
 	Users        UsersService
 	PullRequests PullRequestsService
 	Issues       IssuesService
+	Search       SearchService
+	Checks       ChecksService
 }
 
 // GHInterface defines all necessary methods.
@@ -172,6 +501,8 @@ func (g *GHCreator) NewClient(httpClient *http.Client) GHClient {
 		Users:        client.Users,
 		PullRequests: client.PullRequests,
 		Issues:       client.Issues,
+		Search:       client.Search,
+		Checks:       client.Checks,
 	}
 }
 
@@ -192,7 +523,121 @@ func HandlePullRequest(logger *zap.Logger, postgres db.IClaDB, payload webhook.P
 	return EvaluatePullRequest(logger, postgres, &evalInfo, claVersion)
 }
 
-func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types.EvaluationInfo, claVersion string) error {
+// HandlePullRequestClosed stops tracking a closed (or merged) PR, so it is not re-evaluated when an author signs later.
+func HandlePullRequestClosed(logger *zap.Logger, postgres db.IClaDB, payload webhook.PullRequestPayload) error {
+	evalInfo := types.EvaluationInfo{
+		RepoOwner: payload.Repository.Owner.Login,
+		RepoName:  payload.Repository.Name,
+		PRNumber:  payload.Number,
+	}
+	logger.Debug("remove closed PR", zap.Any("eval", evalInfo))
+
+	return postgres.RemoveClosedPR(&evalInfo)
+}
+
+const commandRecheck = "/cla recheck"
+
+// HandleIssueComment re-evaluates a PR when someone comments "/cla recheck" on it, e.g. after a GitHub API hiccup
+// during a prior evaluation. Returns false if the comment is not a recheck command that needs handling.
+func HandleIssueComment(logger *zap.Logger, postgres db.IClaDB, payload webhook.IssueCommentPayload, appId int64, claVersion string) (handled bool, err error) {
+	if payload.Action != "created" ||
+		// only comments on PRs are of interest
+		payload.Issue.PullRequest == nil ||
+		// ignore comments by bots, including our own
+		payload.Comment.User.Type == "Bot" ||
+		!strings.EqualFold(strings.TrimSpace(payload.Comment.Body), commandRecheck) {
+		return
+	}
+
+	owner := payload.Repository.Owner.Login
+	repo := payload.Repository.Name
+	logger.Debug("recheck requested",
+		zap.String("owner", owner),
+		zap.String("repo", repo),
+		zap.Int64("PRNumber", payload.Issue.Number),
+		zap.String("requestedBy", payload.Comment.User.Login),
+	)
+
+	// the issue comment payload does not include the installation, so ask GitHub
+	atr, err := ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, appId, FilenameTheClaPem)
+	if err != nil {
+		return
+	}
+	install, err := GHJWTImpl.NewJWTClient(&http.Client{Transport: atr}, 0).FindRepositoryInstallation(owner, repo)
+	if err != nil {
+		return
+	}
+
+	itr, err := ghinstallation.NewKeyFromFile(http.DefaultTransport, appId, install.GetID(), FilenameTheClaPem)
+	if err != nil {
+		return
+	}
+	client := GHImpl.NewClient(&http.Client{Transport: itr})
+
+	// the comment does not include the head sha either
+	pr, _, err := client.PullRequests.Get(context.Background(), owner, repo, int(payload.Issue.Number))
+	if err != nil {
+		return
+	}
+
+	evalInfo := types.EvaluationInfo{
+		RepoOwner: owner,
+		RepoName:  repo,
+		Sha:       pr.GetHead().GetSHA(),
+		PRNumber:  payload.Issue.Number,
+		AppId:     appId,
+		InstallId: install.GetID(),
+	}
+	if err = EvaluatePullRequest(logger, postgres, &evalInfo, claVersion); err != nil {
+		return
+	}
+	handled = true
+	return
+}
+
+const checkRunActionRerun = "rerun"
+
+// HandleCheckRun re-evaluates the PR of one of our check runs when someone clicks its "Re-run" button, or asks GitHub
+// to re-run the check. Returns false if the event does not need handling.
+func HandleCheckRun(logger *zap.Logger, postgres db.IClaDB, event *github.CheckRunEvent, appId int64, claVersion string) (handled bool, err error) {
+	switch event.GetAction() {
+	case "requested_action":
+		if event.GetRequestedAction() == nil || event.GetRequestedAction().Identifier != checkRunActionRerun {
+			return
+		}
+	case "rerequested":
+	default:
+		return
+	}
+
+	// we store the PR number as the external id when creating the check run, because GitHub omits the PRs of forks
+	// from the check run payload
+	prNumber, err := strconv.ParseInt(event.GetCheckRun().GetExternalID(), 10, 64)
+	if err != nil {
+		return
+	}
+
+	evalInfo := types.EvaluationInfo{
+		RepoOwner: event.GetRepo().GetOwner().GetLogin(),
+		RepoName:  event.GetRepo().GetName(),
+		Sha:       event.GetCheckRun().GetHeadSHA(),
+		PRNumber:  prNumber,
+		AppId:     appId,
+		InstallId: event.GetInstallation().GetID(),
+	}
+	logger.Debug("re-run requested",
+		zap.Any("eval", evalInfo),
+		zap.String("requestedBy", event.GetSender().GetLogin()),
+	)
+
+	if err = EvaluatePullRequest(logger, postgres, &evalInfo, claVersion); err != nil {
+		return
+	}
+	handled = true
+	return
+}
+
+func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types.EvaluationInfo, claVersion string) (err error) {
 	logger.Debug("start authenticating with GitHub",
 		zap.Any("eval", evalInfo),
 	)
@@ -200,7 +645,7 @@ func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types
 	// get JWT GH stuff first
 	// Getting a JWT Apps Transport to ask GitHub about stuff that needs a JWT for asking, such as installInfo
 	var atr *ghinstallation.AppsTransport
-	atr, err := ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, evalInfo.AppId, FilenameTheClaPem)
+	atr, err = ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, evalInfo.AppId, FilenameTheClaPem)
 	if err != nil {
 		logger.Error("failed to get JWT key",
 			zap.Int64("appId", evalInfo.AppId),
@@ -227,18 +672,48 @@ func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types
 
 	client := GHImpl.NewClient(&http.Client{Transport: itr})
 
-	err = createRepoStatus(client.Repositories, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.Sha, "pending", "Paul Botsco, the CLA verifier is running", botName)
+	config, err := LoadRepoConfig(logger, client.Repositories, evalInfo.RepoOwner, evalInfo.RepoName)
+	if err != nil {
+		return err
+	}
+	// the CLA document mapped to the repo (or its owner) overrides the default version, the repo config overrides both
+	defaultCLAVersion := claVersion
+	doc, err := postgres.GetCLADocumentForRepo(evalInfo.RepoOwner, evalInfo.RepoName, time.Now())
 	if err != nil {
 		return err
 	}
+	if doc != nil {
+		claVersion = doc.CLAVersion
+	}
 
-	opts := &github.ListOptions{}
+	reporter := newStatusReporter(client, evalInfo, botName)
+	if err = reporter.start(); err != nil {
+		return err
+	}
+	// an evaluation that fails must not leave the check in progress, which blocks the PR without a way to re-run it
+	finished := false
+	defer func() {
+		if err != nil && !finished {
+			if failErr := reporter.fail(err); failErr != nil {
+				logger.Error("failed to report evaluation error", zap.Error(failErr))
+			}
+		}
+	}()
 
-	commits, _, err := client.PullRequests.ListCommits(
-		context.Background(),
-		evalInfo.RepoOwner,
-		evalInfo.RepoName,
-		int(evalInfo.PRNumber), opts)
+	if config.CLAVersion != "" && config.CLAVersion != claVersion {
+		// only a version the server publishes can be signed, any other version could never be satisfied
+		var docs []types.CLADocument
+		if docs, err = postgres.GetCLADocumentsByVersion(config.CLAVersion); err != nil {
+			return err
+		}
+		if config.CLAVersion != defaultCLAVersion && len(docs) == 0 {
+			err = fmt.Errorf(msgTemplateCLAVersionNotPublished, config.CLAVersion, ConfigPath)
+			return err
+		}
+		claVersion = config.CLAVersion
+	}
+
+	commits, err := listPullRequestCommits(client.PullRequests, evalInfo)
 	if err != nil {
 		return err
 	}
@@ -248,58 +723,221 @@ func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types
 	// The following loop will change a loop as a result
 	var usersNeedingToSignCLA []types.UserSignature
 	var usersSigned []types.UserSignature
+	var authors []authorStatus
+	// authors needing to sign who signed an older CLA version
+	var outdated []types.UserSignature
+
+	// commit authors and co-authors without a known GitHub account
+	var unlinked []gitAuthor
+	// a contributor usually has many commits in a PR, only check them once
+	checkedLogins := map[string]bool{}
+	// the same co-author is often on many commits, and the search API is rate limited, so resolve each email once
+	coAuthorAccounts := map[string]gitHubAccount{}
 
 	for _, v := range commits {
+		var contributors []contributor
 		// It is important to use GetAuthor() instead of v.Commit.GetCommitter() because the committer can be the GH webflow user, whereas the author is
 		// the canonical author of the commit
-		author := *v.GetAuthor()
+		if author := v.GetAuthor(); author.GetLogin() != "" {
+			contributors = append(contributors, contributor{
+				User: types.User{
+					Login:     author.GetLogin(),
+					Email:     author.GetEmail(),
+					GivenName: author.GetName(),
+				},
+				commitEmail: v.GetCommit().GetAuthor().GetEmail(),
+				accountType: author.GetType(),
+				userId:      author.GetID(),
+			})
+		} else {
+			// GitHub only links a commit to an account if the commit email belongs to the account
+			unlinked = append(unlinked, gitAuthor{
+				Name:      v.GetCommit().GetAuthor().GetName(),
+				Email:     v.GetCommit().GetAuthor().GetEmail(),
+				CommitSHA: v.GetSHA(),
+			})
+		}
 
-		// if author is a collaborator, that author need not sign the cla.
-		var isCollaborator bool
-		isCollaborator, _, err = client.Repositories.IsCollaborator(
-			context.Background(),
-			evalInfo.RepoOwner,
-			evalInfo.RepoName,
-			*author.Login,
-		)
-		if err != nil {
-			return err
+		// code contributed by co-authors needs a signature too
+		for _, coAuth := range parseCoAuthors(v.GetCommit().GetMessage()) {
+			account, resolved := coAuthorAccounts[strings.ToLower(coAuth.Email)]
+			if !resolved {
+				if account, err = resolveLoginByEmail(client.Search, coAuth.Email); err != nil {
+					return err
+				}
+				coAuthorAccounts[strings.ToLower(coAuth.Email)] = account
+			}
+			if account.login == "" {
+				coAuth.CommitSHA = v.GetSHA()
+				unlinked = append(unlinked, coAuth)
+				continue
+			}
+			// anyone can write any email in a trailer, so there is no commit email to match on the domain of a
+			// corporate CLA, only the login the email resolved to
+			contributors = append(contributors, contributor{
+				User: types.User{
+					Login:     account.login,
+					Email:     coAuth.Email,
+					GivenName: coAuth.Name,
+				},
+				userId:   account.userId,
+				coAuthor: true,
+			})
+		}
+
+		for _, contrib := range contributors {
+			// logins are case-insensitive, e.g. a co-author may use another case than GitHub does
+			if checkedLogins[strings.ToLower(contrib.Login)] {
+				continue
+			}
+			checkedLogins[strings.ToLower(contrib.Login)] = true
+
+			if reason := config.AllowList.allowedReason(contrib.Login, contrib.accountType); reason != "" {
+				logger.Debug("author is allow-listed", zap.String("login", contrib.Login), zap.String("reason", reason))
+				authors = append(authors, authorStatus{Login: contrib.Login, Signed: true, Note: reason})
+				continue
+			}
+
+			// if author is a collaborator, that author need not sign the cla, unless the repo wants everyone to sign.
+			var isCollaborator bool
+			if *config.CollaboratorsExempt {
+				isCollaborator, _, err = client.Repositories.IsCollaborator(
+					context.Background(),
+					evalInfo.RepoOwner,
+					evalInfo.RepoName,
+					contrib.Login,
+				)
+				if err != nil {
+					return err
+				}
+			}
+			if isCollaborator {
+				// nothing to do, we've found a collaborator, move along
+				authors = append(authors, authorStatus{Login: contrib.Login, Signed: true, Note: "collaborator, no CLA needed"})
+				continue
+			}
+
+			var foundUserSigned *types.UserSignature
+			hasAuthorSigned, foundUserSigned, err := postgres.HasAuthorSignedTheCla(contrib.Login, contrib.userId, claVersion)
+			if err != nil {
+				return err
+			}
+			if hasAuthorSigned {
+				updateSignatureLogin(logger, postgres, contrib, foundUserSigned)
+			}
+			if !hasAuthorSigned {
+				// a signature of an older CLA version may still satisfy the version policy of the repo
+				var priorSignatures []types.UserSignature
+				priorSignatures, err = postgres.GetAuthorSignatures(contrib.Login, contrib.userId)
+				if err != nil {
+					return err
+				}
+				priorSigned, accepted := findPriorSignature(config.VersionPolicy, priorSignatures, claVersion, time.Now())
+				if accepted {
+					logger.Debug("author signed older CLA version accepted by the version policy",
+						zap.String("login", contrib.Login),
+						zap.String("signedClaVersion", priorSigned.CLAVersion),
+						zap.String("claVersion", claVersion),
+					)
+					updateSignatureLogin(logger, postgres, contrib, priorSigned)
+					// the author is no longer missing a signature of the required version, which is the version their
+					// unsigned_user rows are stored with
+					acceptedSigned := *priorSigned
+					acceptedSigned.CLAVersion = claVersion
+					usersSigned = append(usersSigned, acceptedSigned)
+					authors = append(authors, authorStatus{
+						Login:      contrib.Login,
+						Signed:     true,
+						CLAVersion: priorSigned.CLAVersion,
+						Note:       "accepted by the version policy",
+					})
+					continue
+				}
+
+				// the author may be covered by a corporate CLA signed by their employer. GitHub only links a commit to
+				// an account via a verified email of that account, so the commit email is safe to match on domain.
+				// Co-authors have no commit email, so they are only matched on login.
+				var isCorporateCovered bool
+				var foundCorporateSigned *types.CorporateSignature
+				isCorporateCovered, foundCorporateSigned, err = postgres.HasCorporateSignedTheCla(contrib.Login, contrib.commitEmail, claVersion)
+				if err != nil {
+					return err
+				}
+				if isCorporateCovered {
+					logger.Debug("author covered by corporate signature",
+						zap.String("login", contrib.Login),
+						zap.String("company", foundCorporateSigned.Company),
+					)
+					usersSigned = append(usersSigned, types.UserSignature{
+						User:       types.User{Login: contrib.Login},
+						CLAVersion: foundCorporateSigned.CLAVersion,
+						TimeSigned: foundCorporateSigned.TimeSigned,
+					})
+					authors = append(authors, authorStatus{
+						Login:      contrib.Login,
+						Signed:     true,
+						CLAVersion: foundCorporateSigned.CLAVersion,
+						Note:       "corporate CLA of " + foundCorporateSigned.Company,
+					})
+					continue
+				}
+
+				userMissingSignature := types.UserSignature{
+					User:       contrib.User,
+					CLAVersion: claVersion,
+					// do not populate TimeSigned
+				}
+				logger.Debug("missing author signature",
+					zap.Any("UserSignature", userMissingSignature))
+				usersNeedingToSignCLA = append(usersNeedingToSignCLA, userMissingSignature)
+				note := "needs to sign the CLA"
+				if priorSigned != nil {
+					note = fmt.Sprintf("signed version %s, needs to sign the CLA again", priorSigned.CLAVersion)
+					outdated = append(outdated, types.UserSignature{User: contrib.User, CLAVersion: priorSigned.CLAVersion})
+				}
+				authors = append(authors, authorStatus{Login: contrib.Login, CLAVersion: claVersion, Note: note})
+			} else {
+				usersSigned = append(usersSigned, *foundUserSigned)
+				authors = append(authors, authorStatus{Login: contrib.Login, Signed: true, CLAVersion: foundUserSigned.CLAVersion})
+			}
 		}
-		if isCollaborator {
-			// nothing to do, we've found a collaborator, move along
+	}
+
+	// without a login, the only way to find a signature is the verified email of the signer
+	var unlinkedUnsigned []gitAuthor
+	checkedEmails := map[string]bool{}
+	for _, gitAuth := range unlinked {
+		if checkedEmails[strings.ToLower(gitAuth.Email)] {
 			continue
 		}
+		checkedEmails[strings.ToLower(gitAuth.Email)] = true
 
-		var foundUserSigned *types.UserSignature
-		hasAuthorSigned, foundUserSigned, err := postgres.HasAuthorSignedTheCla(*author.Login, claVersion)
+		hasEmailSigned, foundUserSigned, err := postgres.HasEmailSignedTheCla(gitAuth.Email, claVersion)
 		if err != nil {
 			return err
 		}
-		if !hasAuthorSigned {
-			userMissingSignature := types.UserSignature{
-				User: types.User{
-					Login:     author.GetLogin(),
-					Email:     author.GetEmail(),
-					GivenName: author.GetName(),
-				},
-				CLAVersion: claVersion,
-				// do not populate TimeSigned
-			}
-			logger.Debug("missing author signature",
-				zap.Any("UserSignature", userMissingSignature))
-			usersNeedingToSignCLA = append(usersNeedingToSignCLA, userMissingSignature)
-		} else {
-			usersSigned = append(usersSigned, *foundUserSigned)
+		if hasEmailSigned {
+			authors = append(authors, authorStatus{
+				Email:      gitAuth.Email,
+				Signed:     true,
+				CLAVersion: foundUserSigned.CLAVersion,
+				Note:       "signed by @" + foundUserSigned.User.Login + " with this verified email",
+			})
+			continue
 		}
+
+		logger.Debug("author without GitHub account", zap.Any("gitAuthor", gitAuth))
+		unlinkedUnsigned = append(unlinkedUnsigned, gitAuth)
+		authors = append(authors, authorStatus{Email: gitAuth.Email, Note: "no GitHub account found for this email"})
 	}
 
-	if len(usersNeedingToSignCLA) > 0 {
-		err := createRepoLabel(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, labelNameCLANotSigned, "ff3333", "The CLA needs to be signed", evalInfo.PRNumber)
+	if len(usersNeedingToSignCLA) > 0 || len(unlinkedUnsigned) > 0 {
+		err := createRepoLabel(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, config.LabelNotSigned.Name, config.LabelNotSigned.Color, "The CLA needs to be signed", evalInfo.PRNumber)
 		if err != nil {
 			return err
 		}
 		// handle case where PR was previously open and all authors had signed cla - meaning the old "all signed" label is applied
-		err = _removeLabelFromIssueIfApplied(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber, labelNameCLASigned)
+		err = _removeLabelFromIssueIfApplied(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber, config.LabelSigned.Name)
 		if err != nil {
 			return err
 		}
@@ -309,11 +947,13 @@ func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types
 			users = append(users, " @"+v.User.Login)
 		}
 
-		// store failed users in the db, so we can reevaluate their PR's after they sign the CLA
-		evalInfo.UserSignatures = usersNeedingToSignCLA
-		err = postgres.StorePRAuthorsMissingSignature(evalInfo, time.Now())
-		if err != nil {
-			return err
+		if len(usersNeedingToSignCLA) > 0 {
+			// store failed users in the db, so we can reevaluate their PR's after they sign the CLA
+			evalInfo.UserSignatures = usersNeedingToSignCLA
+			err = postgres.StorePRAuthorsMissingSignature(evalInfo, time.Now())
+			if err != nil {
+				return err
+			}
 		}
 
 		// get info needed to show link to sign the cla
@@ -324,35 +964,52 @@ func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types
 		// Maybe use app.Name in the remaining hard coded Paul Botsco repo status message above...or not.
 		//appName := *app.Name
 		appExternalUrl := *app.ExternalURL
+		if doc != nil || config.CLAVersion != "" {
+			appExternalUrl = signUrlForRepo(appExternalUrl, evalInfo.RepoOwner, evalInfo.RepoName, config.CLAVersion)
+		}
 
-		message := "Thanks for the contribution. Before we can merge this, we need %s to [sign the Contributor License Agreement](%s)"
-		userMsg := strings.Join(users, ",")
+		var message string
+		if len(users) > 0 {
+			message = strings.NewReplacer("{users}", strings.Join(users, ","), "{signUrl}", appExternalUrl).
+				Replace(config.CommentTemplate)
+		}
+		if len(outdated) > 0 {
+			message += "\n\n" + buildOutdatedSignaturesMessage(outdated, claVersion, appExternalUrl)
+		}
+		if len(unlinkedUnsigned) > 0 {
+			if message != "" {
+				message += "\n\n"
+			}
+			message += buildUnlinkedAuthorsMessage(unlinkedUnsigned, appExternalUrl)
+		}
 
-		_, err = addCommentToIssueIfNotExists(client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, int(evalInfo.PRNumber), fmt.Sprintf(message, userMsg, appExternalUrl))
+		_, err = addCommentToIssueIfNotExists(client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, int(evalInfo.PRNumber), message)
 		if err != nil {
 			return err
 		}
 
-		err = createRepoStatus(client.Repositories, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.Sha, "failure", "One or more contributors need to sign the CLA", botName)
+		err = reporter.finish(false, "One or more contributors need to sign the CLA", authors)
 		if err != nil {
 			return err
 		}
+		finished = true
 	} else {
 		logger.Debug("create label for signed CLA")
-		err = createRepoLabel(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, labelNameCLASigned, "66CC00", "The CLA is signed", evalInfo.PRNumber)
+		err = createRepoLabel(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, config.LabelSigned.Name, config.LabelSigned.Color, "The CLA is signed", evalInfo.PRNumber)
 		if err != nil {
 			return err
 		}
 		// handle case where PR was previously open and some authors had NOT signed cla - meaning the old "not signed" label is applied
-		err = _removeLabelFromIssueIfApplied(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber, labelNameCLANotSigned)
+		err = _removeLabelFromIssueIfApplied(logger, client.Issues, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.PRNumber, config.LabelNotSigned.Name)
 		if err != nil {
 			return err
 		}
 
-		err = createRepoStatus(client.Repositories, evalInfo.RepoOwner, evalInfo.RepoName, evalInfo.Sha, "success", "All contributors have signed the CLA", botName)
+		err = reporter.finish(true, "All contributors have signed the CLA", authors)
 		if err != nil {
 			return err
 		}
+		finished = true
 
 	}
 	// delete any prior failed user info from the db for this PR
@@ -360,10 +1017,346 @@ func EvaluatePullRequest(logger *zap.Logger, postgres db.IClaDB, evalInfo *types
 	if err = postgres.RemovePRsForUsers(usersSigned, evalInfo); err != nil {
 		return err
 	}
+	// remember who the PR passed for, so the PR is re-evaluated if one of their signatures is revoked
+	if err = postgres.StorePRSignedAuthors(evalInfo, usersSigned, time.Now()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// contributor is a GitHub user who authored or co-authored a commit
+type contributor struct {
+	types.User
+	// the email used in the commit, which may differ from the public email of the GitHub user. Empty for co-authors.
+	commitEmail string
+	// e.g. "User" or "Bot", unknown for co-authors
+	accountType string
+	// the immutable GitHub user id, 0 if unknown
+	userId int64
+	// found by a Co-authored-by trailer, which the author of the commit can write freely
+	coAuthor bool
+}
+
+// updateSignatureLogin keeps the login of a signature up to date when the author renamed their GitHub account, and
+// records the user id of a signature made before user ids were recorded
+func updateSignatureLogin(logger *zap.Logger, postgres db.IClaDB, contrib contributor, foundUserSigned *types.UserSignature) {
+	// the login and id of a co-author are only as good as the trailer they were found by
+	if contrib.userId == 0 || contrib.coAuthor || foundUserSigned.Evidence == nil {
+		return
+	}
+	if foundUserSigned.Evidence.GitHubUserId == contrib.userId && foundUserSigned.User.Login == contrib.Login {
+		return
+	}
+	logger.Info("updating login of signature",
+		zap.String("signedLogin", foundUserSigned.User.Login),
+		zap.String("login", contrib.Login),
+		zap.Int64("gitHubUserId", contrib.userId),
+	)
+	if err := postgres.UpdateSignatureLogin(contrib.userId, contrib.Login); err != nil {
+		// log this, but don't fail the evaluation, the signature was found anyway
+		logger.Error("failed to update login of signature", zap.Error(err))
+		return
+	}
+	foundUserSigned.User.Login = contrib.Login
+	foundUserSigned.Evidence.GitHubUserId = contrib.userId
+}
+
+// gitAuthor is an author as recorded by git, e.g. in a "Co-authored-by:" commit trailer
+type gitAuthor struct {
+	Name      string
+	Email     string
+	CommitSHA string
+}
+
+var coAuthorTrailer = regexp.MustCompile(`(?im)^co-authored-by:\s*(.*?)\s*<([^>]+)>\s*$`)
+
+func parseCoAuthors(commitMessage string) (coAuthors []gitAuthor) {
+	seen := map[string]bool{}
+	for _, match := range coAuthorTrailer.FindAllStringSubmatch(commitMessage, -1) {
+		if seen[strings.ToLower(match[2])] {
+			continue
+		}
+		seen[strings.ToLower(match[2])] = true
+		coAuthors = append(coAuthors, gitAuthor{Name: match[1], Email: match[2]})
+	}
+	return
+}
+
+// gitHubAccount is the account a commit email resolved to, the login is empty if no account was found
+type gitHubAccount struct {
+	login string
+	// 0 if unknown
+	userId int64
+}
+
+// e.g. 12345+octocat@users.noreply.github.com, or octocat@users.noreply.github.com for older accounts
+var noReplyEmail = regexp.MustCompile(`(?i)^(?:(\d+)\+)?([^@]+)@users\.noreply\.github\.com$`)
+
+// resolveLoginByEmail finds the GitHub account of a commit email. Returns an empty login if the email is not
+// unambiguously linked to an account, e.g. because the email is not public. The user id is returned as well, so a
+// new account that took the old login of a renamed signer is not matched to the signature of the renamed signer.
+func resolveLoginByEmail(searchService SearchService, email string) (account gitHubAccount, err error) {
+	if match := noReplyEmail.FindStringSubmatch(email); match != nil {
+		account.login = match[2]
+		if match[1] != "" {
+			account.userId, _ = strconv.ParseInt(match[1], 10, 64)
+		}
+		return
+	}
+
+	result, _, err := searchService.Users(context.Background(), fmt.Sprintf("%s in:email", email), nil)
+	if err != nil {
+		return
+	}
+	if len(result.Users) == 1 {
+		account.login = result.Users[0].GetLogin()
+		account.userId = result.Users[0].GetID()
+	}
+	return
+}
+
+const QueryParameterOwner = "owner"
+const QueryParameterRepo = "repo"
+
+// QueryParameterCLAVersion selects the published CLA document of a version, e.g. the claVersion of .github/cla.yml
+const QueryParameterCLAVersion = "claversion"
+
+// signUrlForRepo adds the repo (and the CLA version required by its configuration, if any) to the url of the signing
+// page, so the page shows the CLA document of the repo
+func signUrlForRepo(appExternalUrl, owner, repo, claVersion string) string {
+	signUrl, err := url.Parse(appExternalUrl)
+	if err != nil {
+		return appExternalUrl
+	}
+	query := signUrl.Query()
+	query.Set(QueryParameterOwner, owner)
+	query.Set(QueryParameterRepo, repo)
+	if claVersion != "" {
+		query.Set(QueryParameterCLAVersion, claVersion)
+	}
+	signUrl.RawQuery = query.Encode()
+	return signUrl.String()
+}
+
+// SignUrlForCLAVersion returns the link to the signing page configured as the external url of the GitHub app, which
+// selects the published CLA document of the given version
+func SignUrlForCLAVersion(appId int64, claVersion string) (signUrl string, err error) {
+	atr, err := ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, appId, FilenameTheClaPem)
+	if err != nil {
+		return
+	}
+	app, err := GHJWTImpl.NewJWTClient(&http.Client{Transport: atr}, 0).Get()
+	if err != nil {
+		return
+	}
+	signUrl = app.GetExternalURL()
+	parsed, err := url.Parse(signUrl)
+	if err != nil {
+		return
+	}
+	query := parsed.Query()
+	query.Set(QueryParameterCLAVersion, claVersion)
+	parsed.RawQuery = query.Encode()
+	signUrl = parsed.String()
+	return
+}
+
+// findPriorSignature finds the signature of an older CLA version that the policy accepts. If there is none, it
+// finds the most recent signature of an older CLA version, to tell the author the CLA changed since they signed it.
+func findPriorSignature(policy VersionPolicy, signatures []types.UserSignature, claVersion string, now time.Time) (prior *types.UserSignature, accepted bool) {
+	for i := range signatures {
+		signature := &signatures[i]
+		if !IsPriorVersion(signature.CLAVersion, claVersion) {
+			// a newer version than required is not a prior signature, the repo may require an older CLA on purpose
+			continue
+		}
+		if policy.accepts(signature, claVersion, now) {
+			return signature, true
+		}
+		if prior == nil {
+			// signatures are sorted by most recent first
+			prior = signature
+		}
+	}
+	return
+}
+
+func buildOutdatedSignaturesMessage(outdated []types.UserSignature, claVersion, appExternalUrl string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("The Contributor License Agreement changed since these authors signed it, "+
+		"please [sign version %s of the Contributor License Agreement](%s):\n", claVersion, appExternalUrl))
+	for _, signature := range outdated {
+		sb.WriteString(fmt.Sprintf("\n- @%s signed version %s", signature.User.Login, signature.CLAVersion))
+	}
+	return sb.String()
+}
+
+func buildUnlinkedAuthorsMessage(unlinked []gitAuthor, appExternalUrl string) string {
+	var sb strings.Builder
+	sb.WriteString("We could not find a GitHub account for these commit authors or co-authors, so we can not tell if they signed the CLA:\n")
+	for _, gitAuth := range unlinked {
+		sb.WriteString(fmt.Sprintf("\n- %s `%s` (commit %s)", gitAuth.Name, gitAuth.Email, gitAuth.CommitSHA))
+	}
+	sb.WriteString(fmt.Sprintf("\n\nPlease add the email to the GitHub account of the author (as a public email for co-authors), "+
+		"make sure they [sign the Contributor License Agreement](%s), and comment `%s` on this PR.", appExternalUrl, commandRecheck))
+	return sb.String()
+}
+
+// authorStatus is the evaluation result of a single commit author, as listed in the check run summary
+type authorStatus struct {
+	Login string
+	// only set if no login is known
+	Email      string
+	Signed     bool
+	CLAVersion string
+	Note       string
+}
+
+// statusReporter publishes the progress and result of an evaluation on the head commit of the PR
+type statusReporter interface {
+	start() error
+	finish(success bool, title string, authors []authorStatus) error
+	// fail completes a started evaluation that ended with an error
+	fail(evalErr error) error
+}
+
+const msgEvaluationRunning = "Paul Botsco, the CLA verifier is running"
+const msgEvaluationFailed = "The CLA could not be verified"
+
+func newStatusReporter(client GHClient, evalInfo *types.EvaluationInfo, botName string) statusReporter {
+	if getStatusMode() == StatusModeStatuses {
+		return &commitStatusReporter{repositories: client.Repositories, evalInfo: evalInfo, botName: botName}
+	}
+	return &checkRunReporter{checks: client.Checks, evalInfo: evalInfo, botName: botName}
+}
+
+type commitStatusReporter struct {
+	repositories RepositoriesService
+	evalInfo     *types.EvaluationInfo
+	botName      string
+}
+
+func (r *commitStatusReporter) start() error {
+	return createRepoStatus(r.repositories, r.evalInfo.RepoOwner, r.evalInfo.RepoName, r.evalInfo.Sha, "pending", msgEvaluationRunning, r.botName)
+}
+
+func (r *commitStatusReporter) finish(success bool, title string, _ []authorStatus) error {
+	state := "failure"
+	if success {
+		state = "success"
+	}
+	return createRepoStatus(r.repositories, r.evalInfo.RepoOwner, r.evalInfo.RepoName, r.evalInfo.Sha, state, title, r.botName)
+}
+
+// fail leaves the error out of the status, as the description of a status is limited to 140 characters
+func (r *commitStatusReporter) fail(_ error) error {
+	return createRepoStatus(r.repositories, r.evalInfo.RepoOwner, r.evalInfo.RepoName, r.evalInfo.Sha, "error",
+		fmt.Sprintf("%s, comment `%s` to try again", msgEvaluationFailed, commandRecheck), r.botName)
+}
+
+type checkRunReporter struct {
+	checks     ChecksService
+	evalInfo   *types.EvaluationInfo
+	botName    string
+	checkRunID int64
+}
 
+func (r *checkRunReporter) start() error {
+	checkRun, _, err := r.checks.CreateCheckRun(context.Background(), r.evalInfo.RepoOwner, r.evalInfo.RepoName, github.CreateCheckRunOptions{
+		Name:       r.botName,
+		HeadSHA:    r.evalInfo.Sha,
+		ExternalID: github.String(strconv.FormatInt(r.evalInfo.PRNumber, 10)),
+		Status:     github.String("in_progress"),
+		Output: &github.CheckRunOutput{
+			Title:   github.String(msgEvaluationRunning),
+			Summary: github.String(msgEvaluationRunning),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	r.checkRunID = checkRun.GetID()
 	return nil
 }
 
+func (r *checkRunReporter) finish(success bool, title string, authors []authorStatus) error {
+	conclusion := "failure"
+	if success {
+		conclusion = "success"
+	}
+	return r.complete(conclusion, title, buildCheckRunSummary(authors))
+}
+
+func (r *checkRunReporter) fail(evalErr error) error {
+	return r.complete("failure", msgEvaluationFailed,
+		fmt.Sprintf("The evaluation failed with this error, re-run the check to try again:\n\n```\n%s\n```", evalErr))
+}
+
+// complete sets the conclusion of the check run, which can be re-run from then on
+func (r *checkRunReporter) complete(conclusion, title, summary string) error {
+	_, _, err := r.checks.UpdateCheckRun(context.Background(), r.evalInfo.RepoOwner, r.evalInfo.RepoName, r.checkRunID, github.UpdateCheckRunOptions{
+		Name:       r.botName,
+		Status:     github.String("completed"),
+		Conclusion: github.String(conclusion),
+		Output: &github.CheckRunOutput{
+			Title:   github.String(title),
+			Summary: github.String(summary),
+		},
+		Actions: []*github.CheckRunAction{
+			{Label: "Re-run", Description: "Re-run the CLA check", Identifier: checkRunActionRerun},
+		},
+	})
+	return err
+}
+
+// buildCheckRunSummary renders a markdown table with one row per commit author
+func buildCheckRunSummary(authors []authorStatus) string {
+	var sb strings.Builder
+	sb.WriteString("| Commit author | CLA signed | CLA version | Note |\n")
+	sb.WriteString("| --- | --- | --- | --- |\n")
+	seen := map[string]bool{}
+	for _, author := range authors {
+		identity := "`" + author.Email + "`"
+		if author.Login != "" {
+			identity = "@" + author.Login
+		}
+		// an author usually has many commits in a PR
+		if seen[identity] {
+			continue
+		}
+		seen[identity] = true
+
+		signed := ":x:"
+		if author.Signed {
+			signed = ":white_check_mark:"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", identity, signed, author.CLAVersion, author.Note))
+	}
+	return sb.String()
+}
+
+// listPullRequestCommits reads all pages of commits. Note: GitHub lists at most 250 commits of a PR.
+func listPullRequestCommits(pullRequestsService PullRequestsService, evalInfo *types.EvaluationInfo) (commits []*github.RepositoryCommit, err error) {
+	opts := &github.ListOptions{PerPage: listPageSize}
+	for {
+		var page []*github.RepositoryCommit
+		var resp *github.Response
+		page, resp, err = pullRequestsService.ListCommits(
+			context.Background(),
+			evalInfo.RepoOwner,
+			evalInfo.RepoName,
+			int(evalInfo.PRNumber), opts)
+		if err != nil {
+			return
+		}
+		commits = append(commits, page...)
+		if opts.Page = nextPage(resp); opts.Page == 0 {
+			return
+		}
+	}
+}
+
 func createRepoStatus(repositoryService RepositoriesService, owner, repo, sha, state, description, botName string) error {
 	_, _, err := repositoryService.CreateStatus(context.Background(), owner, repo, sha, &github.RepoStatus{State: &state, Description: &description, Context: &botName})
 	if err != nil {
@@ -422,15 +1415,20 @@ func _createRepoLabelIfNotExists(logger *zap.Logger,
 }
 
 func addCommentToIssueIfNotExists(issuesService IssuesService, owner, repo string, issueNumber int, message string) (*github.IssueComment, error) {
-	opts := &github.IssueListCommentsOptions{}
-	comments, _, err := issuesService.ListComments(context.Background(), owner, repo, issueNumber, opts)
-	if err != nil {
-		return nil, err
-	}
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: listPageSize}}
 	alreadyCommented := false
-	for _, v := range comments {
-		if *v.Body == message {
-			alreadyCommented = true
+	for {
+		comments, resp, err := issuesService.ListComments(context.Background(), owner, repo, issueNumber, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range comments {
+			if *v.Body == message {
+				alreadyCommented = true
+			}
+		}
+		if opts.Page = nextPage(resp); alreadyCommented || opts.Page == 0 {
+			break
 		}
 	}
 
@@ -450,18 +1448,25 @@ func addCommentToIssueIfNotExists(issuesService IssuesService, owner, repo strin
 
 func _addLabelToIssueIfNotExists(logger *zap.Logger, issuesService IssuesService, owner, repo string, issueNumber int64, labelName string) (desiredLabel *github.Label, err error) {
 	// check if label is already added to issue
-	opts := github.ListOptions{}
-	issueLabels, _, err := issuesService.ListLabelsByIssue(context.Background(), owner, repo, int(issueNumber), &opts)
-	if err != nil {
-		return
-	}
-	for _, existingLabel := range issueLabels {
-		if *existingLabel.Name == labelName {
-			logger.Debug("found label on issue, getting out of here", zap.String("labelName", labelName))
-			// label already exists on this issue
-			desiredLabel = existingLabel
+	opts := github.ListOptions{PerPage: listPageSize}
+	for {
+		var issueLabels []*github.Label
+		var resp *github.Response
+		issueLabels, resp, err = issuesService.ListLabelsByIssue(context.Background(), owner, repo, int(issueNumber), &opts)
+		if err != nil {
 			return
 		}
+		for _, existingLabel := range issueLabels {
+			if *existingLabel.Name == labelName {
+				logger.Debug("found label on issue, getting out of here", zap.String("labelName", labelName))
+				// label already exists on this issue
+				desiredLabel = existingLabel
+				return
+			}
+		}
+		if opts.Page = nextPage(resp); opts.Page == 0 {
+			break
+		}
 	}
 
 	// didn't find the label on this issue, so add the label to this issue
@@ -506,13 +1511,134 @@ func ReviewPriorPRs(logger *zap.Logger, postgres db.IClaDB, user *types.UserSign
 	}
 
 	logger.Debug("review evaluations", zap.Any("evals", evals))
+	return reviewTrackedPRs(logger, postgres, evals, user.CLAVersion)
+}
 
+// reviewTrackedPRs re-evaluates the tracked PRs that are still open, and stops tracking the closed ones
+func reviewTrackedPRs(logger *zap.Logger, postgres db.IClaDB, evals []types.EvaluationInfo, claVersion string) (err error) {
 	var eval types.EvaluationInfo
 	for _, eval = range evals {
+		var closed bool
+		if closed, err = isPullRequestClosed(&eval); err != nil {
+			return
+		}
+		if closed {
+			// we missed the "closed" event (or it arrived before we tracked the PR), so clean up now
+			logger.Debug("skip closed PR", zap.Any("eval", eval))
+			if err = postgres.RemoveClosedPR(&eval); err != nil {
+				return
+			}
+			continue
+		}
+
 		// get PR webhook parameter equivalents
-		if err = EvaluatePullRequest(logger, postgres, &eval, user.CLAVersion); err != nil {
+		if err = EvaluatePullRequest(logger, postgres, &eval, claVersion); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func isPullRequestClosed(evalInfo *types.EvaluationInfo) (closed bool, err error) {
+	itr, err := ghinstallation.NewKeyFromFile(http.DefaultTransport, evalInfo.AppId, evalInfo.InstallId, FilenameTheClaPem)
+	if err != nil {
+		return
+	}
+	client := GHImpl.NewClient(&http.Client{Transport: itr})
+
+	pr, _, err := client.PullRequests.Get(context.Background(), evalInfo.RepoOwner, evalInfo.RepoName, int(evalInfo.PRNumber))
+	if err != nil {
+		return
+	}
+	closed = pr.GetState() == "closed"
+	return
+}
+
+// ReviewOpenPRsForAuthor re-evaluates the open PRs of the given login. This is needed when a signature is revoked,
+// because PRs that previously passed are not tracked as unsigned. The PRs that passed with a signature of the login
+// are tracked, which includes PRs of others that contain commits by the login. PRs opened by the login are also
+// searched for across all installations of the app, to find PRs that passed before they were tracked.
+func ReviewOpenPRsForAuthor(logger *zap.Logger, postgres db.IClaDB, appId int64, login, claVersion string) (err error) {
+	tracked, err := postgres.GetPRsForSignedAuthor(login)
+	if err != nil {
+		return
+	}
+	if err = reviewTrackedPRs(logger, postgres, tracked, claVersion); err != nil {
+		return
+	}
+	reviewed := map[string]bool{}
+	for _, eval := range tracked {
+		reviewed[pullRequestKey(eval.RepoOwner, eval.RepoName, eval.PRNumber)] = true
+	}
+
+	atr, err := ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, appId, FilenameTheClaPem)
+	if err != nil {
+		return
+	}
+	ghJWTClient := GHJWTImpl.NewJWTClient(&http.Client{Transport: atr}, 0)
+	installs, err := ghJWTClient.ListInstallations()
+	if err != nil {
+		return
+	}
+
+	for _, install := range installs {
+		var itr *ghinstallation.Transport
+		itr, err = ghinstallation.NewKeyFromFile(http.DefaultTransport, appId, install.GetID(), FilenameTheClaPem)
+		if err != nil {
 			return
 		}
+		client := GHImpl.NewClient(&http.Client{Transport: itr})
+
+		query := fmt.Sprintf("is:pr is:open author:%s user:%s", login, install.GetAccount().GetLogin())
+		var issues []*github.Issue
+		opts := &github.SearchOptions{ListOptions: github.ListOptions{PerPage: listPageSize}}
+		for {
+			var result *github.IssuesSearchResult
+			var resp *github.Response
+			if result, resp, err = client.Search.Issues(context.Background(), query, opts); err != nil {
+				return
+			}
+			issues = append(issues, result.Issues...)
+			if opts.Page = nextPage(resp); opts.Page == 0 {
+				break
+			}
+		}
+
+		for _, issue := range issues {
+			// repository url looks like: https://api.github.com/repos/{owner}/{repo}
+			repoUrlParts := strings.Split(issue.GetRepositoryURL(), "/")
+			if len(repoUrlParts) < 2 {
+				logger.Error("unexpected repository url", zap.String("repositoryURL", issue.GetRepositoryURL()))
+				continue
+			}
+			owner := repoUrlParts[len(repoUrlParts)-2]
+			repo := repoUrlParts[len(repoUrlParts)-1]
+			if reviewed[pullRequestKey(owner, repo, int64(issue.GetNumber()))] {
+				continue
+			}
+
+			var pr *github.PullRequest
+			if pr, _, err = client.PullRequests.Get(context.Background(), owner, repo, issue.GetNumber()); err != nil {
+				return
+			}
+
+			evalInfo := types.EvaluationInfo{
+				RepoOwner: owner,
+				RepoName:  repo,
+				Sha:       pr.GetHead().GetSHA(),
+				PRNumber:  int64(issue.GetNumber()),
+				AppId:     appId,
+				InstallId: install.GetID(),
+			}
+			logger.Debug("re-evaluate open PR of author", zap.String("login", login), zap.Any("eval", evalInfo))
+			if err = EvaluatePullRequest(logger, postgres, &evalInfo, claVersion); err != nil {
+				return
+			}
+		}
 	}
 	return
 }
+
+func pullRequestKey(owner, repo string, number int64) string {
+	return fmt.Sprintf("%s/%s#%d", strings.ToLower(owner), strings.ToLower(repo), number)
+}
@@ -21,6 +21,7 @@ package github
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"os"
 	"testing"
@@ -43,6 +44,11 @@ type RepositoriesMock struct {
 	isCollaboratorResult           bool
 	isCollaboratorResp             *github.Response
 	isCollaboratorErr              error
+	// config file content by repo name, a repo without content has no config file
+	mockConfigContents map[string]string
+	mockGetContentsErr error
+	// the status code of a GetContents error, 500 by default
+	mockGetContentsStatus int
 }
 
 var _ RepositoriesService = (*RepositoriesMock)(nil)
@@ -72,6 +78,22 @@ func (r *RepositoriesMock) CreateStatus(ctx context.Context, owner, repo, ref st
 	return r.createStatusRepoStatus, r.createStatusResponse, r.createStatusError
 }
 
+//goland:noinspection GoUnusedParameter
+func (r *RepositoriesMock) GetContents(ctx context.Context, owner, repo, path string, opts *github.RepositoryContentGetOptions) (*github.RepositoryContent, []*github.RepositoryContent, *github.Response, error) {
+	if r.mockGetContentsErr != nil {
+		status := r.mockGetContentsStatus
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		return nil, nil, &github.Response{Response: &http.Response{StatusCode: status}}, r.mockGetContentsErr
+	}
+	content, ok := r.mockConfigContents[repo]
+	if !ok {
+		return nil, nil, &github.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}, fmt.Errorf("404 Not Found")
+	}
+	return &github.RepositoryContent{Content: github.String(content)}, nil, &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+}
+
 // Get returns a repository.
 func (r *RepositoriesMock) Get(context.Context, string, string) (*github.Repository, *github.Response, error) {
 	return &github.Repository{
@@ -97,9 +119,12 @@ func (r *RepositoriesMock) IsCollaborator(ctx context.Context, owner, repo, user
 
 // UsersMock mocks UsersService
 type UsersMock struct {
-	mockUser     *github.User
-	mockResponse *github.Response
-	mockGetError error
+	mockUser           *github.User
+	mockResponse       *github.Response
+	mockGetError       error
+	mockEmails         []*github.UserEmail
+	mockEmailsResponse *github.Response
+	mockEmailsError    error
 }
 
 var _ UsersService = (*UsersMock)(nil)
@@ -109,20 +134,52 @@ func (u *UsersMock) Get(context.Context, string) (*github.User, *github.Response
 	return u.mockUser, u.mockResponse, u.mockGetError
 }
 
+// ListEmails returns the emails of the user.
+func (u *UsersMock) ListEmails(context.Context, *github.ListOptions) ([]*github.UserEmail, *github.Response, error) {
+	return u.mockEmails, u.mockEmailsResponse, u.mockEmailsError
+}
+
 // PullRequestsMock mocks PullRequestsService
 type PullRequestsMock struct {
 	mockRepositoryCommits []*github.RepositoryCommit
 	mockResponse          *github.Response
 	mockListCommitsError  error
+	mockPullRequest       *github.PullRequest
+	mockGetResponse       *github.Response
+	mockGetError          error
+	// when set, ListCommits returns these pages instead of mockRepositoryCommits
+	mockRepositoryCommitsPages [][]*github.RepositoryCommit
 }
 
 var _ PullRequestsService = (*PullRequestsMock)(nil)
 
+// mockPage picks the page requested by opts from a multi-page mock result. It returns the index of the page and a
+// response that links to the next page, like GitHub does. Note: GitHub pages start at 1, with 0 meaning the first page.
+func mockPage(opts *github.ListOptions, pageCount int) (index int, resp *github.Response) {
+	if opts != nil && opts.Page > 0 {
+		index = opts.Page - 1
+	}
+	resp = &github.Response{Response: &http.Response{StatusCode: http.StatusOK}}
+	if index+1 < pageCount {
+		resp.NextPage = index + 2
+	}
+	return
+}
+
 //goland:noinspection GoUnusedParameter
 func (p *PullRequestsMock) ListCommits(ctx context.Context, owner string, repo string, number int, opts *github.ListOptions) ([]*github.RepositoryCommit, *github.Response, error) {
+	if len(p.mockRepositoryCommitsPages) > 0 {
+		index, resp := mockPage(opts, len(p.mockRepositoryCommitsPages))
+		return p.mockRepositoryCommitsPages[index], resp, p.mockListCommitsError
+	}
 	return p.mockRepositoryCommits, p.mockResponse, p.mockListCommitsError
 }
 
+//goland:noinspection GoUnusedParameter
+func (p *PullRequestsMock) Get(ctx context.Context, owner string, repo string, number int) (*github.PullRequest, *github.Response, error) {
+	return p.mockPullRequest, p.mockGetResponse, p.mockGetError
+}
+
 type IssuesMock struct {
 	mockGetLabel                  *github.Label
 	MockGetLabelResponse          *github.Response
@@ -144,6 +201,11 @@ type IssuesMock struct {
 	mockListComments              []*github.IssueComment
 	mockListCommentsResponse      *github.Response
 	mockListCommentsError         error
+	// when set, the list calls return these pages instead of the single page mocks above
+	mockListLabelsByIssuePages [][]*github.Label
+	mockListCommentsPages      [][]*github.IssueComment
+	// collects the body of created comments, if set
+	createdCommentBodies *[]string
 }
 
 var _ IssuesService = (*IssuesMock)(nil)
@@ -160,6 +222,10 @@ func (i *IssuesMock) CreateLabel(ctx context.Context, owner string, repo string,
 
 //goland:noinspection GoUnusedParameter
 func (i *IssuesMock) ListLabelsByIssue(ctx context.Context, owner string, repo string, issueNumber int, opts *github.ListOptions) ([]*github.Label, *github.Response, error) {
+	if len(i.mockListLabelsByIssuePages) > 0 {
+		index, resp := mockPage(opts, len(i.mockListLabelsByIssuePages))
+		return i.mockListLabelsByIssuePages[index], resp, i.mockListLabelsByIssueError
+	}
 	return i.mockListLabelsByIssue, i.mockListLabelsByIssueResponse, i.mockListLabelsByIssueError
 }
 
@@ -175,14 +241,77 @@ func (i *IssuesMock) RemoveLabelForIssue(ctx context.Context, owner string, repo
 
 //goland:noinspection GoUnusedParameter
 func (i *IssuesMock) CreateComment(ctx context.Context, owner string, repo string, number int, comment *github.IssueComment) (*github.IssueComment, *github.Response, error) {
+	if i.createdCommentBodies != nil {
+		*i.createdCommentBodies = append(*i.createdCommentBodies, comment.GetBody())
+	}
 	return i.mockComment, i.mockCreateCommentResponse, i.mockCreateCommentError
 }
 
 //goland:noinspection GoUnusedParameter
 func (i *IssuesMock) ListComments(ctx context.Context, owner string, repo string, number int, opts *github.IssueListCommentsOptions) ([]*github.IssueComment, *github.Response, error) {
+	if len(i.mockListCommentsPages) > 0 {
+		index, resp := mockPage(&opts.ListOptions, len(i.mockListCommentsPages))
+		return i.mockListCommentsPages[index], resp, i.mockListCommentsError
+	}
 	return i.mockListComments, i.mockListCommentsResponse, i.mockListCommentsError
 }
 
+// ChecksMock mocks ChecksService
+type ChecksMock struct {
+	mockCreateCheckRun         *github.CheckRun
+	mockCreateCheckRunResponse *github.Response
+	mockCreateCheckRunError    error
+	mockUpdateCheckRun         *github.CheckRun
+	mockUpdateCheckRunResponse *github.Response
+	mockUpdateCheckRunError    error
+	// records the last update, so tests can verify the published result
+	updatedCheckRunOpts *github.UpdateCheckRunOptions
+}
+
+var _ ChecksService = (*ChecksMock)(nil)
+
+//goland:noinspection GoUnusedParameter
+func (c *ChecksMock) CreateCheckRun(ctx context.Context, owner, repo string, opts github.CreateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	return c.mockCreateCheckRun, c.mockCreateCheckRunResponse, c.mockCreateCheckRunError
+}
+
+//goland:noinspection GoUnusedParameter
+func (c *ChecksMock) UpdateCheckRun(ctx context.Context, owner, repo string, checkRunID int64, opts github.UpdateCheckRunOptions) (*github.CheckRun, *github.Response, error) {
+	c.updatedCheckRunOpts = &opts
+	return c.mockUpdateCheckRun, c.mockUpdateCheckRunResponse, c.mockUpdateCheckRunError
+}
+
+// SearchMock mocks SearchService
+type SearchMock struct {
+	mockIssuesResult   *github.IssuesSearchResult
+	mockIssuesResponse *github.Response
+	mockIssuesError    error
+	mockUsersResult    *github.UsersSearchResult
+	mockUsersResponse  *github.Response
+	mockUsersError     error
+	// the number of calls to Users
+	usersCalls int
+	// when set, Issues returns these pages instead of mockIssuesResult
+	mockIssuesResultPages []*github.IssuesSearchResult
+}
+
+var _ SearchService = (*SearchMock)(nil)
+
+//goland:noinspection GoUnusedParameter
+func (s *SearchMock) Issues(ctx context.Context, query string, opts *github.SearchOptions) (*github.IssuesSearchResult, *github.Response, error) {
+	if len(s.mockIssuesResultPages) > 0 {
+		index, resp := mockPage(&opts.ListOptions, len(s.mockIssuesResultPages))
+		return s.mockIssuesResultPages[index], resp, s.mockIssuesError
+	}
+	return s.mockIssuesResult, s.mockIssuesResponse, s.mockIssuesError
+}
+
+//goland:noinspection GoUnusedParameter
+func (s *SearchMock) Users(ctx context.Context, query string, opts *github.SearchOptions) (*github.UsersSearchResult, *github.Response, error) {
+	s.usersCalls++
+	return s.mockUsersResult, s.mockUsersResponse, s.mockUsersError
+}
+
 type AppsMock struct {
 	mockApp               *github.App
 	mockAppResp           *github.Response
@@ -190,6 +319,14 @@ type AppsMock struct {
 	mockInstallation      *github.Installation
 	mockInstallationResp  *github.Response
 	mockInstallationError error
+	mockInstallations     []*github.Installation
+	mockInstallationsResp *github.Response
+	mockInstallationsErr  error
+	mockRepoInstallation  *github.Installation
+	mockRepoInstallResp   *github.Response
+	mockRepoInstallErr    error
+	// when set, ListInstallations returns these pages instead of mockInstallations
+	mockInstallationsPages [][]*github.Installation
 }
 
 var _ AppsService = (*AppsMock)(nil)
@@ -204,6 +341,20 @@ func (a *AppsMock) GetInstallation(ctx context.Context, id int64) (*github.Insta
 	return a.mockInstallation, a.mockInstallationResp, a.mockInstallationError
 }
 
+//goland:noinspection GoUnusedParameter
+func (a *AppsMock) ListInstallations(ctx context.Context, opts *github.ListOptions) ([]*github.Installation, *github.Response, error) {
+	if len(a.mockInstallationsPages) > 0 {
+		index, resp := mockPage(opts, len(a.mockInstallationsPages))
+		return a.mockInstallationsPages[index], resp, a.mockInstallationsErr
+	}
+	return a.mockInstallations, a.mockInstallationsResp, a.mockInstallationsErr
+}
+
+//goland:noinspection GoUnusedParameter
+func (a *AppsMock) FindRepositoryInstallation(ctx context.Context, owner, repo string) (*github.Installation, *github.Response, error) {
+	return a.mockRepoInstallation, a.mockRepoInstallResp, a.mockRepoInstallErr
+}
+
 var appSlug = "myAppSlug"
 
 func SetupMockGHJWT() (resetImpl func()) {
@@ -221,6 +372,21 @@ func SetupMockGHJWT() (resetImpl func()) {
 	return
 }
 
+// SetupMockGHJWTApp mocks the GitHub app, with the given external url
+func SetupMockGHJWTApp(externalUrl string) (resetImpl func()) {
+	origGHJWT := GHJWTImpl
+	resetImpl = func() {
+		GHJWTImpl = origGHJWT
+	}
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockApp:     &github.App{ExternalURL: &externalUrl},
+			mockAppResp: &github.Response{Response: &http.Response{StatusCode: http.StatusOK}},
+		},
+	}
+	return
+}
+
 type GHJWTMock struct {
 	AppsMock AppsMock
 }
@@ -241,6 +407,8 @@ type GHInterfaceMock struct {
 	UsersMock        UsersMock
 	PullRequestsMock PullRequestsMock
 	IssuesMock       IssuesMock
+	SearchMock       SearchMock
+	ChecksMock       ChecksMock
 }
 
 var _ GHInterface = (*GHInterfaceMock)(nil)
@@ -257,9 +425,13 @@ func (g *GHInterfaceMock) NewClient(httpClient *http.Client) GHClient {
 			mockResponse: g.UsersMock.mockResponse,
 		},
 		PullRequests: &PullRequestsMock{
-			mockListCommitsError:  g.PullRequestsMock.mockListCommitsError,
-			mockRepositoryCommits: g.PullRequestsMock.mockRepositoryCommits,
-			mockResponse:          g.PullRequestsMock.mockResponse,
+			mockListCommitsError:       g.PullRequestsMock.mockListCommitsError,
+			mockRepositoryCommits:      g.PullRequestsMock.mockRepositoryCommits,
+			mockResponse:               g.PullRequestsMock.mockResponse,
+			mockPullRequest:            g.PullRequestsMock.mockPullRequest,
+			mockGetResponse:            g.PullRequestsMock.mockGetResponse,
+			mockGetError:               g.PullRequestsMock.mockGetError,
+			mockRepositoryCommitsPages: g.PullRequestsMock.mockRepositoryCommitsPages,
 		},
 		Issues: &IssuesMock{
 			mockGetLabel:                  g.IssuesMock.mockGetLabel,
@@ -276,7 +448,12 @@ func (g *GHInterfaceMock) NewClient(httpClient *http.Client) GHClient {
 			mockAddLabelsError:            g.IssuesMock.mockAddLabelsError,
 			MockRemoveLabelResponse:       g.IssuesMock.MockRemoveLabelResponse,
 			mockRemoveLabelError:          g.IssuesMock.mockRemoveLabelError,
+			mockListLabelsByIssuePages:    g.IssuesMock.mockListLabelsByIssuePages,
+			mockListCommentsPages:         g.IssuesMock.mockListCommentsPages,
+			createdCommentBodies:          g.IssuesMock.createdCommentBodies,
 		},
+		Search: &g.SearchMock,
+		Checks: &g.ChecksMock,
 	}
 }
 
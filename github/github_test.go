@@ -20,6 +20,8 @@
 package github
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -200,6 +202,7 @@ type mockCLADb struct {
 	insertSignatureUserSignature    *types.UserSignature
 	insertSignatureError            error
 	hasAuthorSignedLogin            string
+	hasAuthorSignedUserId           int64
 	hasAuthorSignedCLAVersion       string
 	hasAuthorSignedResult           bool
 	hasAuthorSignedSignature        *types.UserSignature
@@ -215,6 +218,38 @@ type mockCLADb struct {
 	removePRsUsersSigned          []types.UserSignature
 	removePRsEvalInfo             *types.EvaluationInfo
 	removePRsError                error
+	removeClosedPREvalInfo        *types.EvaluationInfo
+	removeClosedPRError           error
+	hasCorporateSignedLogin       string
+	hasCorporateSignedEmail       string
+	hasCorporateSignedCLAVersion  string
+	hasCorporateSignedResult      bool
+	hasCorporateSignedSignature   *types.CorporateSignature
+	hasCorporateSignedError       error
+	hasEmailSignedEmail           string
+	hasEmailSignedCLAVersion      string
+	hasEmailSignedResult          bool
+	hasEmailSignedSignature       *types.UserSignature
+	hasEmailSignedError           error
+	getCLADocumentForRepoOwner    string
+	getCLADocumentForRepoName     string
+	getCLADocumentForRepoResult   *types.CLADocument
+	getCLADocumentForRepoError    error
+	getCLADocumentsByVersion      string
+	getCLADocumentsByVersionDocs  []types.CLADocument
+	getCLADocumentsByVersionError error
+	updateSignatureLoginUserId    int64
+	updateSignatureLoginLogin     string
+	updateSignatureLoginError     error
+	updateSignatureLoginCalls     *int
+	getAuthorSignaturesLogin      string
+	getAuthorSignaturesUserId     int64
+	getAuthorSignaturesResult     []types.UserSignature
+	getAuthorSignaturesError      error
+	storePRSignedAuthorsError     error
+	getPRsForSignedAuthorLogin    string
+	getPRsForSignedAuthorResult   []types.EvaluationInfo
+	getPRsForSignedAuthorError    error
 }
 
 var _ db.IClaDB = (*mockCLADb)(nil)
@@ -233,14 +268,42 @@ func (m mockCLADb) InsertSignature(u *types.UserSignature) error {
 	return m.insertSignatureError
 }
 
-func (m mockCLADb) HasAuthorSignedTheCla(login, claVersion string) (bool, *types.UserSignature, error) {
+func (m mockCLADb) HasAuthorSignedTheCla(login string, gitHubUserId int64, claVersion string) (bool, *types.UserSignature, error) {
 	if m.assertParameters {
 		assert.Equal(m.t, m.hasAuthorSignedLogin, login)
+		assert.Equal(m.t, m.hasAuthorSignedUserId, gitHubUserId)
 		assert.Equal(m.t, m.hasAuthorSignedCLAVersion, claVersion)
 	}
 	return m.hasAuthorSignedResult, m.hasAuthorSignedSignature, m.hasAuthorSignedError
 }
 
+func (m mockCLADb) UpdateSignatureLogin(gitHubUserId int64, login string) error {
+	if m.assertParameters {
+		assert.Equal(m.t, m.updateSignatureLoginUserId, gitHubUserId)
+		assert.Equal(m.t, m.updateSignatureLoginLogin, login)
+	}
+	if m.updateSignatureLoginCalls != nil {
+		*m.updateSignatureLoginCalls++
+	}
+	return m.updateSignatureLoginError
+}
+
+func (m mockCLADb) GetAuthorSignatures(login string, gitHubUserId int64) ([]types.UserSignature, error) {
+	if m.assertParameters {
+		assert.Equal(m.t, m.getAuthorSignaturesLogin, login)
+		assert.Equal(m.t, m.getAuthorSignaturesUserId, gitHubUserId)
+	}
+	return m.getAuthorSignaturesResult, m.getAuthorSignaturesError
+}
+
+func (m mockCLADb) HasEmailSignedTheCla(email, claVersion string) (bool, *types.UserSignature, error) {
+	if m.assertParameters {
+		assert.Equal(m.t, m.hasEmailSignedEmail, email)
+		assert.Equal(m.t, m.hasEmailSignedCLAVersion, claVersion)
+	}
+	return m.hasEmailSignedResult, m.hasEmailSignedSignature, m.hasEmailSignedError
+}
+
 func (m mockCLADb) MigrateDB(migrateSourceURL string) error {
 	if m.assertParameters {
 		assert.Equal(m.t, m.migrateDBSourceURL, migrateSourceURL)
@@ -271,6 +334,134 @@ func (m mockCLADb) RemovePRsForUsers(usersSigned []types.UserSignature, evalInfo
 	return m.removePRsError
 }
 
+func (m mockCLADb) RemoveClosedPR(evalInfo *types.EvaluationInfo) error {
+	if m.assertParameters {
+		assert.Equal(m.t, m.removeClosedPREvalInfo, evalInfo)
+	}
+	return m.removeClosedPRError
+}
+
+func (m mockCLADb) StorePRSignedAuthors(*types.EvaluationInfo, []types.UserSignature, time.Time) error {
+	return m.storePRSignedAuthorsError
+}
+
+func (m mockCLADb) GetPRsForSignedAuthor(login string) ([]types.EvaluationInfo, error) {
+	if m.assertParameters {
+		assert.Equal(m.t, m.getPRsForSignedAuthorLogin, login)
+	}
+	return m.getPRsForSignedAuthorResult, m.getPRsForSignedAuthorError
+}
+
+func (m mockCLADb) InsertCorporateSignature(*types.CorporateSignature) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) GetCorporateSignature(string, string) (*types.CorporateSignature, error) {
+	panic("implement me")
+}
+
+func (m mockCLADb) AddCorporateMember(string, *types.CorporateMember) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) RemoveCorporateMember(string, *types.CorporateMember) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) SetCorporateSignatureActive(string, bool) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) HasCorporateSignedTheCla(login, email, claVersion string) (bool, *types.CorporateSignature, error) {
+	if m.assertParameters {
+		assert.Equal(m.t, m.hasCorporateSignedLogin, login)
+		assert.Equal(m.t, m.hasCorporateSignedEmail, email)
+		assert.Equal(m.t, m.hasCorporateSignedCLAVersion, claVersion)
+	}
+	return m.hasCorporateSignedResult, m.hasCorporateSignedSignature, m.hasCorporateSignedError
+}
+
+func (m mockCLADb) RevokeSignature(*types.SignatureRevocation) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) SetCLAVersionExpiry(*types.CLAVersionExpiry) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) UpsertCLADocument(*types.CLADocument) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) GetCLADocuments() ([]types.CLADocument, error) {
+	panic("implement me")
+}
+
+func (m mockCLADb) GetCLADocumentsByVersion(claVersion string) ([]types.CLADocument, error) {
+	if m.assertParameters {
+		assert.Equal(m.t, m.getCLADocumentsByVersion, claVersion)
+	}
+	return m.getCLADocumentsByVersionDocs, m.getCLADocumentsByVersionError
+}
+
+func (m mockCLADb) GetCLADocumentForRepo(owner, repo string, at time.Time) (*types.CLADocument, error) {
+	// most tests do not care about the repo, so only check it when one is expected
+	if m.assertParameters && m.getCLADocumentForRepoOwner != "" {
+		assert.Equal(m.t, m.getCLADocumentForRepoOwner, owner)
+		assert.Equal(m.t, m.getCLADocumentForRepoName, repo)
+		assert.False(m.t, at.IsZero())
+	}
+	return m.getCLADocumentForRepoResult, m.getCLADocumentForRepoError
+}
+
+func (m mockCLADb) StoreCLADocument(*types.StoredCLADocument) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) GetStoredCLADocumentByUrl(string, string) (*types.StoredCLADocument, error) {
+	panic("implement me")
+}
+
+func (m mockCLADb) SetCLADocumentMapping(*types.CLADocumentMapping) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) RemoveCLADocumentMapping(*types.CLADocumentMapping) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) InsertResignCampaign(*types.ResignCampaign) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) GetResignCandidates(string, string, time.Time) ([]types.ResignContact, error) {
+	panic("implement me")
+}
+
+func (m mockCLADb) UpdateResignContact(string, *types.ResignContact) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) GetResignCampaign(string) (*types.ResignCampaign, error) {
+	panic("implement me")
+}
+
+func (m mockCLADb) ListSignatures(*types.SignatureQuery) (*types.SignaturePage, error) {
+	panic("implement me")
+}
+
+func (m mockCLADb) CountSignatures(*types.SignatureFilter) (int64, error) {
+	panic("implement me")
+}
+
+func (m mockCLADb) ExportSignatures(*types.SignatureFilter, bool, func(signature *types.UserSignature) error) error {
+	panic("implement me")
+}
+
+func (m mockCLADb) ImportSignatures([]types.UserSignature, bool) ([]int, error) {
+	panic("implement me")
+}
+
 func TestHandlePullRequestIsCollaboratorError(t *testing.T) {
 	origGHAppIDEnvVar := os.Getenv(EnvGhAppId)
 	defer func() {
@@ -302,6 +493,7 @@ func TestHandlePullRequestIsCollaboratorError(t *testing.T) {
 
 	mockDB, logger := setupMockDB(t, true)
 	mockDB.hasAuthorSignedLogin = mockAuthorLogin
+	mockDB.hasCorporateSignedLogin = mockAuthorLogin
 
 	err := HandlePullRequest(logger, mockDB, prEvent, 0, "")
 	assert.EqualError(t, err, forcedError.Error())
@@ -346,6 +538,7 @@ func TestHandlePullRequestIsCollaboratorTrueCollaborator(t *testing.T) {
 
 	mockDB, logger := setupMockDB(t, true)
 	mockDB.hasAuthorSignedLogin = mockAuthorLogin
+	mockDB.hasCorporateSignedLogin = mockAuthorLogin
 	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
 
 	err := HandlePullRequest(logger, mockDB, prEvent, 0, "")
@@ -384,6 +577,8 @@ func TestHandlePullRequestCreateLabelError(t *testing.T) {
 
 	mockDB, logger := setupMockDB(t, true)
 	mockDB.hasAuthorSignedLogin = mockAuthorLogin
+	mockDB.getAuthorSignaturesLogin = mockAuthorLogin
+	mockDB.hasCorporateSignedLogin = mockAuthorLogin
 
 	err := HandlePullRequest(logger, mockDB, prEvent, 0, "")
 	assert.EqualError(t, err, forcedError.Error())
@@ -424,6 +619,8 @@ func TestHandlePullRequestAddLabelsToIssueError(t *testing.T) {
 
 	mockDB, logger := setupMockDB(t, true)
 	mockDB.hasAuthorSignedLogin = mockAuthorLogin
+	mockDB.getAuthorSignaturesLogin = mockAuthorLogin
+	mockDB.hasCorporateSignedLogin = mockAuthorLogin
 
 	err := HandlePullRequest(logger, mockDB, prEvent, 0, "")
 	assert.EqualError(t, err, forcedError.Error())
@@ -475,6 +672,8 @@ func TestHandlePullRequestGetAppError(t *testing.T) {
 
 	mockDB, logger := setupMockDB(t, true)
 	mockDB.hasAuthorSignedLogin = mockAuthorLogin
+	mockDB.getAuthorSignaturesLogin = mockAuthorLogin
+	mockDB.hasCorporateSignedLogin = mockAuthorLogin
 	mockDB.storeUsersNeedingToSignEvalInfo = &types.EvaluationInfo{
 		UserSignatures: []types.UserSignature{
 			{
@@ -530,17 +729,25 @@ func TestHandlePullRequestListCommitsError(t *testing.T) {
 		GHImpl = origGithubImpl
 	}()
 	forcedError := fmt.Errorf("forced ListCommits error")
-	GHImpl = &GHInterfaceMock{
+	mockGH := &GHInterfaceMock{
 		RepositoriesMock: *setupMockRepositoriesService(t, false),
 		PullRequestsMock: PullRequestsMock{
 			mockListCommitsError: forcedError,
 		},
 	}
+	GHImpl = mockGH
 
 	prEvent := webhook.PullRequestPayload{}
 	mockDB, logger := setupMockDB(t, true)
 	err := HandlePullRequest(logger, mockDB, prEvent, 0, "")
 	assert.EqualError(t, err, forcedError.Error())
+
+	// the check run started by the evaluation is completed, so it can be re-run
+	assert.Equal(t, "completed", mockGH.ChecksMock.updatedCheckRunOpts.GetStatus())
+	assert.Equal(t, "failure", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+	assert.Equal(t, msgEvaluationFailed, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetTitle())
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), forcedError.Error())
+	assert.Equal(t, checkRunActionRerun, mockGH.ChecksMock.updatedCheckRunOpts.Actions[0].Identifier)
 }
 
 func TestHandlePullRequestListCommits(t *testing.T) {
@@ -611,6 +818,72 @@ func TestHandlePullRequestListCommits(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestHandlePullRequestCorporateSignatureCovers(t *testing.T) {
+	origGHAppIDEnvVar := os.Getenv(EnvGhAppId)
+	defer func() {
+		resetEnvVariable(t, EnvGhAppId, origGHAppIDEnvVar)
+	}()
+	assert.NoError(t, os.Setenv(EnvGhAppId, "-1"))
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	mockAuthorLogin := "myAuthorLogin"
+	mockAuthorEmail := "me@acme.tld"
+	mockRepositoryCommits := []*github.RepositoryCommit{
+		{
+			Author: &github.User{Login: &mockAuthorLogin},
+			Commit: &github.Commit{Author: &github.CommitAuthor{Email: &mockAuthorEmail}},
+		},
+	}
+	GHImpl = &GHInterfaceMock{
+		PullRequestsMock: PullRequestsMock{mockRepositoryCommits: mockRepositoryCommits},
+		IssuesMock: IssuesMock{
+			MockGetLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+			MockRemoveLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+		},
+	}
+
+	prEvent := webhook.PullRequestPayload{}
+
+	claVersion := "myCLAVersion"
+	now := time.Now()
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.hasAuthorSignedLogin = mockAuthorLogin
+	mockDB.getAuthorSignaturesLogin = mockAuthorLogin
+	mockDB.hasAuthorSignedCLAVersion = claVersion
+	mockDB.hasCorporateSignedLogin = mockAuthorLogin
+	mockDB.hasCorporateSignedEmail = mockAuthorEmail
+	mockDB.hasCorporateSignedCLAVersion = claVersion
+	mockDB.hasCorporateSignedResult = true
+	mockDB.hasCorporateSignedSignature = &types.CorporateSignature{
+		Company:    "Acme",
+		CLAVersion: claVersion,
+		TimeSigned: now,
+	}
+	mockDB.removePRsUsersSigned = []types.UserSignature{
+		{
+			User:       types.User{Login: mockAuthorLogin},
+			CLAVersion: claVersion,
+			TimeSigned: now,
+		},
+	}
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, prEvent, 0, claVersion))
+}
+
 func Test_removeLabelFromIssueIfExists_Removed(t *testing.T) {
 	issuesMock := &IssuesMock{
 		MockRemoveLabelResponse: &github.Response{
@@ -691,6 +964,49 @@ func TestReviewPriorPRsEvaluatePRError(t *testing.T) {
 	resetGHJWTImpl := SetupMockGHJWT()
 	defer resetGHJWTImpl()
 
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	forcedError := fmt.Errorf("forced create check run error")
+	GHImpl = &GHInterfaceMock{
+		ChecksMock: ChecksMock{
+			mockCreateCheckRunError: forcedError,
+		},
+	}
+
+	assert.EqualError(t, ReviewPriorPRs(logger, mockDB, &user), forcedError.Error())
+}
+
+func TestReviewPriorPRsEvaluatePRErrorStatusMode(t *testing.T) {
+	origStatusMode := os.Getenv(EnvStatusMode)
+	defer func() {
+		resetEnvVariable(t, EnvStatusMode, origStatusMode)
+	}()
+	assert.NoError(t, os.Setenv(EnvStatusMode, StatusModeStatuses))
+
+	mockDB, logger := setupMockDB(t, true)
+
+	user := types.UserSignature{
+		User: types.User{
+			Login: "myUserLogin",
+		},
+		CLAVersion: "myCLAVersion",
+	}
+
+	mockDB.getPRsForUserUser = &user
+	mockDB.getPRsForUserEvalInfo = []types.EvaluationInfo{
+		{
+			UserSignatures: []types.UserSignature{user},
+		},
+	}
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+
 	origGithubImpl := GHImpl
 	defer func() {
 		GHImpl = origGithubImpl
@@ -744,7 +1060,7 @@ func TestReviewPriorPRsEvalSuccess(t *testing.T) {
 		GHImpl = origGithubImpl
 	}()
 	//forcedError := fmt.Errorf("forced create status error")
-	GHImpl = &GHInterfaceMock{
+	mockGH := &GHInterfaceMock{
 		IssuesMock: IssuesMock{
 			MockGetLabelResponse: &github.Response{
 				Response: &http.Response{},
@@ -754,10 +1070,99 @@ func TestReviewPriorPRsEvalSuccess(t *testing.T) {
 			},
 		},
 	}
+	GHImpl = mockGH
+
+	assert.NoError(t, ReviewPriorPRs(logger, mockDB, &user))
+	assert.Equal(t, "success", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+	assert.Equal(t, checkRunActionRerun, mockGH.ChecksMock.updatedCheckRunOpts.Actions[0].Identifier)
+}
+
+func TestReviewPriorPRsSkipsClosedPR(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+
+	user := types.UserSignature{
+		User: types.User{
+			Login: "myUserLogin",
+		},
+		CLAVersion: "myCLAVersion",
+	}
+
+	mockDB.getPRsForUserUser = &user
+	mockDB.getPRsForUserEvalInfo = []types.EvaluationInfo{
+		{
+			RepoOwner: "myOwner",
+			RepoName:  "myRepo",
+			PRNumber:  5,
+		},
+	}
+	mockDB.removeClosedPREvalInfo = &mockDB.getPRsForUserEvalInfo[0]
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	GHImpl = &GHInterfaceMock{
+		PullRequestsMock: PullRequestsMock{
+			// any attempt to evaluate the PR would fail on listing commits
+			mockListCommitsError: fmt.Errorf("closed PR should not be evaluated"),
+			mockPullRequest:      &github.PullRequest{State: github.String("closed")},
+		},
+	}
 
 	assert.NoError(t, ReviewPriorPRs(logger, mockDB, &user))
 }
 
+func TestReviewPriorPRsGetPRError(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+
+	user := types.UserSignature{
+		User: types.User{
+			Login: "myUserLogin",
+		},
+		CLAVersion: "myCLAVersion",
+	}
+
+	mockDB.getPRsForUserUser = &user
+	mockDB.getPRsForUserEvalInfo = []types.EvaluationInfo{{}}
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	forcedError := fmt.Errorf("forced get PR error")
+	GHImpl = &GHInterfaceMock{
+		PullRequestsMock: PullRequestsMock{
+			mockGetError: forcedError,
+		},
+	}
+
+	assert.EqualError(t, ReviewPriorPRs(logger, mockDB, &user), forcedError.Error())
+}
+
+func TestHandlePullRequestClosed(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+
+	prEvent := webhook.PullRequestPayload{}
+	prEvent.Repository.Owner.Login = "myOwner"
+	prEvent.Repository.Name = "myRepo"
+	prEvent.Number = 5
+	mockDB.removeClosedPREvalInfo = &types.EvaluationInfo{
+		RepoOwner: "myOwner",
+		RepoName:  "myRepo",
+		PRNumber:  5,
+	}
+	forcedError := fmt.Errorf("forced remove closed PR error")
+	mockDB.removeClosedPRError = forcedError
+
+	assert.EqualError(t, HandlePullRequestClosed(logger, mockDB, prEvent), forcedError.Error())
+}
+
 func TestReviewPriorPRs(t *testing.T) {
 	mockDB, logger := setupMockDB(t, true)
 
@@ -779,3 +1184,1162 @@ func TestReviewPriorPRs(t *testing.T) {
 
 	assert.NoError(t, ReviewPriorPRs(logger, mockDB, &user))
 }
+
+func TestReviewOpenPRsForAuthorTrackedPRsError(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getPRsForSignedAuthorLogin = "myLogin"
+	forcedError := fmt.Errorf("forced tracked PRs error")
+	mockDB.getPRsForSignedAuthorError = forcedError
+
+	assert.EqualError(t, ReviewOpenPRsForAuthor(logger, mockDB, -1, "myLogin", "myCLAVersion"), forcedError.Error())
+}
+
+func TestReviewOpenPRsForAuthorTrackedPRClosed(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	trackedPR := types.EvaluationInfo{RepoOwner: "otherOwner", RepoName: "otherRepo", PRNumber: 7, AppId: -1, InstallId: -2}
+	mockDB.getPRsForSignedAuthorLogin = "myLogin"
+	mockDB.getPRsForSignedAuthorResult = []types.EvaluationInfo{trackedPR}
+	mockDB.removeClosedPREvalInfo = &trackedPR
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+	GHJWTImpl = &GHJWTMock{AppsMock: AppsMock{}}
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	GHImpl = &GHInterfaceMock{
+		PullRequestsMock: PullRequestsMock{
+			mockPullRequest: &github.PullRequest{State: github.String("closed")},
+		},
+	}
+
+	assert.NoError(t, ReviewOpenPRsForAuthor(logger, mockDB, -1, "myLogin", "myCLAVersion"))
+}
+
+func TestReviewOpenPRsForAuthorListInstallationsError(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getPRsForSignedAuthorLogin = "myLogin"
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+	forcedError := fmt.Errorf("forced list installations error")
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockInstallationsErr: forcedError,
+		},
+	}
+
+	assert.EqualError(t, ReviewOpenPRsForAuthor(logger, mockDB, -1, "myLogin", "myCLAVersion"), forcedError.Error())
+}
+
+func TestReviewOpenPRsForAuthorSearchError(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getPRsForSignedAuthorLogin = "myLogin"
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockInstallations: []*github.Installation{{ID: github.Int64(-2)}},
+		},
+	}
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	forcedError := fmt.Errorf("forced search error")
+	GHImpl = &GHInterfaceMock{
+		SearchMock: SearchMock{mockIssuesError: forcedError},
+	}
+
+	assert.EqualError(t, ReviewOpenPRsForAuthor(logger, mockDB, -1, "myLogin", "myCLAVersion"), forcedError.Error())
+}
+
+func TestReviewOpenPRsForAuthor(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getPRsForSignedAuthorLogin = "myLogin"
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{
+		RepoOwner: "myOwner",
+		RepoName:  "myRepo",
+		Sha:       "mySha",
+		PRNumber:  5,
+		AppId:     -1,
+		InstallId: -2,
+	}
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockInstallation: &github.Installation{
+				AppSlug: &appSlug,
+			},
+			mockInstallations: []*github.Installation{{ID: github.Int64(-2)}},
+		},
+	}
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	GHImpl = &GHInterfaceMock{
+		SearchMock: SearchMock{
+			mockIssuesResult: &github.IssuesSearchResult{
+				Issues: []*github.Issue{
+					{
+						Number:        github.Int(5),
+						RepositoryURL: github.String("https://api.github.com/repos/myOwner/myRepo"),
+					},
+				},
+			},
+		},
+		PullRequestsMock: PullRequestsMock{
+			mockPullRequest: &github.PullRequest{Head: &github.PullRequestBranch{SHA: github.String("mySha")}},
+		},
+		IssuesMock: IssuesMock{
+			MockGetLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+			MockRemoveLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+		},
+	}
+
+	assert.NoError(t, ReviewOpenPRsForAuthor(logger, mockDB, -1, "myLogin", "myCLAVersion"))
+}
+
+func setupIssueCommentPayload(t *testing.T, body, userType string, onPR bool) (payload webhook.IssueCommentPayload) {
+	pullRequest := ""
+	if onPR {
+		pullRequest = `"pull_request": {"url": "https://api.github.com/repos/myOwner/myRepo/pulls/5"},`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(`{
+		"action": "created",
+		"issue": {%s "number": 5},
+		"comment": {"body": %q, "user": {"login": "commenter", "type": %q}},
+		"repository": {"name": "myRepo", "owner": {"login": "myOwner"}}
+	}`, pullRequest, body, userType)), &payload))
+	return
+}
+
+func TestHandleIssueCommentNotOnPR(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	handled, err := HandleIssueComment(logger, mockDB, setupIssueCommentPayload(t, commandRecheck, "User", false), -1, "")
+	assert.NoError(t, err)
+	assert.False(t, handled)
+}
+
+func TestHandleIssueCommentByBot(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	handled, err := HandleIssueComment(logger, mockDB, setupIssueCommentPayload(t, commandRecheck, "Bot", true), -1, "")
+	assert.NoError(t, err)
+	assert.False(t, handled)
+}
+
+func TestHandleIssueCommentOtherText(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	handled, err := HandleIssueComment(logger, mockDB, setupIssueCommentPayload(t, "looks good to me", "User", true), -1, "")
+	assert.NoError(t, err)
+	assert.False(t, handled)
+}
+
+func TestHandleIssueCommentFindInstallationError(t *testing.T) {
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+	forcedError := fmt.Errorf("forced find installation error")
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockRepoInstallErr: forcedError,
+		},
+	}
+
+	mockDB, logger := setupMockDB(t, true)
+	handled, err := HandleIssueComment(logger, mockDB, setupIssueCommentPayload(t, " /CLA Recheck ", "User", true), -1, "")
+	assert.EqualError(t, err, forcedError.Error())
+	assert.False(t, handled)
+}
+
+func TestHandleIssueComment(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{
+		RepoOwner: "myOwner",
+		RepoName:  "myRepo",
+		Sha:       "mySha",
+		PRNumber:  5,
+		AppId:     -1,
+		InstallId: -2,
+	}
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockInstallation: &github.Installation{
+				AppSlug: &appSlug,
+			},
+			mockRepoInstallation: &github.Installation{ID: github.Int64(-2)},
+		},
+	}
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	GHImpl = &GHInterfaceMock{
+		PullRequestsMock: PullRequestsMock{
+			mockPullRequest: &github.PullRequest{Head: &github.PullRequestBranch{SHA: github.String("mySha")}},
+		},
+		IssuesMock: IssuesMock{
+			MockGetLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+			MockRemoveLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+		},
+	}
+
+	handled, err := HandleIssueComment(logger, mockDB, setupIssueCommentPayload(t, commandRecheck, "User", true), -1, "myCLAVersion")
+	assert.NoError(t, err)
+	assert.True(t, handled)
+}
+
+func TestBuildCheckRunSummary(t *testing.T) {
+	summary := buildCheckRunSummary([]authorStatus{
+		{Login: "signer", Signed: true, CLAVersion: "1.0"},
+		{Login: "unsigned", CLAVersion: "1.0", Note: "needs to sign the CLA"},
+		{Login: "signer", Signed: true, CLAVersion: "1.0"},
+	})
+	assert.Equal(t, `| Commit author | CLA signed | CLA version | Note |
+| --- | --- | --- | --- |
+| @signer | :white_check_mark: | 1.0 |  |
+| @unsigned | :x: | 1.0 | needs to sign the CLA |
+`, summary)
+}
+
+func TestHandleCheckRunIgnoredAction(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	handled, err := HandleCheckRun(logger, mockDB, &github.CheckRunEvent{Action: github.String("completed")}, -1, "")
+	assert.NoError(t, err)
+	assert.False(t, handled)
+}
+
+func TestHandleCheckRunOtherRequestedAction(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	handled, err := HandleCheckRun(logger, mockDB, &github.CheckRunEvent{
+		Action:          github.String("requested_action"),
+		RequestedAction: &github.RequestedAction{Identifier: "somethingElse"},
+	}, -1, "")
+	assert.NoError(t, err)
+	assert.False(t, handled)
+}
+
+func TestHandleCheckRunInvalidExternalID(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	handled, err := HandleCheckRun(logger, mockDB, &github.CheckRunEvent{
+		Action:   github.String("rerequested"),
+		CheckRun: &github.CheckRun{ExternalID: github.String("notANumber")},
+	}, -1, "")
+	assert.EqualError(t, err, `strconv.ParseInt: parsing "notANumber": invalid syntax`)
+	assert.False(t, handled)
+}
+
+func TestHandleCheckRun(t *testing.T) {
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{
+		RepoOwner: "myOwner",
+		RepoName:  "myRepo",
+		Sha:       "mySha",
+		PRNumber:  5,
+		AppId:     -1,
+		InstallId: -2,
+	}
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	GHImpl = &GHInterfaceMock{
+		IssuesMock: IssuesMock{
+			MockGetLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+			MockRemoveLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+		},
+	}
+
+	handled, err := HandleCheckRun(logger, mockDB, &github.CheckRunEvent{
+		Action:          github.String("requested_action"),
+		RequestedAction: &github.RequestedAction{Identifier: checkRunActionRerun},
+		CheckRun: &github.CheckRun{
+			HeadSHA:    github.String("mySha"),
+			ExternalID: github.String("5"),
+		},
+		Repo: &github.Repository{
+			Name:  github.String("myRepo"),
+			Owner: &github.User{Login: github.String("myOwner")},
+		},
+		Installation: &github.Installation{ID: github.Int64(-2)},
+	}, -1, "myCLAVersion")
+	assert.NoError(t, err)
+	assert.True(t, handled)
+}
+
+func TestParseCoAuthors(t *testing.T) {
+	assert.Nil(t, parseCoAuthors("just a commit message"))
+	assert.Equal(t, []gitAuthor{
+		{Name: "Jane Doe", Email: "jane@doe.tld"},
+		{Name: "octocat", Email: "1+octocat@users.noreply.github.com"},
+	}, parseCoAuthors(`fix the thing
+
+Co-authored-by: Jane Doe <jane@doe.tld>
+co-authored-by:octocat <1+octocat@users.noreply.github.com>  `))
+}
+
+func TestResolveLoginByEmailNoReply(t *testing.T) {
+	searchMock := &SearchMock{mockUsersError: fmt.Errorf("search should not be called")}
+
+	account, err := resolveLoginByEmail(searchMock, "12345+octocat@users.noreply.github.com")
+	assert.NoError(t, err)
+	assert.Equal(t, gitHubAccount{login: "octocat", userId: 12345}, account)
+
+	account, err = resolveLoginByEmail(searchMock, "octocat@Users.NoReply.GitHub.com")
+	assert.NoError(t, err)
+	assert.Equal(t, gitHubAccount{login: "octocat"}, account)
+}
+
+func TestResolveLoginByEmailSearchError(t *testing.T) {
+	forcedError := fmt.Errorf("forced search users error")
+	_, err := resolveLoginByEmail(&SearchMock{mockUsersError: forcedError}, "jane@doe.tld")
+	assert.EqualError(t, err, forcedError.Error())
+}
+
+func TestResolveLoginByEmailSearch(t *testing.T) {
+	account, err := resolveLoginByEmail(&SearchMock{
+		mockUsersResult: &github.UsersSearchResult{Users: []*github.User{{Login: github.String("jane"), ID: github.Int64(42)}}},
+	}, "jane@doe.tld")
+	assert.NoError(t, err)
+	assert.Equal(t, gitHubAccount{login: "jane", userId: 42}, account)
+}
+
+func TestResolveLoginByEmailNotFound(t *testing.T) {
+	account, err := resolveLoginByEmail(&SearchMock{mockUsersResult: &github.UsersSearchResult{}}, "jane@doe.tld")
+	assert.NoError(t, err)
+	assert.Equal(t, gitHubAccount{}, account)
+}
+
+func TestHandlePullRequestCoAuthors(t *testing.T) {
+	origGHAppIDEnvVar := os.Getenv(EnvGhAppId)
+	defer func() {
+		resetEnvVariable(t, EnvGhAppId, origGHAppIDEnvVar)
+	}()
+	assert.NoError(t, os.Setenv(EnvGhAppId, "-1"))
+
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+
+	resetGHJWTImpl := SetupMockGHJWT()
+	defer resetGHJWTImpl()
+	mockExternalUrl := "fakeExternalURL"
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockInstallation: &github.Installation{
+				AppSlug: &appSlug,
+			},
+			mockAppResp: &github.Response{Response: &http.Response{StatusCode: http.StatusOK}},
+			mockApp:     &github.App{ExternalURL: &mockExternalUrl},
+		},
+	}
+
+	origGithubImpl := GHImpl
+	defer func() {
+		GHImpl = origGithubImpl
+	}()
+	mockRepositoryCommits := []*github.RepositoryCommit{
+		{
+			Author: &github.User{Login: github.String("john")},
+			SHA:    github.String("johnSHA"),
+			Commit: &github.Commit{
+				Message: github.String("pair programmed\n\nCo-authored-by: Doe <1+doe@users.noreply.github.com>\nCo-authored-by: Jane <jane@doe.tld>"),
+			},
+		},
+		{
+			Author: &github.User{Login: github.String("john")},
+			SHA:    github.String("johnSHA2"),
+			Commit: &github.Commit{
+				Message: github.String("fixup\n\nCo-authored-by: Jane <Jane@Doe.tld>\nCo-authored-by: Jane <jane@doe.tld>"),
+			},
+		},
+	}
+	mockGH := &GHInterfaceMock{
+		PullRequestsMock: PullRequestsMock{
+			mockRepositoryCommits: mockRepositoryCommits,
+		},
+		SearchMock: SearchMock{
+			mockUsersResult: &github.UsersSearchResult{},
+		},
+		IssuesMock: IssuesMock{
+			mockGetLabel: &github.Label{},
+			MockGetLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+			MockRemoveLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+		},
+	}
+	GHImpl = mockGH
+
+	prEvent := webhook.PullRequestPayload{}
+
+	mockDB, logger := setupMockDB(t, false)
+	assert.NoError(t, HandlePullRequest(logger, mockDB, prEvent, 0, "myCLAVersion"))
+
+	assert.Equal(t, "failure", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+	// the email of a co-author is only searched once, however many trailers name it
+	assert.Equal(t, 1, mockGH.SearchMock.usersCalls)
+	assert.Equal(t, `| Commit author | CLA signed | CLA version | Note |
+| --- | --- | --- | --- |
+| @john | :x: | myCLAVersion | needs to sign the CLA |
+| @doe | :x: | myCLAVersion | needs to sign the CLA |
+| `+"`jane@doe.tld`"+` | :x: |  | no GitHub account found for this email |
+`, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary())
+}
+
+func TestBuildUnlinkedAuthorsMessage(t *testing.T) {
+	assert.Equal(t, "We could not find a GitHub account for these commit authors or co-authors, so we can not tell if they signed the CLA:\n"+
+		"\n- Jane `jane@doe.tld` (commit mySha)"+
+		"\n\nPlease add the email to the GitHub account of the author (as a public email for co-authors), "+
+		"make sure they [sign the Contributor License Agreement](myUrl), and comment `/cla recheck` on this PR.",
+		buildUnlinkedAuthorsMessage([]gitAuthor{{Name: "Jane", Email: "jane@doe.tld", CommitSHA: "mySha"}}, "myUrl"))
+}
+
+func setupUnlinkedAuthorPR(t *testing.T) (mockGH *GHInterfaceMock, reset func()) {
+	origGHAppIDEnvVar := os.Getenv(EnvGhAppId)
+	assert.NoError(t, os.Setenv(EnvGhAppId, "-1"))
+	resetPemFileImpl := SetupTestPemFile(t)
+	resetGHJWTImpl := SetupMockGHJWT()
+	mockExternalUrl := "fakeExternalURL"
+	GHJWTImpl = &GHJWTMock{
+		AppsMock: AppsMock{
+			mockInstallation: &github.Installation{
+				AppSlug: &appSlug,
+			},
+			mockAppResp: &github.Response{Response: &http.Response{StatusCode: http.StatusOK}},
+			mockApp:     &github.App{ExternalURL: &mockExternalUrl},
+		},
+	}
+	origGithubImpl := GHImpl
+	reset = func() {
+		GHImpl = origGithubImpl
+		resetGHJWTImpl()
+		resetPemFileImpl()
+		resetEnvVariable(t, EnvGhAppId, origGHAppIDEnvVar)
+	}
+
+	mockGH = &GHInterfaceMock{
+		PullRequestsMock: PullRequestsMock{
+			// the commit email is not linked to any GitHub account, so there is no author
+			mockRepositoryCommits: []*github.RepositoryCommit{
+				{
+					SHA: github.String("mySha"),
+					Commit: &github.Commit{
+						Author: &github.CommitAuthor{Name: github.String("Jane"), Email: github.String("jane@doe.tld")},
+					},
+				},
+			},
+		},
+		IssuesMock: IssuesMock{
+			mockGetLabel: &github.Label{},
+			MockGetLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+			MockRemoveLabelResponse: &github.Response{
+				Response: &http.Response{},
+			},
+		},
+	}
+	GHImpl = mockGH
+	return
+}
+
+func TestHandlePullRequestAuthorNotLinked(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.hasEmailSignedEmail = "jane@doe.tld"
+	mockDB.hasEmailSignedCLAVersion = "myCLAVersion"
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Equal(t, "failure", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| `jane@doe.tld` | :x: |  | no GitHub account found for this email |")
+}
+
+func TestHandlePullRequestAuthorNotLinkedSignedByEmail(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.hasEmailSignedEmail = "jane@doe.tld"
+	mockDB.hasEmailSignedCLAVersion = "myCLAVersion"
+	mockDB.hasEmailSignedResult = true
+	mockDB.hasEmailSignedSignature = &types.UserSignature{
+		User:       types.User{Login: "jane"},
+		CLAVersion: "myCLAVersion",
+	}
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Equal(t, "success", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| `jane@doe.tld` | :white_check_mark: | myCLAVersion | signed by @jane with this verified email |")
+}
+
+func TestHandlePullRequestAuthorNotLinkedEmailDBError(t *testing.T) {
+	_, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+
+	mockDB, logger := setupMockDB(t, false)
+	forcedError := fmt.Errorf("forced email signature db error")
+	mockDB.hasEmailSignedError = forcedError
+
+	assert.EqualError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"), forcedError.Error())
+}
+
+func TestListPullRequestCommitsMultiplePages(t *testing.T) {
+	pullRequestsMock := &PullRequestsMock{
+		mockRepositoryCommitsPages: [][]*github.RepositoryCommit{
+			{{SHA: github.String("sha1")}, {SHA: github.String("sha2")}},
+			{{SHA: github.String("sha3")}},
+			{{SHA: github.String("sha4")}},
+		},
+	}
+
+	commits, err := listPullRequestCommits(pullRequestsMock, &types.EvaluationInfo{})
+	assert.NoError(t, err)
+	var shas []string
+	for _, commit := range commits {
+		shas = append(shas, commit.GetSHA())
+	}
+	assert.Equal(t, []string{"sha1", "sha2", "sha3", "sha4"}, shas)
+}
+
+func TestListPullRequestCommitsError(t *testing.T) {
+	forcedError := fmt.Errorf("forced ListCommits error")
+	pullRequestsMock := &PullRequestsMock{
+		mockRepositoryCommitsPages: [][]*github.RepositoryCommit{{}, {}},
+		mockListCommitsError:       forcedError,
+	}
+
+	_, err := listPullRequestCommits(pullRequestsMock, &types.EvaluationInfo{})
+	assert.EqualError(t, err, forcedError.Error())
+}
+
+func TestAddCommentToIssueIfNotExistsFoundOnLaterPage(t *testing.T) {
+	message := "myMessage"
+	issuesMock := &IssuesMock{
+		mockListCommentsPages: [][]*github.IssueComment{
+			{{Body: github.String("other")}},
+			{{Body: github.String("another")}, {Body: github.String(message)}},
+		},
+		mockCreateCommentError: fmt.Errorf("comment should not be created again"),
+	}
+
+	comment, err := addCommentToIssueIfNotExists(issuesMock, "", "", 0, message)
+	assert.NoError(t, err)
+	assert.Nil(t, comment)
+}
+
+func TestAddLabelToIssueIfNotExistsFoundOnLaterPage(t *testing.T) {
+	labelName := "myLabel"
+	issuesMock := &IssuesMock{
+		mockListLabelsByIssuePages: [][]*github.Label{
+			{{Name: github.String("other")}},
+			{{Name: github.String(labelName)}},
+		},
+		mockAddLabelsError: fmt.Errorf("label should not be added again"),
+	}
+
+	label, err := _addLabelToIssueIfNotExists(zaptest.NewLogger(t), issuesMock, "", "", 0, labelName)
+	assert.NoError(t, err)
+	assert.Equal(t, labelName, label.GetName())
+}
+
+func TestListInstallationsMultiplePages(t *testing.T) {
+	ghJWTClient := (&GHJWTMock{
+		AppsMock: AppsMock{
+			mockInstallationsPages: [][]*github.Installation{
+				{{ID: github.Int64(1)}},
+				{{ID: github.Int64(2)}},
+			},
+		},
+	}).NewJWTClient(nil, 0)
+
+	installs, err := ghJWTClient.ListInstallations()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(installs))
+	assert.Equal(t, int64(2), installs[1].GetID())
+}
+
+func TestHandlePullRequestCommitsOnLaterPage(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john")}}},
+		{{Author: &github.User{Login: github.String("doe")}}},
+	}
+
+	mockDB, logger := setupMockDB(t, false)
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| @doe | :x: | myCLAVersion | needs to sign the CLA |")
+}
+
+func TestAllowListAllowedReason(t *testing.T) {
+	allowList := AllowList{
+		Logins:       []string{"Renovate[bot]", "*-ci", `*\[bot\]`, "[bad"},
+		AccountTypes: []string{"Bot"},
+	}
+	assert.Equal(t, "allow-listed account type Bot", allowList.allowedReason("someone", "Bot"))
+	assert.Equal(t, "allow-listed login", allowList.allowedReason("renovate[bot]", "User"))
+	assert.Equal(t, "allow-listed by pattern *-ci", allowList.allowedReason("acme-CI", "User"))
+	assert.Equal(t, `allow-listed by pattern *\[bot\]`, allowList.allowedReason("dependabot[bot]", ""))
+	assert.Equal(t, "", allowList.allowedReason("someone", "User"))
+	assert.Equal(t, "", allowList.allowedReason("someone", ""))
+	assert.Equal(t, "", AllowList{}.allowedReason("dependabot[bot]", "Bot"))
+}
+
+func TestGetAllowList(t *testing.T) {
+	origLogins := os.Getenv(EnvAllowListLogins)
+	origTypes := os.Getenv(EnvAllowListTypes)
+	defer func() {
+		resetEnvVariable(t, EnvAllowListLogins, origLogins)
+		resetEnvVariable(t, EnvAllowListTypes, origTypes)
+	}()
+	assert.NoError(t, os.Setenv(EnvAllowListLogins, " dependabot[bot], ,*-ci"))
+	assert.NoError(t, os.Unsetenv(EnvAllowListTypes))
+
+	assert.Equal(t, AllowList{Logins: []string{"dependabot[bot]", "*-ci"}}, getAllowList())
+}
+
+func TestHandlePullRequestAllowListedBot(t *testing.T) {
+	origTypes := os.Getenv(EnvAllowListTypes)
+	defer func() {
+		resetEnvVariable(t, EnvAllowListTypes, origTypes)
+	}()
+	assert.NoError(t, os.Setenv(EnvAllowListTypes, "Bot"))
+
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("dependabot[bot]"), Type: github.String("Bot")}}},
+	}
+
+	// asserting parameters fails if the signature of the bot is queried
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Equal(t, "success", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| @dependabot[bot] | :white_check_mark: |  | allow-listed account type Bot |")
+}
+
+func TestLoadRepoConfigDefaults(t *testing.T) {
+	config, err := LoadRepoConfig(zaptest.NewLogger(t), &RepositoriesMock{}, "myOwner", "myRepo")
+	assert.NoError(t, err)
+	assert.Equal(t, defaultRepoConfig(), config)
+}
+
+func TestLoadRepoConfigOwnerAndRepo(t *testing.T) {
+	repositoriesMock := &RepositoriesMock{
+		mockConfigContents: map[string]string{
+			".github": `
+claVersion: "2.0"
+allowList:
+  logins: [org-bot]
+labelSigned:
+  name: cla ok
+  color: "00ff00"
+commentTemplate: "Please sign {signUrl}, {users}"
+collaboratorsExempt: false
+`,
+			"myRepo": `
+claVersion: "3.0"
+allowList:
+  accountTypes: [Bot]
+labelSigned:
+  name: cla signed here
+`,
+		},
+	}
+
+	config, err := LoadRepoConfig(zaptest.NewLogger(t), repositoriesMock, "myOwner", "myRepo")
+	assert.NoError(t, err)
+	assert.Equal(t, "3.0", config.CLAVersion)
+	assert.Equal(t, []string{"org-bot"}, config.AllowList.Logins)
+	assert.Equal(t, []string{"Bot"}, config.AllowList.AccountTypes)
+	assert.Equal(t, LabelConfig{Name: "cla signed here", Color: "00ff00"}, config.LabelSigned)
+	assert.Equal(t, defaultRepoConfig().LabelNotSigned, config.LabelNotSigned)
+	assert.Equal(t, "Please sign {signUrl}, {users}", config.CommentTemplate)
+	assert.False(t, *config.CollaboratorsExempt)
+}
+
+func TestLoadRepoConfigInvalid(t *testing.T) {
+	repositoriesMock := &RepositoriesMock{
+		mockConfigContents: map[string]string{"myRepo": "claVersion: [not, a, string"},
+	}
+
+	_, err := LoadRepoConfig(zaptest.NewLogger(t), repositoriesMock, "myOwner", "myRepo")
+	assert.ErrorContains(t, err, "invalid config file .github/cla.yml in myOwner/myRepo")
+}
+
+func TestLoadRepoConfigGetContentsError(t *testing.T) {
+	forcedError := fmt.Errorf("forced get contents error")
+	_, err := LoadRepoConfig(zaptest.NewLogger(t), &RepositoriesMock{mockGetContentsErr: forcedError}, "myOwner", "myRepo")
+	assert.EqualError(t, err, forcedError.Error())
+}
+
+func TestLoadRepoConfigForbidden(t *testing.T) {
+	config, err := LoadRepoConfig(zaptest.NewLogger(t), &RepositoriesMock{
+		mockGetContentsErr:    fmt.Errorf("403 Resource not accessible by integration"),
+		mockGetContentsStatus: http.StatusForbidden,
+	}, "myOwner", "myRepo")
+	assert.NoError(t, err)
+	assert.Equal(t, defaultRepoConfig(), config)
+}
+
+func TestHandlePullRequestRepoConfig(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john")}}},
+	}
+	mockGH.RepositoriesMock = RepositoriesMock{
+		// collaborators are not exempt, so asking GitHub would fail the evaluation
+		isCollaboratorErr: fmt.Errorf("collaborator should not be checked"),
+		mockConfigContents: map[string]string{
+			"": `
+claVersion: "2.0"
+collaboratorsExempt: false
+`,
+		},
+	}
+
+	var commentBodies []string
+	mockGH.IssuesMock.createdCommentBodies = &commentBodies
+
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getCLADocumentsByVersion = "2.0"
+	mockDB.getCLADocumentsByVersionDocs = []types.CLADocument{{Id: "icla", CLAVersion: "2.0"}}
+	mockDB.hasAuthorSignedLogin = "john"
+	mockDB.getAuthorSignaturesLogin = "john"
+	mockDB.hasAuthorSignedCLAVersion = "2.0"
+	mockDB.hasCorporateSignedLogin = "john"
+	mockDB.hasCorporateSignedCLAVersion = "2.0"
+	mockDB.storeUsersNeedingToSignEvalInfo = &types.EvaluationInfo{
+		UserSignatures: []types.UserSignature{{User: types.User{Login: "john"}, CLAVersion: "2.0"}},
+	}
+	mockDB.removePRsEvalInfo = mockDB.storeUsersNeedingToSignEvalInfo
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| @john | :x: | 2.0 | needs to sign the CLA |")
+	// the sign link selects the document of the configured version
+	assert.Equal(t, 1, len(commentBodies))
+	assert.Contains(t, commentBodies[0], "(fakeExternalURL?claversion=2.0&owner=&repo=)")
+}
+
+func TestHandlePullRequestRepoConfigVersionNotPublished(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.RepositoriesMock = RepositoriesMock{
+		mockConfigContents: map[string]string{"": `claVersion: "9.9"`},
+	}
+
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getCLADocumentsByVersion = "9.9"
+
+	assert.EqualError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"),
+		"claVersion 9.9 of .github/cla.yml is not a CLA version published by the server")
+	assert.Equal(t, "failure", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+}
+
+func TestHandlePullRequestRepoConfigDefaultVersion(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john")}}},
+	}
+	mockGH.RepositoriesMock = RepositoriesMock{
+		mockConfigContents: map[string]string{"": `claVersion: "myCLAVersion"`},
+	}
+	// a repo mapped to another document may still require the version of the default document
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getCLADocumentForRepoResult = &types.CLADocument{Id: "icla", CLAVersion: "3.0"}
+	mockDB.getCLADocumentsByVersion = "myCLAVersion"
+	mockDB.hasAuthorSignedLogin = "john"
+	mockDB.hasAuthorSignedCLAVersion = "myCLAVersion"
+	mockDB.hasAuthorSignedResult = true
+	mockDB.hasAuthorSignedSignature = &types.UserSignature{User: types.User{Login: "john"}, CLAVersion: "myCLAVersion"}
+	mockDB.removePRsUsersSigned = []types.UserSignature{*mockDB.hasAuthorSignedSignature}
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Equal(t, "success", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+}
+
+func TestLoadRepoConfigVersionPolicy(t *testing.T) {
+	repositoriesMock := &RepositoriesMock{
+		mockConfigContents: map[string]string{
+			".github": `
+versionPolicy:
+  accept: minimum
+  minimumVersion: "1.1"
+`,
+			"myRepo": `
+versionPolicy:
+  resignAfter: 2022-06-01T00:00:00Z
+`,
+		},
+	}
+
+	config, err := LoadRepoConfig(zaptest.NewLogger(t), repositoriesMock, "myOwner", "myRepo")
+	assert.NoError(t, err)
+	resignAfter := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, VersionPolicy{Accept: VersionPolicyMinimum, MinimumVersion: "1.1", ResignAfter: &resignAfter}, config.VersionPolicy)
+}
+
+func TestVersionPolicyAccepts(t *testing.T) {
+	resignAfter := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	before := resignAfter.Add(-time.Hour)
+	after := resignAfter.Add(time.Hour)
+	for _, tc := range []struct {
+		name          string
+		policy        VersionPolicy
+		signedVersion string
+		signedAt      time.Time
+		claVersion    string
+		now           time.Time
+		accepted      bool
+	}{
+		{"exact", VersionPolicy{Accept: VersionPolicyExact}, "1.0", before, "1.1", before, false},
+		{"same major", VersionPolicy{Accept: VersionPolicySameMajor}, "1.0", before, "1.2", before, true},
+		{"same major prefix", VersionPolicy{Accept: VersionPolicySameMajor}, "v1", before, "1.2.3", before, true},
+		{"other major", VersionPolicy{Accept: VersionPolicySameMajor}, "1.9", before, "2.0", before, false},
+		{"newer", VersionPolicy{Accept: VersionPolicySameMajor}, "1.3", before, "1.2", before, false},
+		{"minimum", VersionPolicy{Accept: VersionPolicyMinimum, MinimumVersion: "1.1"}, "1.10", before, "2.0", before, true},
+		{"below minimum", VersionPolicy{Accept: VersionPolicyMinimum, MinimumVersion: "1.1"}, "1.0", before, "2.0", before, false},
+		{"invalid minimum", VersionPolicy{Accept: VersionPolicyMinimum, MinimumVersion: "one"}, "1.0", before, "2.0", before, false},
+		{"not a number", VersionPolicy{Accept: VersionPolicySameMajor}, "myCLAVersion", before, "1.2", before, false},
+		{"resign not yet due", VersionPolicy{Accept: VersionPolicySameMajor, ResignAfter: &resignAfter}, "1.0", before, "1.2", before, true},
+		{"resign due", VersionPolicy{Accept: VersionPolicySameMajor, ResignAfter: &resignAfter}, "1.0", before, "1.2", after, false},
+		{"signed after resign date", VersionPolicy{Accept: VersionPolicySameMajor, ResignAfter: &resignAfter}, "1.0", after, "1.2", after, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			signature := &types.UserSignature{CLAVersion: tc.signedVersion, TimeSigned: tc.signedAt}
+			assert.Equal(t, tc.accepted, tc.policy.accepts(signature, tc.claVersion, tc.now))
+		})
+	}
+}
+
+func TestFindPriorSignature(t *testing.T) {
+	signatures := []types.UserSignature{{CLAVersion: "3.0"}, {CLAVersion: "1.1"}, {CLAVersion: "1.0"}}
+
+	prior, accepted := findPriorSignature(VersionPolicy{Accept: VersionPolicyExact}, signatures, "2.0", time.Now())
+	assert.False(t, accepted)
+	assert.Equal(t, "1.1", prior.CLAVersion)
+
+	prior, accepted = findPriorSignature(VersionPolicy{Accept: VersionPolicyMinimum, MinimumVersion: "1.0"}, signatures, "2.0", time.Now())
+	assert.True(t, accepted)
+	assert.Equal(t, "1.1", prior.CLAVersion)
+
+	prior, accepted = findPriorSignature(VersionPolicy{Accept: VersionPolicyExact}, signatures[:1], "2.0", time.Now())
+	assert.False(t, accepted)
+	assert.Nil(t, prior)
+}
+
+func TestIsPriorVersion(t *testing.T) {
+	assert.True(t, IsPriorVersion("1.0", "2.0"))
+	assert.True(t, IsPriorVersion("1.9", "1.10"))
+	assert.False(t, IsPriorVersion("2.0", "2.0"))
+	assert.False(t, IsPriorVersion("3.0", "2.0"))
+	// versions that are not numbers can not be ordered
+	assert.True(t, IsPriorVersion("draft", "2.0"))
+	assert.True(t, IsPriorVersion("3.0", "final"))
+}
+
+func TestHandlePullRequestOlderVersionAccepted(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john"), ID: github.Int64(42)}}},
+	}
+	mockGH.RepositoriesMock.mockConfigContents = map[string]string{
+		"": `
+versionPolicy:
+  accept: sameMajor
+`,
+	}
+
+	now := time.Now()
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.hasAuthorSignedLogin = "john"
+	mockDB.hasAuthorSignedUserId = 42
+	mockDB.hasAuthorSignedCLAVersion = "1.2"
+	mockDB.getAuthorSignaturesLogin = "john"
+	mockDB.getAuthorSignaturesUserId = 42
+	mockDB.getAuthorSignaturesResult = []types.UserSignature{{
+		User:       types.User{Login: "john"},
+		CLAVersion: "1.0",
+		TimeSigned: now,
+		Evidence:   &types.SigningEvidence{GitHubUserId: 42},
+	}}
+	// the tracked rows of the author are removed for the required version, not the version they signed
+	mockDB.removePRsUsersSigned = []types.UserSignature{{
+		User:       types.User{Login: "john"},
+		CLAVersion: "1.2",
+		TimeSigned: now,
+		Evidence:   &types.SigningEvidence{GitHubUserId: 42},
+	}}
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "1.2"))
+	assert.Equal(t, "success", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| @john | :white_check_mark: | 1.0 | accepted by the version policy |")
+}
+
+func TestHandlePullRequestOlderVersionNeedsToSignAgain(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john")}}},
+	}
+	var commentBodies []string
+	mockGH.IssuesMock.createdCommentBodies = &commentBodies
+
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.hasAuthorSignedLogin = "john"
+	mockDB.hasAuthorSignedCLAVersion = "2.0"
+	mockDB.getAuthorSignaturesLogin = "john"
+	mockDB.getAuthorSignaturesResult = []types.UserSignature{{User: types.User{Login: "john"}, CLAVersion: "1.0"}}
+	mockDB.hasCorporateSignedLogin = "john"
+	mockDB.hasCorporateSignedCLAVersion = "2.0"
+	mockDB.storeUsersNeedingToSignEvalInfo = &types.EvaluationInfo{
+		UserSignatures: []types.UserSignature{{User: types.User{Login: "john"}, CLAVersion: "2.0"}},
+	}
+	mockDB.removePRsEvalInfo = mockDB.storeUsersNeedingToSignEvalInfo
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "2.0"))
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| @john | :x: | 2.0 | signed version 1.0, needs to sign the CLA again |")
+	assert.Equal(t, 1, len(commentBodies))
+	assert.Contains(t, commentBodies[0],
+		"please [sign version 2.0 of the Contributor License Agreement](fakeExternalURL):\n\n- @john signed version 1.0")
+}
+
+func TestHandlePullRequestGetAuthorSignaturesError(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john")}}},
+	}
+
+	forcedError := fmt.Errorf("forced get author signatures error")
+	mockDB, logger := setupMockDB(t, false)
+	mockDB.getAuthorSignaturesError = forcedError
+
+	assert.EqualError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "2.0"), forcedError.Error())
+}
+
+func TestHandlePullRequestSignedBeforeRename(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john-new"), ID: github.Int64(42)}}},
+	}
+
+	now := time.Now()
+	updateCalls := 0
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.hasAuthorSignedLogin = "john-new"
+	mockDB.hasAuthorSignedUserId = 42
+	mockDB.hasAuthorSignedCLAVersion = "myCLAVersion"
+	mockDB.hasAuthorSignedResult = true
+	mockDB.hasAuthorSignedSignature = &types.UserSignature{
+		User:       types.User{Login: "john-old"},
+		CLAVersion: "myCLAVersion",
+		TimeSigned: now,
+		Evidence:   &types.SigningEvidence{GitHubUserId: 42},
+	}
+	mockDB.updateSignatureLoginUserId = 42
+	mockDB.updateSignatureLoginLogin = "john-new"
+	mockDB.updateSignatureLoginCalls = &updateCalls
+	mockDB.removePRsUsersSigned = []types.UserSignature{{
+		User:       types.User{Login: "john-new"},
+		CLAVersion: "myCLAVersion",
+		TimeSigned: now,
+		Evidence:   &types.SigningEvidence{GitHubUserId: 42},
+	}}
+	mockDB.removePRsEvalInfo = &types.EvaluationInfo{}
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Equal(t, 1, updateCalls)
+	assert.Equal(t, "success", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+}
+
+func TestHandlePullRequestSignedWithoutUserId(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john"), ID: github.Int64(42)}}},
+	}
+
+	updateCalls := 0
+	mockDB, logger := setupMockDB(t, false)
+	mockDB.hasAuthorSignedResult = true
+	mockDB.hasAuthorSignedSignature = &types.UserSignature{
+		User:       types.User{Login: "john"},
+		CLAVersion: "myCLAVersion",
+		Evidence:   &types.SigningEvidence{},
+	}
+	mockDB.updateSignatureLoginCalls = &updateCalls
+	// failing to record the user id does not fail the evaluation
+	mockDB.updateSignatureLoginError = fmt.Errorf("forced update error")
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Equal(t, 1, updateCalls)
+	assert.Equal(t, "success", mockGH.ChecksMock.updatedCheckRunOpts.GetConclusion())
+}
+
+func TestHandlePullRequestSignedSameLogin(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john"), ID: github.Int64(42)}}},
+	}
+
+	updateCalls := 0
+	mockDB, logger := setupMockDB(t, false)
+	mockDB.hasAuthorSignedResult = true
+	mockDB.hasAuthorSignedSignature = &types.UserSignature{
+		User:       types.User{Login: "john"},
+		CLAVersion: "myCLAVersion",
+		Evidence:   &types.SigningEvidence{GitHubUserId: 42},
+	}
+	mockDB.updateSignatureLoginCalls = &updateCalls
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"))
+	assert.Equal(t, 0, updateCalls)
+}
+
+func TestHandlePullRequestCLADocumentMapped(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john")}}},
+	}
+
+	prEvent := webhook.PullRequestPayload{}
+	prEvent.Repository.Owner.Login = "myOwner"
+	prEvent.Repository.Name = "myRepo"
+
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getCLADocumentForRepoOwner = "myOwner"
+	mockDB.getCLADocumentForRepoName = "myRepo"
+	mockDB.getCLADocumentForRepoResult = &types.CLADocument{Id: "icla", CLAVersion: "3.0"}
+	mockDB.hasAuthorSignedLogin = "john"
+	mockDB.getAuthorSignaturesLogin = "john"
+	mockDB.hasAuthorSignedCLAVersion = "3.0"
+	mockDB.hasCorporateSignedLogin = "john"
+	mockDB.hasCorporateSignedCLAVersion = "3.0"
+	mockDB.storeUsersNeedingToSignEvalInfo = &types.EvaluationInfo{
+		RepoOwner:      "myOwner",
+		RepoName:       "myRepo",
+		UserSignatures: []types.UserSignature{{User: types.User{Login: "john"}, CLAVersion: "3.0"}},
+	}
+	mockDB.removePRsEvalInfo = mockDB.storeUsersNeedingToSignEvalInfo
+
+	assert.NoError(t, HandlePullRequest(logger, mockDB, prEvent, 0, "myCLAVersion"))
+	assert.Contains(t, mockGH.ChecksMock.updatedCheckRunOpts.Output.GetSummary(), "| @john | :x: | 3.0 | needs to sign the CLA |")
+}
+
+func TestHandlePullRequestCLADocumentError(t *testing.T) {
+	mockGH, reset := setupUnlinkedAuthorPR(t)
+	defer reset()
+	mockGH.PullRequestsMock.mockRepositoryCommitsPages = [][]*github.RepositoryCommit{
+		{{Author: &github.User{Login: github.String("john")}}},
+	}
+
+	forcedError := fmt.Errorf("forced cla document error")
+	mockDB, logger := setupMockDB(t, true)
+	mockDB.getCLADocumentForRepoError = forcedError
+
+	assert.EqualError(t, HandlePullRequest(logger, mockDB, webhook.PullRequestPayload{}, 0, "myCLAVersion"), forcedError.Error())
+}
+
+func TestSignUrlForRepo(t *testing.T) {
+	assert.Equal(t, "https://cla.example.com/?owner=myOwner&repo=myRepo",
+		signUrlForRepo("https://cla.example.com/", "myOwner", "myRepo", ""))
+	assert.Equal(t, "https://cla.example.com/?owner=myOwner&repo=my+Repo&x=y",
+		signUrlForRepo("https://cla.example.com/?x=y", "myOwner", "my Repo", ""))
+	assert.Equal(t, "https://cla.example.com/?claversion=2.0&owner=myOwner&repo=myRepo",
+		signUrlForRepo("https://cla.example.com/", "myOwner", "myRepo", "2.0"))
+}
+
+func TestSignUrlForCLAVersion(t *testing.T) {
+	resetPemFileImpl := SetupTestPemFile(t)
+	defer resetPemFileImpl()
+	resetGHJWTImpl := SetupMockGHJWTApp("https://cla.example.com/")
+	defer resetGHJWTImpl()
+
+	signUrl, err := SignUrlForCLAVersion(-1, "2.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://cla.example.com/?claversion=2.0", signUrl)
+}
+
+func TestCommitStatusReporterFail(t *testing.T) {
+	mockRepositories := setupMockRepositoriesService(t, true)
+	mockRepositories.expectedCtx = context.Background()
+	mockRepositories.expectedOwner = "myOwner"
+	mockRepositories.expectedRepo = "myRepo"
+	mockRepositories.expectedRef = "mySha"
+	mockRepositories.expectedCreateStatusRepoStatus = &github.RepoStatus{
+		State:       github.String("error"),
+		Description: github.String("The CLA could not be verified, comment `/cla recheck` to try again"),
+		Context:     github.String("myBot"),
+	}
+	reporter := &commitStatusReporter{
+		repositories: mockRepositories,
+		evalInfo:     &types.EvaluationInfo{RepoOwner: "myOwner", RepoName: "myRepo", Sha: "mySha"},
+		botName:      "myBot",
+	}
+
+	assert.NoError(t, reporter.fail(fmt.Errorf("forced evaluation error")))
+}
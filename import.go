@@ -0,0 +1,384 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sonatype-nexus-community/the-cla/types"
+)
+
+const importFormatJSON = "json"
+const defaultImportBatchSize = 500
+const maxImportBatchSize = 5000
+
+// byteOrderMark is written by some spreadsheets at the start of a CSV
+const byteOrderMark = "\ufeff"
+
+var importColumnsRequired = []string{"login", "claVersion", "signedAt"}
+
+var gitHubLogin = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+
+// importRecord is a signature to import, with the keys (or CSV columns) of an export
+type importRecord struct {
+	Login         string `json:"login"`
+	Email         string `json:"email"`
+	Name          string `json:"name"`
+	CLAVersion    string `json:"claVersion"`
+	SignedAt      string `json:"signedAt"`
+	CLATextUrl    string `json:"claTextUrl"`
+	CLATextSha256 string `json:"claTextSha256"`
+	IPAddress     string `json:"ipAddress"`
+	UserAgent     string `json:"userAgent"`
+	GitHubUserId  int64  `json:"gitHubUserId"`
+	RevokedAt     string `json:"revokedAt"`
+}
+
+// importRow is a signature read from an import, err tells why it can not be imported
+type importRow struct {
+	record    importRecord
+	signature types.UserSignature
+	err       error
+}
+
+// readImport reads the signatures of an import: a CSV with a header of export column names, or JSON objects with the
+// keys of an export, either in an array, or one after another like an ndjson export. Errors of a single signature are
+// set on its row, an error is only returned if the import can not be read at all.
+func readImport(format string, r io.Reader) (rows []importRow, err error) {
+	switch format {
+	case "", exportFormatCSV:
+		rows, err = readImportCSV(r)
+	case importFormatJSON, exportFormatNDJSON:
+		rows, err = readImportJSON(r)
+	default:
+		return nil, fmt.Errorf("invalid import format: %s", format)
+	}
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	for i := range rows {
+		if rows[i].err == nil {
+			rows[i].signature, rows[i].err = rows[i].record.toSignature(now)
+		}
+	}
+	return
+}
+
+func readImportCSV(r io.Reader) (rows []importRow, err error) {
+	reader := csv.NewReader(r)
+	// a row with the wrong number of columns is reported as an invalid row
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing CSV header")
+	}
+	if err != nil {
+		return
+	}
+	header[0] = strings.TrimPrefix(header[0], byteOrderMark)
+	if err = checkImportHeader(header); err != nil {
+		return
+	}
+
+	for {
+		var values []string
+		values, err = reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return
+		}
+		var row importRow
+		if len(values) != len(header) {
+			row.err = fmt.Errorf("expected %d columns, found %d", len(header), len(values))
+		}
+		for i := 0; i < len(values) && i < len(header); i++ {
+			if columnErr := row.record.set(header[i], unescapeCSVFormula(values[i])); columnErr != nil && row.err == nil {
+				row.err = columnErr
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func checkImportHeader(header []string) error {
+	found := map[string]bool{}
+	for _, name := range header {
+		if _, ok := findExportColumn(name); !ok {
+			return fmt.Errorf("invalid import column: %s", name)
+		}
+		if found[name] {
+			return fmt.Errorf("duplicate import column: %s", name)
+		}
+		found[name] = true
+	}
+	for _, name := range importColumnsRequired {
+		if !found[name] {
+			return fmt.Errorf("missing import column: %s", name)
+		}
+	}
+	return nil
+}
+
+// set sets the field of the export column to the value of a CSV
+func (r *importRecord) set(column, value string) (err error) {
+	switch column {
+	case "login":
+		r.Login = value
+	case "email":
+		r.Email = value
+	case "name":
+		r.Name = value
+	case "claVersion":
+		r.CLAVersion = value
+	case "signedAt":
+		r.SignedAt = value
+	case "claTextUrl":
+		r.CLATextUrl = value
+	case "claTextSha256":
+		r.CLATextSha256 = value
+	case "ipAddress":
+		r.IPAddress = value
+	case "userAgent":
+		r.UserAgent = value
+	case "gitHubUserId":
+		if value != "" {
+			if r.GitHubUserId, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return fmt.Errorf("invalid gitHubUserId: %s", value)
+			}
+		}
+	case exportColumnRevokedAt:
+		r.RevokedAt = value
+	}
+	// the ids of a signature and of its CLA document belong to the database the signature was exported from, and the
+	// CLA text is not stored with a signature, so the id, claDocumentId and claText columns are not imported. An
+	// email was not verified with GitHub by this server, so the emailVerified column is not imported either.
+	return nil
+}
+
+func readImportJSON(r io.Reader) (rows []importRow, err error) {
+	reader := bufio.NewReader(r)
+	if err = skipJSONSpace(reader); err != nil {
+		return
+	}
+	first, err := reader.Peek(1)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return
+	}
+
+	decoder := json.NewDecoder(reader)
+	if first[0] != '[' {
+		for {
+			var raw json.RawMessage
+			if err = decoder.Decode(&raw); err == io.EOF {
+				return rows, nil
+			}
+			if err != nil {
+				return
+			}
+			rows = append(rows, newJSONImportRow(raw))
+		}
+	}
+
+	if _, err = decoder.Token(); err != nil {
+		return
+	}
+	for decoder.More() {
+		var raw json.RawMessage
+		if err = decoder.Decode(&raw); err != nil {
+			return
+		}
+		rows = append(rows, newJSONImportRow(raw))
+	}
+	// the closing bracket of the array
+	_, err = decoder.Token()
+	return
+}
+
+func newJSONImportRow(raw json.RawMessage) (row importRow) {
+	if err := json.Unmarshal(raw, &row.record); err != nil {
+		row.err = fmt.Errorf("invalid signature: %s", err)
+	}
+	return
+}
+
+// skipJSONSpace skips the white space (and byte order mark) before the first JSON value, to tell if it is an array
+func skipJSONSpace(reader *bufio.Reader) error {
+	for {
+		r, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !strings.ContainsRune(" \t\r\n"+byteOrderMark, r) {
+			return reader.UnreadRune()
+		}
+	}
+}
+
+// toSignature validates the record, and converts it to the signature to store
+func (r *importRecord) toSignature(now time.Time) (signature types.UserSignature, err error) {
+	r.Login = strings.TrimSpace(r.Login)
+	r.CLAVersion = strings.TrimSpace(r.CLAVersion)
+	switch {
+	case r.Login == "":
+		return signature, fmt.Errorf(msgTemplateMissingField, "login")
+	case !gitHubLogin.MatchString(r.Login):
+		return signature, fmt.Errorf("invalid login: %s", r.Login)
+	case r.CLAVersion == "":
+		return signature, fmt.Errorf(msgTemplateMissingField, "claVersion")
+	case len(r.CLAVersion) > 10:
+		return signature, fmt.Errorf("invalid claVersion, longer than 10 characters: %s", r.CLAVersion)
+	case r.SignedAt == "":
+		return signature, fmt.Errorf(msgTemplateMissingField, "signedAt")
+	case strings.TrimSpace(r.RevokedAt) != "":
+		// an export includes revoked signatures, which must not become active again
+		return signature, fmt.Errorf("revoked signature, revoked at %s", r.RevokedAt)
+	}
+	signedAt, err := time.Parse(time.RFC3339, strings.TrimSpace(r.SignedAt))
+	if err != nil {
+		return signature, fmt.Errorf("invalid signedAt: %s", r.SignedAt)
+	}
+	if signedAt.After(now) {
+		return signature, fmt.Errorf("invalid signedAt, in the future: %s", r.SignedAt)
+	}
+	switch {
+	case len(r.Email) > 250:
+		return signature, fmt.Errorf("invalid email, longer than 250 characters")
+	case len(r.Name) > 250:
+		return signature, fmt.Errorf("invalid name, longer than 250 characters")
+	case len(r.CLATextUrl) > 250:
+		return signature, fmt.Errorf("invalid claTextUrl, longer than 250 characters")
+	case len(r.IPAddress) > 45:
+		return signature, fmt.Errorf("invalid ipAddress: %s", r.IPAddress)
+	}
+	if r.CLATextSha256 != "" {
+		if sum, hexErr := hex.DecodeString(r.CLATextSha256); hexErr != nil || len(sum) != 32 {
+			return signature, fmt.Errorf("invalid claTextSha256: %s", r.CLATextSha256)
+		}
+	}
+
+	return types.UserSignature{
+		User: types.User{
+			Login:     r.Login,
+			Email:     strings.TrimSpace(r.Email),
+			GivenName: strings.TrimSpace(r.Name),
+		},
+		CLAVersion:    r.CLAVersion,
+		TimeSigned:    signedAt,
+		CLATextUrl:    r.CLATextUrl,
+		CLATextSha256: strings.ToLower(r.CLATextSha256),
+		Evidence: &types.SigningEvidence{
+			IPAddress:    r.IPAddress,
+			UserAgent:    r.UserAgent,
+			GitHubUserId: r.GitHubUserId,
+		},
+	}, nil
+}
+
+// unescapeCSVFormula reverts escapeCSVFormula, so an export can be imported again
+func unescapeCSVFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// importSignatures stores the valid rows in batches of batchSize signatures, each batch in its own transaction. A
+// signature whose login is repeated with the same CLA version later in the import is invalid. If a batch fails, the
+// import stops, and the error is returned along with the report of the batches before it.
+func importSignatures(rows []importRow, batchSize int, dryRun bool) (report *types.ImportReport, err error) {
+	report = &types.ImportReport{DryRun: dryRun, Rows: len(rows), Problems: []types.ImportProblem{}}
+	addProblem := func(i int, status string, problem error) {
+		report.Problems = append(report.Problems, types.ImportProblem{
+			Row:        i + 1,
+			Login:      rows[i].record.Login,
+			CLAVersion: rows[i].record.CLAVersion,
+			Status:     status,
+			Error:      problem.Error(),
+		})
+	}
+
+	var valid []int
+	firstRows := map[string]int{}
+	for i := range rows {
+		if rows[i].err == nil {
+			key := strings.ToLower(rows[i].signature.User.Login) + "\n" + rows[i].signature.CLAVersion
+			if first, ok := firstRows[key]; ok {
+				rows[i].err = fmt.Errorf("duplicate of row %d", first+1)
+			} else {
+				firstRows[key] = i
+			}
+		}
+		if rows[i].err != nil {
+			report.Invalid++
+			addProblem(i, types.ImportStatusInvalid, rows[i].err)
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	for start := 0; start < len(valid); start += batchSize {
+		end := start + batchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		batch := make([]types.UserSignature, 0, end-start)
+		for _, i := range valid[start:end] {
+			batch = append(batch, rows[i].signature)
+		}
+
+		var conflicts []int
+		if conflicts, err = postgresDB.ImportSignatures(batch, dryRun); err != nil {
+			report.Error = err.Error()
+			break
+		}
+		for _, conflict := range conflicts {
+			i := valid[start+conflict]
+			report.Conflicts++
+			addProblem(i, types.ImportStatusConflict,
+				fmt.Errorf("%s already signed CLA version %s", rows[i].signature.User.Login, rows[i].signature.CLAVersion))
+		}
+		report.Imported += len(batch) - len(conflicts)
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		return report.Problems[i].Row < report.Problems[j].Row
+	})
+	return
+}
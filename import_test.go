@@ -0,0 +1,233 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sonatype-nexus-community/the-cla/db"
+	"github.com/sonatype-nexus-community/the-cla/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadImportInvalidFormat(t *testing.T) {
+	_, err := readImport("xml", strings.NewReader(""))
+	assert.EqualError(t, err, "invalid import format: xml")
+}
+
+func TestReadImportCSV(t *testing.T) {
+	rows, err := readImport(exportFormatCSV, strings.NewReader(byteOrderMark+
+		"id,login,name,claVersion,signedAt,emailVerified,gitHubUserId\n"+
+		"myId,john,'=John,2.0,2022-01-02T03:04:05Z,true,42\n"+
+		"myId2,jane,Jane,2.0,2022-01-02T03:04:05Z,maybe,myUserId\n"+
+		"myId3,joe\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(rows))
+
+	assert.NoError(t, rows[0].err)
+	assert.Equal(t, types.UserSignature{
+		User:       types.User{Login: "john", GivenName: "=John"},
+		CLAVersion: "2.0",
+		TimeSigned: time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC),
+		Evidence:   &types.SigningEvidence{GitHubUserId: 42},
+	}, rows[0].signature)
+	assert.EqualError(t, rows[1].err, "invalid gitHubUserId: myUserId")
+	assert.EqualError(t, rows[2].err, "expected 7 columns, found 2")
+	assert.Equal(t, "joe", rows[2].record.Login)
+}
+
+func TestReadImportCSVInvalidHeader(t *testing.T) {
+	_, err := readImport(exportFormatCSV, strings.NewReader(""))
+	assert.EqualError(t, err, "missing CSV header")
+
+	_, err = readImport(exportFormatCSV, strings.NewReader("login,claVersion,signedAt,password\n"))
+	assert.EqualError(t, err, "invalid import column: password")
+
+	_, err = readImport(exportFormatCSV, strings.NewReader("login,claVersion,signedAt,login\n"))
+	assert.EqualError(t, err, "duplicate import column: login")
+
+	_, err = readImport(exportFormatCSV, strings.NewReader("login,claVersion\n"))
+	assert.EqualError(t, err, "missing import column: signedAt")
+}
+
+func TestReadImportJSONArray(t *testing.T) {
+	rows, err := readImport(importFormatJSON, strings.NewReader(` [
+		{"login": "john", "claVersion": "2.0", "signedAt": "2022-01-02T03:04:05Z", "claDocumentId": 7},
+		{"login": "jane", "claVersion": "2.0", "signedAt": "2022-01-02T03:04:05Z", "gitHubUserId": "42"}
+	]`))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.NoError(t, rows[0].err)
+	assert.Equal(t, "john", rows[0].signature.User.Login)
+	assert.Equal(t, int64(0), rows[0].signature.CLADocumentId)
+	assert.ErrorContains(t, rows[1].err, "invalid signature")
+}
+
+func TestReadImportNDJSON(t *testing.T) {
+	rows, err := readImport(exportFormatNDJSON, strings.NewReader(
+		`{"login":"john","claVersion":"2.0","signedAt":"2022-01-02T03:04:05Z"}`+"\n"+
+			`{"login":"jane","claVersion":"2.0","signedAt":"2022-01-02T03:04:05Z"}`+"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "jane", rows[1].signature.User.Login)
+}
+
+func TestReadImportJSONEmpty(t *testing.T) {
+	rows, err := readImport(importFormatJSON, strings.NewReader(" \n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(rows))
+}
+
+func TestReadImportJSONSyntaxError(t *testing.T) {
+	_, err := readImport(importFormatJSON, strings.NewReader(`[{"login": "john"`))
+	assert.Error(t, err)
+}
+
+func TestImportRecordToSignature(t *testing.T) {
+	now := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	valid := importRecord{Login: "john", CLAVersion: "2.0", SignedAt: "2022-01-02T03:04:05Z"}
+	for _, test := range []struct {
+		change func(r *importRecord)
+		err    string
+	}{
+		{func(r *importRecord) { r.Login = " " }, "missing required field: login"},
+		{func(r *importRecord) { r.Login = "john@doe.tld" }, "invalid login: john@doe.tld"},
+		{func(r *importRecord) { r.CLAVersion = "" }, "missing required field: claVersion"},
+		{func(r *importRecord) { r.CLAVersion = "12345678901" }, "invalid claVersion, longer than 10 characters: 12345678901"},
+		{func(r *importRecord) { r.SignedAt = "" }, "missing required field: signedAt"},
+		{func(r *importRecord) { r.SignedAt = "yesterday" }, "invalid signedAt: yesterday"},
+		{func(r *importRecord) { r.SignedAt = "2022-01-02T03:04:06Z" }, "invalid signedAt, in the future: 2022-01-02T03:04:06Z"},
+		{func(r *importRecord) { r.Email = strings.Repeat("e", 251) }, "invalid email, longer than 250 characters"},
+		{func(r *importRecord) { r.CLATextSha256 = "mySha" }, "invalid claTextSha256: mySha"},
+		{func(r *importRecord) { r.RevokedAt = "2022-01-02T03:04:05Z" }, "revoked signature, revoked at 2022-01-02T03:04:05Z"},
+	} {
+		record := valid
+		test.change(&record)
+		_, err := record.toSignature(now)
+		assert.EqualError(t, err, test.err)
+	}
+
+	signature, err := valid.toSignature(now)
+	assert.NoError(t, err)
+	assert.Equal(t, now, signature.TimeSigned)
+}
+
+func TestUnescapeCSVFormula(t *testing.T) {
+	assert.Equal(t, "=1+1", unescapeCSVFormula(escapeCSVFormula("=1+1")))
+	assert.Equal(t, "'quoted'", unescapeCSVFormula("'quoted'"))
+	assert.Equal(t, "'", unescapeCSVFormula("'"))
+}
+
+func importTestRows(logins ...string) (rows []importRow) {
+	for _, login := range logins {
+		rows = append(rows, importRow{
+			record:    importRecord{Login: login, CLAVersion: "1.0"},
+			signature: types.UserSignature{User: types.User{Login: login}, CLAVersion: "1.0"},
+		})
+	}
+	return
+}
+
+func TestImportSignatures(t *testing.T) {
+	mock, closeDbFunc := setupMockDBCommand(t)
+	defer closeDbFunc()
+
+	rows := importTestRows("john", "jane", "JOHN", "joe", "bob")
+	rows[1].err = fmt.Errorf("forced invalid row")
+	mock.ExpectBegin()
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlImportSignature)).
+		WithArgs("john", "", "", time.Time{}, "1.0", "", nil, nil, false, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlImportSignature)).
+		WithArgs("joe", "", "", time.Time{}, "1.0", "", nil, nil, false, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlImportSignature)).
+		WithArgs("bob", "", "", time.Time{}, "1.0", "", nil, nil, false, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	report, err := importSignatures(rows, 2, false)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, &types.ImportReport{
+		Rows:      5,
+		Imported:  2,
+		Conflicts: 1,
+		Invalid:   2,
+		Problems: []types.ImportProblem{
+			{Row: 2, Login: "jane", CLAVersion: "1.0", Status: types.ImportStatusInvalid, Error: "forced invalid row"},
+			{Row: 3, Login: "JOHN", CLAVersion: "1.0", Status: types.ImportStatusInvalid, Error: "duplicate of row 1"},
+			{Row: 4, Login: "joe", CLAVersion: "1.0", Status: types.ImportStatusConflict, Error: "joe already signed CLA version 1.0"},
+		},
+	}, report)
+}
+
+func TestImportSignaturesBatchError(t *testing.T) {
+	mock, closeDbFunc := setupMockDBCommand(t)
+	defer closeDbFunc()
+
+	forcedError := fmt.Errorf("forced import error")
+	mock.ExpectBegin()
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlImportSignature)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlImportSignature)).
+		WillReturnError(forcedError)
+	mock.ExpectRollback()
+
+	report, err := importSignatures(importTestRows("john", "jane", "joe"), 1, true)
+	assert.ErrorContains(t, err, forcedError.Error())
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Imported)
+	assert.Equal(t, err.Error(), report.Error)
+}
+
+func TestImportSignaturesExportRoundTrip(t *testing.T) {
+	export, err := newSignatureExport(exportFormatCSV, "", false)
+	assert.NoError(t, err)
+	var exported bytes.Buffer
+	write, finish, err := export.start(&exported)
+	assert.NoError(t, err)
+	signature := exportTestSignature()
+	signature.CLATextSha256 = strings.Repeat("ab", 32)
+	assert.NoError(t, write(signature))
+	assert.NoError(t, finish())
+
+	rows, err := readImport(exportFormatCSV, &exported)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rows))
+	assert.NoError(t, rows[0].err)
+	assert.Equal(t, signature.User, rows[0].signature.User)
+	assert.Equal(t, signature.CLATextSha256, rows[0].signature.CLATextSha256)
+	assert.Equal(t, signature.Evidence, rows[0].signature.Evidence)
+	assert.Equal(t, int64(0), rows[0].signature.CLADocumentId)
+	// the email was verified by the server the signature was exported from, not by this one
+	assert.True(t, signature.EmailVerified)
+	assert.False(t, rows[0].signature.EmailVerified)
+}
@@ -24,9 +24,15 @@ resource "random_string" "info_user_password" {
   special = false
 }
 
+resource "random_string" "session_secret" {
+  length  = 64
+  special = false
+}
+
 locals {
   cla_db_username  = "the_cla_bot"
   cla_db_name = "the_cla"
   info_username = "info-user-${random_string.info_username_suffix.result}"
   info_password = "${random_string.info_user_password.result}"
+  session_secret = "${random_string.session_secret.result}"
 }
\ No newline at end of file
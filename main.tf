@@ -42,6 +42,7 @@ resource "kubernetes_secret" "the_cla" {
     "env_github_webhook_secret" = var.env_github_webhook_secret
     "env_react_app_gh_client_id" = var.env_react_app_gh_client_id
     "info_password" = local.info_password
+    "session_secret" = local.session_secret
     "psql_password" = module.database.user_password
     "smtp_username" = var.env_smtp_username
     "smtp_password" = var.env_smtp_password
@@ -168,6 +169,16 @@ resource "kubernetes_deployment" "the_cla" {
             }
           }
 
+          env {
+            name = "SESSION_SECRET"
+            value_from {
+              secret_key_ref {
+                name = "the-cla"
+                key  = "session_secret"
+              }
+            }
+          }
+
           env {
             name = "PG_HOST"
             value = module.shared.pgsql_cluster_endpoint_write
@@ -238,6 +249,11 @@ resource "kubernetes_deployment" "the_cla" {
             value = "require"
           }
 
+          env {
+            name = "TRUSTED_PROXIES"
+            value = var.env_trusted_proxies
+          }
+
           port {
             name           = "app"
             container_port = 4200
@@ -35,7 +35,8 @@ var githubImpl ourGithub.GHInterface = &ourGithub.GHCreator{}
 type OAuthInterface interface {
 	Exchange(ctx context.Context, code string, opts ...oauth2.AuthCodeOption) (*oauth2.Token, error)
 	Client(ctx context.Context, t *oauth2.Token) *http.Client
-	GetOAuthUser(logger *zap.Logger, code string) (user *github.User, err error)
+	GetOAuthUser(logger *zap.Logger, code string) (user *github.User, verifiedEmails []string, err error)
+	AuthCodeURL(state, redirectUrl string) string
 	// for testing only
 	getConf() *oauth2.Config
 }
@@ -51,11 +52,18 @@ func (oa *OAuthImpl) Exchange(ctx context.Context, code string, opts ...oauth2.A
 func (oa *OAuthImpl) Client(ctx context.Context, t *oauth2.Token) *http.Client {
 	return oa.oauthConf.Client(ctx, t)
 }
+
+// AuthCodeURL returns the GitHub url that asks the user to log in, and then redirects to redirectUrl with the state
+func (oa *OAuthImpl) AuthCodeURL(state, redirectUrl string) string {
+	return oa.oauthConf.AuthCodeURL(state, oauth2.SetAuthURLParam("redirect_uri", redirectUrl))
+}
 func (oa *OAuthImpl) getConf() *oauth2.Config {
 	return oa.oauthConf
 }
 
-func (oa *OAuthImpl) GetOAuthUser(logger *zap.Logger, code string) (user *github.User, err error) {
+// GetOAuthUser returns the user that logged in, and the emails GitHub verified belong to the user, the primary email
+// first. The email of the user is the primary verified email (if any), instead of the public email of the profile.
+func (oa *OAuthImpl) GetOAuthUser(logger *zap.Logger, code string) (user *github.User, verifiedEmails []string, err error) {
 	token, err := oa.Exchange(context.Background(), code)
 	if err != nil {
 		logger.Error("failed to get oauth user", zap.Error(err))
@@ -72,6 +80,33 @@ func (oa *OAuthImpl) GetOAuthUser(logger *zap.Logger, code string) (user *github
 		return
 	}
 
+	emails, _, err := client.Users.ListEmails(context.Background(), &github.ListOptions{PerPage: 100})
+	if err != nil {
+		logger.Error("failed to get oauth client user emails", zap.Error(err))
+		return nil, nil, err
+	}
+
+	verifiedEmails = getVerifiedEmails(emails)
+	if len(verifiedEmails) > 0 {
+		user.Email = &verifiedEmails[0]
+	} else {
+		user.Email = nil
+	}
+	return
+}
+
+// getVerifiedEmails returns the verified emails, the primary email first
+func getVerifiedEmails(emails []*github.UserEmail) (verifiedEmails []string) {
+	for _, email := range emails {
+		if !email.GetVerified() || email.GetEmail() == "" {
+			continue
+		}
+		if email.GetPrimary() {
+			verifiedEmails = append([]string{email.GetEmail()}, verifiedEmails...)
+		} else {
+			verifiedEmails = append(verifiedEmails, email.GetEmail())
+		}
+	}
 	return
 }
 
@@ -26,6 +26,7 @@ import (
 	"go.uber.org/zap/zaptest"
 	"golang.org/x/oauth2"
 	"net/http"
+	"net/url"
 	"os"
 	"testing"
 
@@ -61,6 +62,18 @@ func TestCreateOAuth(t *testing.T) {
 	assert.Equal(t, forcedGHClientSecret, oauth.getConf().ClientSecret)
 }
 
+func TestAuthCodeURL(t *testing.T) {
+	oauth := CreateOAuth("myClientId", "myClientSecret")
+
+	authUrl, err := url.Parse(oauth.AuthCodeURL("myState", "https://cla.example.com/"))
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com", authUrl.Host)
+	assert.Equal(t, "myClientId", authUrl.Query().Get("client_id"))
+	assert.Equal(t, "myState", authUrl.Query().Get("state"))
+	assert.Equal(t, "https://cla.example.com/", authUrl.Query().Get("redirect_uri"))
+	assert.Equal(t, "user:email", authUrl.Query().Get("scope"))
+}
+
 type OAuthMock struct {
 	t                *testing.T
 	assertParameters bool
@@ -86,16 +99,20 @@ func (o *OAuthMock) Client(ctx context.Context, t *oauth2.Token) *http.Client {
 	return &http.Client{}
 }
 
+func (o *OAuthMock) AuthCodeURL(state, redirectUrl string) string {
+	return ""
+}
+
 func (o *OAuthMock) getConf() *oauth2.Config {
 	return nil
 }
 
-func (o *OAuthMock) GetOAuthUser(logger *zap.Logger, code string) (user *github.User, err error) {
+func (o *OAuthMock) GetOAuthUser(logger *zap.Logger, code string) (user *github.User, verifiedEmails []string, err error) {
 	if o.assertParameters {
 		assert.Equal(o.t, o.getUserLogger, logger)
 		assert.Equal(o.t, o.getUserCode, code)
 	}
-	return o.getUserUser, o.getUserErr
+	return o.getUserUser, nil, o.getUserErr
 }
 
 func setupMockOAuth(t *testing.T, assertParameters bool) (mockOAuth OAuthMock, logger *zap.Logger) {
@@ -112,7 +129,7 @@ func TestGetOAuthUserMock(t *testing.T) {
 	oauth, logger := setupMockOAuth(t, true)
 	oauth.getUserLogger = logger
 
-	user, err := oauth.GetOAuthUser(logger, "")
+	user, _, err := oauth.GetOAuthUser(logger, "")
 	assert.Equal(t, (*github.User)(nil), user)
 	assert.Equal(t, nil, err)
 }
@@ -121,7 +138,18 @@ func TestGetOAuthUserFail(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	oauth := CreateOAuth("myClientId", "myClientSecret")
 
-	user, err := oauth.GetOAuthUser(logger, "myOAuthCode")
+	user, _, err := oauth.GetOAuthUser(logger, "myOAuthCode")
 	assert.Nil(t, user)
 	assert.True(t, err != nil)
 }
+
+func TestGetVerifiedEmails(t *testing.T) {
+	emails := []*github.UserEmail{
+		{Email: github.String("other@example.com"), Verified: github.Bool(true)},
+		{Email: github.String("unverified@example.com"), Verified: github.Bool(false)},
+		{Email: github.String("primary@example.com"), Verified: github.Bool(true), Primary: github.Bool(true)},
+		{Email: github.String("unverified-primary@example.com"), Primary: github.Bool(true)},
+	}
+	assert.Equal(t, []string{"primary@example.com", "other@example.com"}, getVerifiedEmails(emails))
+	assert.Nil(t, getVerifiedEmails(nil))
+}
@@ -20,16 +20,20 @@
 package main
 
 import (
+	"bytes"
 	"crypto/subtle"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/smtp"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -40,8 +44,10 @@ import (
 	"github.com/sonatype-nexus-community/the-cla/db"
 	ourGithub "github.com/sonatype-nexus-community/the-cla/github"
 	"github.com/sonatype-nexus-community/the-cla/oauth"
+	"github.com/sonatype-nexus-community/the-cla/session"
 	"github.com/sonatype-nexus-community/the-cla/types"
 
+	"github.com/google/go-github/v42/github"
 	"github.com/joho/godotenv"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -51,24 +57,38 @@ import (
 const defaultServicePort = ":4200"
 
 const pathClaText string = "/cla-text"
+const pathCLADocument = "/cla-document"
+const pathCLADocumentMapping = pathCLADocument + "/mapping"
+const pathOAuthLogin string = "/oauth-login"
 const pathOAuthCallback string = "/oauth-callback"
 const pathSignCla string = "/sign-cla"
 const pathWebhook string = "/webhook-integration"
 const pathInfo = "/info"
 const pathSignature = "/signature"
 const pathTestEmail = "/test-email"
+const pathRevokeSignature = pathSignature + "/revoke"
+const pathSignatureEvidence = pathSignature + "/evidence"
+const pathSignatures = "/signatures"
+const pathSignaturesExport = pathSignatures + "/export"
+const pathSignaturesImport = pathSignatures + "/import"
+const pathCLAVersionExpiry = "/cla-version/expiry"
+const pathCorporateSignature = "/corporate-signature"
+const pathCorporateMember = pathCorporateSignature + "/:" + pathParamCorporateId + "/member"
+const pathCorporateActive = pathCorporateSignature + "/:" + pathParamCorporateId + "/active"
+const pathParamCorporateId = "id"
 const buildLocation string = "build"
 
 const envReactAppClaVersion string = "REACT_APP_CLA_VERSION"
 const envGhWebhookSecret string = "GH_WEBHOOK_SECRET"
 const envReactAppGithubClientId string = "REACT_APP_GITHUB_CLIENT_ID"
 const envGithubClientSecret string = "GITHUB_CLIENT_SECRET"
+const envSessionSecret string = "SESSION_SECRET"
 
 const msgUnhandledGitHubEventType = "I do not handle this type of event, sorry!"
 
 var postgresDB db.IClaDB
 
-var claCache = make(map[string]string)
+var sessionManager *session.Manager
 
 const envPGHost = "PG_HOST"
 const envPGPort = "PG_PORT"
@@ -79,10 +99,30 @@ const envSSLMode = "SSL_MODE"
 const envInfoUsername = "INFO_USERNAME"
 const envInfoPassword = "INFO_PASSWORD"
 const envLogFilterIncludeHostname = "LOG_FILTER_INCLUDE_HOSTNAME"
+const envTrustedProxies = "TRUSTED_PROXIES"
 
 var errRecovered error
 var logger *zap.Logger
 
+// backgroundJobs are the jobs started by requests that take too long to make the caller wait for them
+var backgroundJobs sync.WaitGroup
+
+// runInBackground runs the job without holding up the request that started it. The recover middleware does not
+// cover the job, so a panic of the job is logged here.
+func runInBackground(job func()) {
+	jobLogger := logger
+	backgroundJobs.Add(1)
+	go func() {
+		defer backgroundJobs.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				jobLogger.Error("background job panic", zap.Any("panic", r))
+			}
+		}()
+		job()
+	}()
+}
+
 func main() {
 	e := echo.New()
 
@@ -119,7 +159,11 @@ func main() {
 	buildInfoMessage := fmt.Sprintf("BuildVersion: %s, BuildTime: %s, BuildCommit: %s",
 		buildversion.BuildVersion, buildversion.BuildTime, buildversion.BuildCommit)
 	logger.Info("build", zap.String("buildMsg", buildInfoMessage))
-	fmt.Println(buildInfoMessage)
+	// with arguments, run a command (see runCommand) instead of the server. A command may write to stdout.
+	commandArgs := os.Args[1:]
+	if len(commandArgs) == 0 {
+		fmt.Println(buildInfoMessage)
+	}
 
 	err = godotenv.Load(".env")
 	if err != nil {
@@ -153,6 +197,29 @@ func main() {
 		logger.Info("db migration complete")
 	}
 
+	if len(commandArgs) > 0 {
+		if err = runCommand(commandArgs, os.Stdout); err != nil {
+			logger.Error("command failed", zap.Strings("args", commandArgs), zap.Error(err))
+			// os.Exit skips the deferred calls
+			_ = pg.Close()
+			_ = logger.Sync()
+			os.Exit(1)
+		}
+		return
+	}
+
+	sessionManager, err = newSessionManager()
+	if err != nil {
+		logger.Error("session key", zap.Error(err))
+		panic(fmt.Errorf("failed to create session key. err: %+v", err))
+	}
+
+	e.IPExtractor, err = newIPExtractor(os.Getenv(envTrustedProxies))
+	if err != nil {
+		logger.Error("trusted proxies", zap.Error(err))
+		panic(fmt.Errorf("failed to parse %s. err: %+v", envTrustedProxies, err))
+	}
+
 	e.Use(middleware.CORS())
 
 	e.GET("/build-info", func(c echo.Context) error {
@@ -161,6 +228,10 @@ func main() {
 
 	e.GET(pathClaText, handleRetrieveCLAText)
 
+	e.GET(pathCLADocument, handleRetrieveCLADocument)
+
+	e.GET(pathOAuthLogin, handleGitHubOAuthLogin)
+
 	e.GET(pathOAuthCallback, handleProcessGitHubOAuth)
 
 	e.POST(pathWebhook, handleProcessWebhook)
@@ -169,7 +240,24 @@ func main() {
 
 	g := e.Group(pathInfo, middleware.BasicAuth(infoBasicValidator))
 	g.GET(pathSignature, handleSignature)
+	g.GET(pathSignatureEvidence, handleSignatureEvidence)
+	g.GET(pathSignatures, handleSignatures)
+	g.GET(pathSignaturesExport, handleExportSignatures)
+	g.PUT(pathSignaturesImport, handleImportSignatures)
 	g.GET(pathTestEmail, handleTestEmail)
+	g.PUT(pathRevokeSignature, handleRevokeSignature)
+	g.PUT(pathCLAVersionExpiry, handleCLAVersionExpiry)
+	g.PUT(pathCorporateSignature, handleCorporateSignCla)
+	g.GET(pathCorporateSignature, handleCorporateSignature)
+	g.PUT(pathCorporateMember, handleCorporateMember)
+	g.DELETE(pathCorporateMember, handleCorporateMember)
+	g.PUT(pathCorporateActive, handleCorporateSignatureActive)
+	g.PUT(pathCLADocument, handleCLADocument)
+	g.GET(pathCLADocument, handleCLADocuments)
+	g.PUT(pathCLADocumentMapping, handleCLADocumentMapping)
+	g.DELETE(pathCLADocumentMapping, handleCLADocumentMapping)
+	g.PUT(pathResignCampaign, handleStartResignCampaign)
+	g.GET(pathResignCampaign, handleResignCampaign)
 
 	e.Static("/", buildLocation)
 
@@ -213,7 +301,7 @@ func handleSignature(c echo.Context) (err error) {
 		return c.String(http.StatusUnprocessableEntity, err.Error())
 	}
 
-	hasUserSignedCLA, foundUserSignature, err := postgresDB.HasAuthorSignedTheCla(login, claVersion)
+	hasUserSignedCLA, foundUserSignature, err := postgresDB.HasAuthorSignedTheCla(login, 0, claVersion)
 	if err != nil {
 		logger.Error("error checking signature", zap.Error(err))
 		return c.String(http.StatusInternalServerError, err.Error())
@@ -226,10 +314,490 @@ func handleSignature(c echo.Context) (err error) {
 	// hide sensitive info
 	foundUserSignature.User.Email = hiddenFieldValue
 	foundUserSignature.User.GivenName = hiddenFieldValue
+	foundUserSignature.Evidence = nil
 	logger.Debug("found login signature", zap.Any("foundUserSignature", foundUserSignature))
 	return c.JSON(http.StatusOK, foundUserSignature)
 }
 
+// handleSignatureEvidence returns the complete signature, with the evidence of how it was made, e.g. when a signature
+// is disputed
+func handleSignatureEvidence(c echo.Context) (err error) {
+	login, err := getRequiredQueryParameter(c, queryParameterLogin)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	claVersion, err := getRequiredQueryParameter(c, queryParameterCLAVersion)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	hasUserSignedCLA, foundUserSignature, err := postgresDB.HasAuthorSignedTheCla(login, 0, claVersion)
+	if err != nil {
+		logger.Error("error checking signature", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if !hasUserSignedCLA {
+		return c.String(http.StatusNotFound, fmt.Sprintf("cla version %s not signed by %s", claVersion, login))
+	}
+	return c.JSON(http.StatusOK, foundUserSignature)
+}
+
+const queryParameterSignedAfter = "signedafter"
+const queryParameterSignedBefore = "signedbefore"
+const queryParameterLoginPrefix = "loginprefix"
+const queryParameterEmailDomain = "emaildomain"
+const queryParameterSort = "sort"
+const queryParameterLimit = "limit"
+const queryParameterCursor = "cursor"
+const defaultSignaturesLimit = 100
+const maxSignaturesLimit = 1000
+const msgTemplateInvalidQueryParam = "invalid query parameter %s: %s"
+
+// handleSignatures lists the active signatures, filtered by the query parameters. The sort parameter is one of
+// signedAt (the default), login or claVersion, prefixed with "-" to sort descending. Pass the nextCursor of the
+// response as cursor to read the next page.
+func handleSignatures(c echo.Context) (err error) {
+	query := &types.SignatureQuery{
+		SortBy: types.SignatureSortSignedAt,
+		Cursor: c.QueryParam(queryParameterCursor),
+		Limit:  defaultSignaturesLimit,
+	}
+	if query.SignatureFilter, err = getSignatureFilter(c); err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+	if sort := c.QueryParam(queryParameterSort); sort != "" {
+		query.Descending = strings.HasPrefix(sort, "-")
+		query.SortBy = strings.TrimPrefix(sort, "-")
+		switch query.SortBy {
+		case types.SignatureSortSignedAt, types.SignatureSortLogin, types.SignatureSortCLAVersion:
+		default:
+			return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterSort, sort))
+		}
+	}
+	if limit := c.QueryParam(queryParameterLimit); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 || query.Limit > maxSignaturesLimit {
+			return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterLimit, limit))
+		}
+	}
+
+	page, err := postgresDB.ListSignatures(query)
+	if errors.Is(err, db.ErrInvalidCursor) {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterCursor, query.Cursor))
+	}
+	if err != nil {
+		logger.Error("failed to list signatures", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if page.TotalCount, err = postgresDB.CountSignatures(&query.SignatureFilter); err != nil {
+		logger.Error("failed to count signatures", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, page)
+}
+
+const queryParameterFormat = "format"
+const queryParameterColumns = "columns"
+const queryParameterExcludeText = "excludetext"
+const queryParameterIncludeRevoked = "includerevoked"
+
+// handleExportSignatures streams the signatures, filtered like handleSignatures, as CSV or as newline delimited JSON.
+// Unlike the list of signatures, an export includes the revoked signatures, unless includerevoked is false.
+func handleExportSignatures(c echo.Context) (err error) {
+	filter, err := getSignatureFilter(c)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+	filter.IncludeRevoked = true
+	if value := c.QueryParam(queryParameterIncludeRevoked); value != "" {
+		if filter.IncludeRevoked, err = strconv.ParseBool(value); err != nil {
+			return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterIncludeRevoked, value))
+		}
+	}
+	excludeText, _ := strconv.ParseBool(c.QueryParam(queryParameterExcludeText))
+	export, err := newSignatureExport(c.QueryParam(queryParameterFormat), c.QueryParam(queryParameterColumns), excludeText)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, export.contentType())
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=signatures.%s", export.format))
+	c.Response().WriteHeader(http.StatusOK)
+	if err = exportSignatures(export, &filter, c.Response()); err != nil {
+		// the status is sent already, so the export ends without the remaining signatures
+		logger.Error("failed to export signatures", zap.Error(err))
+	}
+	return nil
+}
+
+func exportSignatures(export *signatureExport, filter *types.SignatureFilter, w io.Writer) (err error) {
+	write, finish, err := export.start(w)
+	if err != nil {
+		return
+	}
+	if err = postgresDB.ExportSignatures(filter, export.includesText(), write); err != nil {
+		return
+	}
+	return finish()
+}
+
+const queryParameterDryRun = "dryrun"
+const queryParameterBatchSize = "batchsize"
+
+// maxImportBodySize limits the body of an import, which is read into memory, to 64 MiB
+const maxImportBodySize = 64 << 20
+
+const msgTemplateImportTooLarge = "import larger than %d bytes"
+
+// handleImportSignatures imports the signatures in the body of the request, a CSV or JSON in the format of an export.
+// Invalid signatures, and signatures of a login that already signed the CLA version, are reported and not imported.
+func handleImportSignatures(c echo.Context) (err error) {
+	dryRun := false
+	if value := c.QueryParam(queryParameterDryRun); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterDryRun, value))
+		}
+	}
+	batchSize := defaultImportBatchSize
+	if value := c.QueryParam(queryParameterBatchSize); value != "" {
+		if batchSize, err = strconv.Atoi(value); err != nil || batchSize < 1 || batchSize > maxImportBatchSize {
+			return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterBatchSize, value))
+		}
+	}
+
+	body := http.MaxBytesReader(c.Response(), c.Request().Body, maxImportBodySize)
+	rows, err := readImport(c.QueryParam(queryParameterFormat), body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return c.String(http.StatusRequestEntityTooLarge, fmt.Sprintf(msgTemplateImportTooLarge, tooLarge.Limit))
+	}
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	report, err := importSignatures(rows, batchSize, dryRun)
+	if err != nil {
+		logger.Error("failed to import signatures", zap.Error(err))
+		return c.JSON(http.StatusBadRequest, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
+
+// getSignatureFilter reads the filter of signatures from the query parameters, which are all optional
+func getSignatureFilter(c echo.Context) (filter types.SignatureFilter, err error) {
+	filter = types.SignatureFilter{
+		CLAVersion:  c.QueryParam(queryParameterCLAVersion),
+		LoginPrefix: c.QueryParam(queryParameterLoginPrefix),
+		EmailDomain: c.QueryParam(queryParameterEmailDomain),
+	}
+	if filter.SignedAfter, err = getOptionalTimeQueryParameter(c, queryParameterSignedAfter); err != nil {
+		return
+	}
+	filter.SignedBefore, err = getOptionalTimeQueryParameter(c, queryParameterSignedBefore)
+	return
+}
+
+// getOptionalTimeQueryParameter parses an RFC 3339 time, like "2022-01-01T00:00:00Z"
+func getOptionalTimeQueryParameter(c echo.Context, parameterName string) (parameterValue *time.Time, err error) {
+	parameterValue, err = parseOptionalTime(c.QueryParam(parameterName))
+	if err != nil {
+		return nil, fmt.Errorf(msgTemplateInvalidQueryParam, parameterName, c.QueryParam(parameterName))
+	}
+	return
+}
+
+func parseOptionalTime(value string) (parsed *time.Time, err error) {
+	if value == "" {
+		return
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return
+	}
+	return &t, nil
+}
+
+func handleRevokeSignature(c echo.Context) (err error) {
+	revocation := new(types.SignatureRevocation)
+	if err := c.Bind(revocation); err != nil {
+		return err
+	}
+
+	switch {
+	case revocation.Login == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "login"))
+	case revocation.CLAVersion == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "claVersion"))
+	case revocation.Reason == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "reason"))
+	}
+	if revocation.RevokedBy == "" {
+		// default to the (info endpoint) user that made the call
+		revocation.RevokedBy, _, _ = c.Request().BasicAuth()
+	}
+	revocation.RevokedAt = time.Now()
+
+	if err = postgresDB.RevokeSignature(revocation); err != nil {
+		logger.Error("failed to revoke signature", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	// open PRs of the author may have passed using the revoked signature, so flip them back. That takes GitHub calls
+	// for every installation, so it does not hold up the response.
+	jobLogger, jobDB, login, claVersion := logger, postgresDB, revocation.Login, getCurrentCLAVersion()
+	runInBackground(func() {
+		appId, err := ourGithub.GetAppId()
+		if err == nil {
+			err = ourGithub.ReviewOpenPRsForAuthor(jobLogger, jobDB, appId, login, claVersion)
+		}
+		if err != nil {
+			jobLogger.Error("error reviewing open PRs of revoked author", zap.String("login", login), zap.Error(err))
+		}
+	})
+
+	return c.JSON(http.StatusOK, revocation)
+}
+
+func handleCLAVersionExpiry(c echo.Context) (err error) {
+	expiry := new(types.CLAVersionExpiry)
+	if err := c.Bind(expiry); err != nil {
+		return err
+	}
+	if expiry.CLAVersion == "" {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "claVersion"))
+	}
+
+	if err = postgresDB.SetCLAVersionExpiry(expiry); err != nil {
+		logger.Error("failed to set cla version expiry", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, expiry)
+}
+
+const queryParameterCompany = "company"
+const queryParameterActive = "active"
+const msgTemplateMissingField = "missing required field: %s"
+
+func handleCorporateSignCla(c echo.Context) (err error) {
+	logger.Debug("Attempting to sign the corporate CLA")
+	corp := new(types.CorporateSignature)
+
+	if err := c.Bind(corp); err != nil {
+		return err
+	}
+
+	switch {
+	case corp.Company == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "company"))
+	case corp.Signatory.Login == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "signatory.login"))
+	case corp.CLAVersion == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "claVersion"))
+	}
+	for _, member := range corp.Members {
+		// an empty member (or a lone "@" domain) would cover every author without an email
+		if strings.TrimPrefix(strings.TrimSpace(member.Value), "@") == "" {
+			return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "members.value"))
+		}
+	}
+
+	doc, err := findPublishedCLADocument(corp.CLAVersion, corp.CLATextUrl)
+	if err != nil {
+		logger.Error("failed to find cla document", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if doc == nil {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateCLADocumentUnknown, corp.CLAVersion, corp.CLATextUrl))
+	}
+	corp.CLATextUrl = doc.CLATextUrl
+
+	corp.TimeSigned = time.Now()
+	corp.Active = true
+	stored, err := getStoredCLADocument(doc)
+	if err != nil {
+		logger.Error("failed to get cla text", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	corp.CLAText = stored.CLAText
+	corp.CLADocumentId = stored.Id
+	corp.CLATextSha256 = stored.CLATextSha256
+
+	err = postgresDB.InsertCorporateSignature(corp)
+	if err != nil {
+		logger.Error("failed to process corporate sign cla", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Debug("corporate CLA signed successfully", zap.String("company", corp.Company))
+
+	// re-evaluate any PRs of allow-listed logins that were waiting on a signature. That takes GitHub calls for every
+	// member, so it does not hold up the response.
+	jobLogger, jobDB, members, claVersion := logger, postgresDB, corp.Members, corp.CLAVersion
+	runInBackground(func() {
+		for _, member := range members {
+			if member.Type != types.CorporateMemberTypeLogin {
+				continue
+			}
+			memberSignature := &types.UserSignature{User: types.User{Login: member.Value}, CLAVersion: claVersion}
+			if err := ourGithub.ReviewPriorPRs(jobLogger, jobDB, memberSignature); err != nil {
+				jobLogger.Error("error reviewing prior PRs", zap.String("login", member.Value), zap.Error(err))
+			}
+		}
+	})
+
+	return c.JSON(http.StatusCreated, corp)
+}
+
+func handleCorporateSignature(c echo.Context) (err error) {
+	company, err := getRequiredQueryParameter(c, queryParameterCompany)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	claVersion, err := getRequiredQueryParameter(c, queryParameterCLAVersion)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	corp, err := postgresDB.GetCorporateSignature(company, claVersion)
+	if err != nil {
+		logger.Error("error checking corporate signature", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if corp == nil {
+		logger.Debug("corporate cla not signed", zap.String("company", company))
+		return c.String(http.StatusOK, fmt.Sprintf("cla version %s not signed by company %s", claVersion, company))
+	}
+
+	return c.JSON(http.StatusOK, corp)
+}
+
+// handleCorporateMember adds (PUT) or removes (DELETE) a member on the allow-list of a corporate signature
+func handleCorporateMember(c echo.Context) (err error) {
+	member := new(types.CorporateMember)
+	if err := c.Bind(member); err != nil {
+		return err
+	}
+	if member.Value == "" {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "value"))
+	}
+
+	corporateSignatureId := c.Param(pathParamCorporateId)
+	if c.Request().Method == http.MethodDelete {
+		err = postgresDB.RemoveCorporateMember(corporateSignatureId, member)
+	} else {
+		err = postgresDB.AddCorporateMember(corporateSignatureId, member)
+	}
+	if err != nil {
+		logger.Error("failed to update corporate member", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, member)
+}
+
+func handleCorporateSignatureActive(c echo.Context) (err error) {
+	activeParam, err := getRequiredQueryParameter(c, queryParameterActive)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+	active, err := strconv.ParseBool(activeParam)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	corporateSignatureId := c.Param(pathParamCorporateId)
+	if err = postgresDB.SetCorporateSignatureActive(corporateSignatureId, active); err != nil {
+		logger.Error("failed to update corporate signature", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.String(http.StatusOK, fmt.Sprintf("corporate signature %s active: %t", corporateSignatureId, active))
+}
+
+const msgTemplateCLAVersionPublished = "CLA version %s is published by %s already, each CLA document needs its own versions"
+
+// handleCLADocument adds (or updates) a version of a CLA document in the catalog
+func handleCLADocument(c echo.Context) (err error) {
+	doc := new(types.CLADocument)
+	if err := c.Bind(doc); err != nil {
+		return err
+	}
+
+	switch {
+	case doc.Id == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "id"))
+	case doc.CLAVersion == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "claVersion"))
+	case doc.CLATextUrl == "" && doc.CLAText == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "claTextUrl or claText"))
+	}
+	if doc.EffectiveAt.IsZero() {
+		doc.EffectiveAt = time.Now()
+	}
+
+	// signatures are matched on the CLA version alone, so signing a version must not satisfy another document
+	if doc.CLAVersion == getCurrentCLAVersion() {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateCLAVersionPublished, doc.CLAVersion, "the default CLA document"))
+	}
+	published, err := postgresDB.GetCLADocumentsByVersion(doc.CLAVersion)
+	if err != nil {
+		logger.Error("failed to find cla documents", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	for _, other := range published {
+		if other.Id != doc.Id {
+			return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateCLAVersionPublished, doc.CLAVersion, fmt.Sprintf("CLA document %q", other.Id)))
+		}
+	}
+
+	if err = postgresDB.UpsertCLADocument(doc); err != nil {
+		logger.Error("failed to store cla document", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, doc)
+}
+
+func handleCLADocuments(c echo.Context) (err error) {
+	docs, err := postgresDB.GetCLADocuments()
+	if err != nil {
+		logger.Error("error reading cla documents", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, docs)
+}
+
+// handleCLADocumentMapping maps (PUT) or un-maps (DELETE) an owner or repo to a CLA document
+func handleCLADocumentMapping(c echo.Context) (err error) {
+	mapping := new(types.CLADocumentMapping)
+	if err := c.Bind(mapping); err != nil {
+		return err
+	}
+
+	isDelete := c.Request().Method == http.MethodDelete
+	switch {
+	case mapping.RepoOwner == "":
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "repoOwner"))
+	case mapping.DocumentId == "" && !isDelete:
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "documentId"))
+	}
+
+	if isDelete {
+		err = postgresDB.RemoveCLADocumentMapping(mapping)
+	} else {
+		err = postgresDB.SetCLADocumentMapping(mapping)
+	}
+	if err != nil {
+		logger.Error("failed to update cla document mapping", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, mapping)
+}
+
 func getRequiredQueryParameter(c echo.Context, parameterName string) (parameterValue string, err error) {
 	parameterValue = c.QueryParam(parameterName)
 	if parameterValue == "" {
@@ -333,7 +901,14 @@ func handleProcessWebhook(c echo.Context) (err error) {
 
 	hook, _ := webhook.New(webhook.Options.Secret(ghSecret))
 
-	payload, err := hook.Parse(c.Request(), webhook.PullRequestEvent)
+	// keep the raw body, because some payloads lack fields we need and must be decoded again
+	rawPayload, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(rawPayload))
+
+	payload, err := hook.Parse(c.Request(), webhook.PullRequestEvent, webhook.IssueCommentEvent, webhook.CheckRunEvent)
 
 	if err != nil {
 		if err == webhook.ErrEventNotFound {
@@ -361,6 +936,14 @@ func handleProcessWebhook(c echo.Context) (err error) {
 			}
 
 			return c.String(http.StatusAccepted, "accepted pull request for processing")
+		case "closed":
+			err := ourGithub.HandlePullRequestClosed(logger, postgresDB, payload)
+			if err != nil {
+				logger.Error("failed to handle closed pull request", zap.Error(err))
+				return c.String(http.StatusBadRequest, err.Error())
+			}
+
+			return c.String(http.StatusAccepted, "accepted closed pull request")
 		default:
 			logger.Debug("ignore pull request payload",
 				zap.String("action", payload.Action),
@@ -370,6 +953,33 @@ func handleProcessWebhook(c echo.Context) (err error) {
 			)
 			return c.String(http.StatusAccepted, fmt.Sprintf("No action taken for: %s", payload.Action))
 		}
+	case webhook.IssueCommentPayload:
+		handled, err := ourGithub.HandleIssueComment(logger, postgresDB, payload, appId, getCurrentCLAVersion())
+		if err != nil {
+			logger.Error("failed to handle issue comment", zap.Error(err))
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if !handled {
+			return c.String(http.StatusAccepted, fmt.Sprintf("No action taken for comment: %s", payload.Action))
+		}
+
+		return c.String(http.StatusAccepted, "accepted pull request for recheck")
+	case webhook.CheckRunPayload:
+		// the webhook library omits the requested action and the installation
+		var checkRunEvent github.CheckRunEvent
+		if err = json.Unmarshal(rawPayload, &checkRunEvent); err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		handled, err := ourGithub.HandleCheckRun(logger, postgresDB, &checkRunEvent, appId, getCurrentCLAVersion())
+		if err != nil {
+			logger.Error("failed to handle check run", zap.Error(err))
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if !handled {
+			return c.String(http.StatusAccepted, fmt.Sprintf("No action taken for check run: %s", payload.Action))
+		}
+
+		return c.String(http.StatusAccepted, "accepted pull request for re-run")
 	default:
 		// theoretically can't get here due to hook.Parse() call above (events param), but better safe than sorry
 		logger.Debug("Unhandled payload type encountered", zap.Any("payload", payload))
@@ -390,12 +1000,46 @@ func handleProcessSignCla(c echo.Context) (err error) {
 		return err
 	}
 
-	user.TimeSigned = time.Now()
-	user.CLAText, err = getClaText(user.CLATextUrl)
+	authenticated, err := getSession(c)
+	if err != nil {
+		logger.Info("sign cla without a session", zap.Error(err))
+		return c.String(http.StatusUnauthorized, msgNotAuthenticated)
+	}
+	// sign as the GitHub user that logged in, never as the login claimed in the request
+	user.User.Login = authenticated.Login
+	user.EmailVerified = authenticated.IsVerifiedEmail(user.User.Email)
+	user.Evidence = &types.SigningEvidence{
+		IPAddress:    normalizeIP(c.RealIP()),
+		UserAgent:    c.Request().UserAgent(),
+		GitHubUserId: authenticated.UserId,
+	}
+
+	doc, err := resolveRequestCLADocument(c)
+	if err != nil {
+		logger.Error("failed to resolve cla document", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if doc == nil {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateCLAVersionUnknown, c.QueryParam(ourGithub.QueryParameterCLAVersion)))
+	}
+	// only the cla document published for the repo may be signed, never text from a url chosen by the caller
+	if !matchesCLADocument(doc, user.CLAVersion, user.CLATextUrl) {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateCLADocumentMismatch,
+			user.CLAVersion, user.CLATextUrl, doc.CLAVersion, doc.CLATextUrl))
+	}
+	user.CLAVersion = doc.CLAVersion
+	user.CLATextUrl = doc.CLATextUrl
 
+	// a signature must reference the exact text that was agreed to
+	stored, err := getStoredCLADocument(doc)
 	if err != nil {
-		logger.Error("Failed to get CLA Text - not blocking signature registration", zap.Error(err))
+		logger.Error("failed to get cla text", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	user.TimeSigned = time.Now()
+	user.CLAText = stored.CLAText
+	user.CLADocumentId = stored.Id
+	user.CLATextSha256 = stored.CLATextSha256
 
 	err = postgresDB.InsertSignature(user)
 	if err != nil {
@@ -417,59 +1061,285 @@ func handleProcessSignCla(c echo.Context) (err error) {
 		logger.Error("Failed to send CLA signature notification", zap.Error(err))
 	}
 
+	// evidence is only exposed by the /info endpoints
+	user.Evidence = nil
 	return c.JSON(http.StatusCreated, user)
 }
 
+// createOAuth is replaced by tests
+var createOAuth = oauth.CreateOAuth
+
+const cookieNameOAuthState = "cla_oauth_state"
+const oauthStateTTL = 10 * time.Minute
+const msgInvalidOAuthState = "invalid oauth state, please log in with GitHub again"
+const msgOAuthLoginFailed = "failed to log in with GitHub, please try again"
+
+// handleGitHubOAuthLogin starts the OAuth login flow with a random state, held by a cookie until GitHub redirects
+// the user back with the state
+func handleGitHubOAuthLogin(c echo.Context) (err error) {
+	state, token, err := sessionManager.CreateState(time.Now(), oauthStateTTL)
+	if err != nil {
+		logger.Error("failed to create oauth state", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	c.SetCookie(newCookie(c, cookieNameOAuthState, token, oauthStateTTL))
+
+	oauthImpl := createOAuth(os.Getenv(envReactAppGithubClientId), os.Getenv(envGithubClientSecret))
+	redirectUrl := fmt.Sprintf("%s://%s/", c.Scheme(), c.Request().Host)
+	return c.Redirect(http.StatusFound, oauthImpl.AuthCodeURL(state, redirectUrl))
+}
+
 func handleProcessGitHubOAuth(c echo.Context) (err error) {
 	logger.Debug("Attempting to fetch GitHub crud")
 
 	code := c.QueryParam("code")
+	if code == "" {
+		return c.String(http.StatusBadRequest, fmt.Sprintf(msgTemplateMissingField, "code"))
+	}
 
 	state := c.QueryParam("state")
 	if state == "" {
-		return
+		return c.String(http.StatusBadRequest, fmt.Sprintf(msgTemplateMissingField, "state"))
+	}
+
+	stateCookie, err := c.Cookie(cookieNameOAuthState)
+	if err != nil {
+		logger.Info("oauth callback without a state cookie", zap.Error(err))
+		return c.String(http.StatusBadRequest, msgInvalidOAuthState)
+	}
+	// a state is only good for one login
+	c.SetCookie(newCookie(c, cookieNameOAuthState, "", -time.Second))
+	if err = sessionManager.VerifyState(stateCookie.Value, state, time.Now()); err != nil {
+		logger.Info("oauth callback with an invalid state", zap.Error(err))
+		return c.String(http.StatusBadRequest, msgInvalidOAuthState)
 	}
 
-	oauthImpl := oauth.CreateOAuth(os.Getenv(envReactAppGithubClientId), os.Getenv(envGithubClientSecret))
+	oauthImpl := createOAuth(os.Getenv(envReactAppGithubClientId), os.Getenv(envGithubClientSecret))
 
-	user, err := oauthImpl.GetOAuthUser(logger, code)
+	user, verifiedEmails, err := oauthImpl.GetOAuthUser(logger, code)
 	if err != nil {
 		logger.Error("failed to get oauth user", zap.Error(err))
-		return
+		return c.String(http.StatusUnauthorized, msgOAuthLoginFailed)
+	}
+
+	_, token, err := sessionManager.Create(user.GetLogin(), user.GetID(), verifiedEmails, time.Now())
+	if err != nil {
+		logger.Error("failed to create session", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
 	}
+	c.SetCookie(newCookie(c, cookieNameSession, token, sessionTTL))
+
+	return c.JSON(http.StatusOK, oauthLogin{User: user, VerifiedEmails: verifiedEmails})
+}
 
-	return c.JSON(http.StatusOK, user)
+// oauthLogin is the GitHub user that logged in, with the verified emails the user can choose from to sign the CLA
+type oauthLogin struct {
+	*github.User
+	VerifiedEmails []string `json:"verifiedEmails"`
+}
+
+const cookieNameSession = "cla_session"
+const sessionTTL = time.Hour
+const msgNotAuthenticated = "not authenticated, please log in with GitHub"
+
+func newSessionManager() (*session.Manager, error) {
+	key := []byte(os.Getenv(envSessionSecret))
+	if len(key) == 0 {
+		logger.Warn("missing " + envSessionSecret + " environment variable, sessions will not survive a restart of the server")
+		var err error
+		if key, err = session.NewRandomKey(); err != nil {
+			return nil, err
+		}
+	}
+	return session.NewManager(key, sessionTTL), nil
+}
+
+// newIPExtractor returns how to find the IP address of a client. The X-Forwarded-For header is only trusted when the
+// request comes from one of the trusted proxies, given as a comma separated list of IP ranges (CIDR). Without trusted
+// proxies, the IP address is the one the request comes from.
+func newIPExtractor(trustedProxies string) (echo.IPExtractor, error) {
+	if strings.TrimSpace(trustedProxies) == "" {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		_, ipRange, err := net.ParseCIDR(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// normalizeIP returns the IP address in its canonical form, or an empty string if it is not an IP address
+func normalizeIP(ip string) string {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return ""
+	}
+	return parsed.String()
+}
+
+// newCookie returns a cookie that scripts can not read, and that is only sent over https if the request used https
+func newCookie(c echo.Context, name, value string, maxAge time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Scheme() == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// getSession returns the session of the GitHub user that logged in via OAuth
+func getSession(c echo.Context) (*session.Session, error) {
+	cookie, err := c.Cookie(cookieNameSession)
+	if err != nil {
+		return nil, err
+	}
+	return sessionManager.Read(cookie.Value, time.Now())
 }
 
 const envClsUrl = "CLA_URL"
 const msgMissingClaUrl = "missing " + envClsUrl + " environment variable"
 
+const msgTemplateCLADocumentMismatch = "cla version %s (text url: %s) is not the cla published for this repository, which is version %s (text url: %s)"
+const msgTemplateCLADocumentUnknown = "cla version %s (text url: %s) is not a published cla"
+const msgTemplateCLAVersionUnknown = "cla version %s is not published"
+
+// matchesCLADocument checks the CLA version and text url given by a signer against a published document. A value
+// that is not given matches the document.
+func matchesCLADocument(doc *types.CLADocument, claVersion, claTextUrl string) bool {
+	return (claVersion == "" || claVersion == doc.CLAVersion) &&
+		(claTextUrl == "" || claTextUrl == doc.CLATextUrl)
+}
+
+// findPublishedCLADocument returns the published document (the default document, or a document of the catalog)
+// matching the given CLA version and text url, or nil if no such document is published.
+func findPublishedCLADocument(claVersion, claTextUrl string) (doc *types.CLADocument, err error) {
+	defaultDoc := defaultCLADocument()
+	if matchesCLADocument(defaultDoc, claVersion, claTextUrl) {
+		return defaultDoc, nil
+	}
+
+	docs, err := postgresDB.GetCLADocumentsByVersion(claVersion)
+	if err != nil {
+		return
+	}
+	for i := range docs {
+		if matchesCLADocument(&docs[i], claVersion, claTextUrl) {
+			return &docs[i], nil
+		}
+	}
+	return
+}
+
+// resolveCLADocument returns the CLA document in effect for the repo. If neither the repo nor its owner is mapped to
+// a document of the catalog, the default document configured by environment variables is returned. A CLA version (the
+// claVersion of the .github/cla.yml of the repo) takes precedence, and selects the published document of that
+// version, or nil if no document of that version is published.
+func resolveCLADocument(owner, repo, claVersion string) (doc *types.CLADocument, err error) {
+	if claVersion != "" {
+		return findPublishedCLADocument(claVersion, "")
+	}
+	if owner != "" {
+		if doc, err = postgresDB.GetCLADocumentForRepo(owner, repo, time.Now()); err != nil || doc != nil {
+			return
+		}
+	}
+	return defaultCLADocument(), nil
+}
+
+// defaultCLADocument returns the CLA document configured by environment variables
+func defaultCLADocument() *types.CLADocument {
+	return &types.CLADocument{
+		CLAVersion: getCurrentCLAVersion(),
+		CLATextUrl: os.Getenv(envClsUrl),
+	}
+}
+
+// resolveRequestCLADocument resolves the CLA document selected by the query parameters of the signing page
+func resolveRequestCLADocument(c echo.Context) (*types.CLADocument, error) {
+	return resolveCLADocument(c.QueryParam(ourGithub.QueryParameterOwner), c.QueryParam(ourGithub.QueryParameterRepo),
+		c.QueryParam(ourGithub.QueryParameterCLAVersion))
+}
+
+// getStoredCLADocument returns the stored text of the CLA document. Text that is not stored yet is fetched from the
+// url of the document and stored, so the text of a CLA version is fetched only once.
+func getStoredCLADocument(doc *types.CLADocument) (stored *types.StoredCLADocument, err error) {
+	if doc.StoredDocumentId != 0 {
+		stored = &types.StoredCLADocument{
+			Id:            doc.StoredDocumentId,
+			CLAVersion:    doc.CLAVersion,
+			CLATextSha256: doc.CLATextSha256,
+			CLATextUrl:    doc.CLATextUrl,
+			CLAText:       doc.CLAText,
+		}
+		return
+	}
+	if doc.CLATextUrl == "" {
+		return nil, errors.New(msgMissingClaUrl)
+	}
+
+	if stored, err = postgresDB.GetStoredCLADocumentByUrl(doc.CLAVersion, doc.CLATextUrl); err != nil || stored != nil {
+		return
+	}
+
+	logger.Debug("CLA text not stored, moving forward to fetch", zap.String("claTextUrl", doc.CLATextUrl))
+	claText, err := fetchClaText(doc.CLATextUrl)
+	if err != nil {
+		return
+	}
+	stored = &types.StoredCLADocument{CLAVersion: doc.CLAVersion, CLATextUrl: doc.CLATextUrl, CLAText: claText}
+	err = postgresDB.StoreCLADocument(stored)
+	return
+}
+
+// handleRetrieveCLADocument describes (without the text) the CLA document to be signed for the repo
+func handleRetrieveCLADocument(c echo.Context) (err error) {
+	doc, err := resolveRequestCLADocument(c)
+	if err != nil {
+		logger.Error("failed to resolve cla document", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if doc == nil {
+		return c.String(http.StatusNotFound, fmt.Sprintf(msgTemplateCLAVersionUnknown, c.QueryParam(ourGithub.QueryParameterCLAVersion)))
+	}
+	doc.CLAText = ""
+
+	return c.JSON(http.StatusOK, doc)
+}
+
 func handleRetrieveCLAText(c echo.Context) (err error) {
 	logger.Debug("Attempting to fetch CLA text")
-	claURL := os.Getenv(envClsUrl)
-	claText, err := getClaText(claURL)
+	doc, err := resolveRequestCLADocument(c)
+	if err != nil {
+		logger.Error("failed to resolve cla document", zap.Error(err))
+		return err
+	}
+	if doc == nil {
+		return c.String(http.StatusNotFound, fmt.Sprintf(msgTemplateCLAVersionUnknown, c.QueryParam(ourGithub.QueryParameterCLAVersion)))
+	}
+	stored, err := getStoredCLADocument(doc)
 
 	if err != nil {
 		logger.Error("Failed to get CLA Text", zap.Error(err))
 		return err
 	}
 
-	return c.String(http.StatusOK, claText)
+	return c.String(http.StatusOK, stored.CLAText)
 }
 
-func getClaText(claTextUrl string) (claText string, err error) {
+func fetchClaText(claTextUrl string) (claText string, err error) {
 	logger.Debug("Attempting to fetch CLA text")
 
-	if claCache[claTextUrl] != "" {
-		logger.Debug("CLA text was already cached, returning from cache", zap.String("claTextUrl", claTextUrl))
-		return claCache[claTextUrl], nil
-	}
-
-	logger.Debug("CLA text not in cache, moving forward to fetch", zap.String("claTextUrl", claTextUrl))
-	if claTextUrl == "" {
-		return "", errors.New(msgMissingClaUrl)
-	}
-
 	client := http.Client{}
 
 	resp, err := client.Get(claTextUrl)
@@ -494,9 +1364,7 @@ func getClaText(claTextUrl string) (claText string, err error) {
 		return "", err
 	}
 
-	claCache[claTextUrl] = string(content)
-
-	return claCache[claTextUrl], nil
+	return string(content), nil
 }
 
 const envSmtpHost = "SMTP_HOST"
@@ -513,7 +1381,10 @@ func handleTestEmail(c echo.Context) (err error) {
 	testSignature.CLAVersion = getCurrentCLAVersion()
 	testSignature.TimeSigned = time.Now()
 	testSignature.CLATextUrl = os.Getenv(envClsUrl)
-	testSignature.CLAText, _ = getClaText(testSignature.CLATextUrl)
+	if stored, err := getStoredCLADocument(&types.CLADocument{CLAVersion: testSignature.CLAVersion, CLATextUrl: testSignature.CLATextUrl}); err == nil {
+		testSignature.CLAText = stored.CLAText
+		testSignature.CLATextSha256 = stored.CLATextSha256
+	}
 
 	return notifySignatureComplete(testSignature)
 }
@@ -521,13 +1392,9 @@ func handleTestEmail(c echo.Context) (err error) {
 func notifySignatureComplete(signature *types.UserSignature) (err error) {
 	smtpHost := os.Getenv(envSmtpHost)
 	smtpPort := os.Getenv(envSmtpPort)
-	smtpUsername := os.Getenv(envSmtpUsername)
-	smtpPassword := os.Getenv(envSmtpPassword)
 	notificationAddress := os.Getenv(envNotificationAddress)
 
 	logger.Info("Preparing SMTP...")
-	auth := smtp.PlainAuth("", smtpUsername, smtpPassword, smtpHost)
-	to := []string{notificationAddress}
 	msg := []byte("To: " + notificationAddress + "\r\n" +
 
 		"Subject: CLA Signature Received\r\n" +
@@ -541,15 +1408,32 @@ func notifySignatureComplete(signature *types.UserSignature) (err error) {
 		"	Given Name    : " + signature.User.GivenName + "\r\n" +
 		"	Email Address : " + signature.User.Email + "\r\n\r\n" +
 
-		"CLA Text below was as signed (obtained from " + signature.CLATextUrl + "):\r\n\r\n" + signature.CLAText)
+		"CLA Text below was as signed (obtained from " + signature.CLATextUrl + ", SHA-256 " + signature.CLATextSha256 + "):\r\n\r\n" + signature.CLAText)
 
 	if smtpHost == "" || smtpPort == "" || notificationAddress == "" {
 		logger.Error("SMTP Host, SMTP Port or Notification Address are empty - cannot send notification")
 		return errors.New("SMTP Host, SMTP Port or Notification Address are empty - cannot send notification")
 	}
 
+	return sendEmail(notificationAddress, msg)
+}
+
+const emailFrom = "cla-legal@sonatype.com"
+
+// sendMail is replaced by tests
+var sendMail = smtp.SendMail
+
+// sendEmail sends the message via the SMTP server configured by the environment
+func sendEmail(to string, msg []byte) (err error) {
+	smtpHost := os.Getenv(envSmtpHost)
+	smtpPort := os.Getenv(envSmtpPort)
+	smtpUsername := os.Getenv(envSmtpUsername)
+	smtpPassword := os.Getenv(envSmtpPassword)
+
+	auth := smtp.PlainAuth("", smtpUsername, smtpPassword, smtpHost)
+
 	logger.Debug("Calling SMTP Send...")
-	err = smtp.SendMail(fmt.Sprintf("%s:%s", smtpHost, smtpPort), auth, "cla-legal@sonatype.com", to, msg)
+	err = sendMail(fmt.Sprintf("%s:%s", smtpHost, smtpPort), auth, emailFrom, []string{to}, msg)
 	logger.Debug("SMTP Send Complete", zap.Error(err))
 
 	if err != nil {
@@ -559,3 +1443,184 @@ func notifySignatureComplete(signature *types.UserSignature) (err error) {
 
 	return nil
 }
+
+const pathResignCampaign = "/resign-campaign"
+const queryParameterId = "id"
+const resignCampaignActivityWindow = 90 * 24 * time.Hour
+const msgSmtpNotConfigured = "SMTP Host or SMTP Port are empty - cannot send emails"
+const msgTemplateResignCampaignNotFound = "resign campaign not found: %s"
+const msgTemplateResignCampaignDocumentNotFound = "CLA document %q version %s is not published"
+
+// handleStartResignCampaign emails the signers of prior versions of a CLA document who are still active a link to sign
+// the given version of the document (by default, the current version of the default document). The contacts are
+// stored before the emails are sent in the background, and each contact records if its email was sent.
+func handleStartResignCampaign(c echo.Context) (err error) {
+	campaign := new(types.ResignCampaign)
+	if err := c.Bind(campaign); err != nil {
+		return err
+	}
+	if os.Getenv(envSmtpHost) == "" || os.Getenv(envSmtpPort) == "" {
+		logger.Error(msgSmtpNotConfigured)
+		return c.String(http.StatusInternalServerError, msgSmtpNotConfigured)
+	}
+
+	if campaign.DocumentId != "" && campaign.CLAVersion == "" {
+		return c.String(http.StatusUnprocessableEntity, fmt.Sprintf(msgTemplateMissingField, "claVersion"))
+	}
+	doc, err := findResignCampaignDocument(campaign.DocumentId, campaign.CLAVersion)
+	if err != nil {
+		logger.Error("failed to find resign campaign document", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if doc == nil {
+		return c.String(http.StatusUnprocessableEntity,
+			fmt.Sprintf(msgTemplateResignCampaignDocumentNotFound, campaign.DocumentId, campaign.CLAVersion))
+	}
+	campaign.CLAVersion = doc.CLAVersion
+	if campaign.ActiveSince.IsZero() {
+		campaign.ActiveSince = time.Now().Add(-resignCampaignActivityWindow)
+	}
+	campaign.StartedBy, _, _ = c.Request().BasicAuth()
+	campaign.StartedAt = time.Now()
+
+	signatures, err := postgresDB.GetResignCandidates(campaign.DocumentId, campaign.CLAVersion, campaign.ActiveSince)
+	if err != nil {
+		logger.Error("failed to find resign candidates", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	campaign.Contacts = selectResignContacts(signatures, campaign.CLAVersion)
+
+	// the link is the signing page configured for the GitHub app, never the host of the request of the admin
+	appId, err := ourGithub.GetAppId()
+	if err != nil {
+		logger.Error("failed to get app id", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	signUrl, err := ourGithub.SignUrlForCLAVersion(appId, campaign.CLAVersion)
+	if err != nil {
+		logger.Error("failed to get the url to sign the cla", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+
+	if err = postgresDB.InsertResignCampaign(campaign); err != nil {
+		logger.Error("failed to start resign campaign", zap.Error(err))
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Info("started resign campaign",
+		zap.String("id", campaign.Id),
+		zap.String("documentId", campaign.DocumentId),
+		zap.String("claVersion", campaign.CLAVersion),
+		zap.Int("contacts", len(campaign.Contacts)),
+	)
+
+	jobLogger := logger
+	jobDB := postgresDB
+	campaignId := campaign.Id
+	claVersion := campaign.CLAVersion
+	contacts := append([]types.ResignContact{}, campaign.Contacts...)
+	runInBackground(func() {
+		for _, contact := range contacts {
+			if err := notifyResignRequest(&contact, claVersion, signUrl); err != nil {
+				contact.Error = err.Error()
+			} else {
+				contactedAt := time.Now()
+				contact.ContactedAt = &contactedAt
+			}
+			if err := jobDB.UpdateResignContact(campaignId, &contact); err != nil {
+				// log this, and keep going, the email is sent already
+				jobLogger.Error("failed to record resign contact", zap.String("login", contact.Login), zap.Error(err))
+			}
+		}
+		jobLogger.Info("sent resign campaign emails", zap.String("id", campaignId), zap.Int("contacts", len(contacts)))
+	})
+	return c.JSON(http.StatusCreated, campaign)
+}
+
+// findResignCampaignDocument returns the published CLA document a campaign asks to sign: the given version of the
+// document of the catalog, or of the default document if documentId is empty. If claVersion is empty, the current
+// version of the default document is returned. Nil is returned if the document is not published.
+func findResignCampaignDocument(documentId, claVersion string) (doc *types.CLADocument, err error) {
+	if documentId == "" {
+		doc = defaultCLADocument()
+		if !matchesCLADocument(doc, claVersion, "") {
+			return nil, nil
+		}
+		return
+	}
+
+	docs, err := postgresDB.GetCLADocumentsByVersion(claVersion)
+	if err != nil {
+		return
+	}
+	for i := range docs {
+		if docs[i].Id == documentId {
+			return &docs[i], nil
+		}
+	}
+	return
+}
+
+// selectResignContacts picks the most recent prior signature of each signer, from signatures sorted by signer and
+// most recent first. A signer who signed a newer CLA version than the campaign asks for is not contacted.
+func selectResignContacts(signatures []types.ResignContact, claVersion string) (contacts []types.ResignContact) {
+	skipped := map[string]bool{}
+	for _, signature := range signatures {
+		if !ourGithub.IsPriorVersion(signature.SignedCLAVersion, claVersion) {
+			skipped[strings.ToLower(signature.Login)] = true
+		}
+	}
+	contacts = []types.ResignContact{}
+	for _, signature := range signatures {
+		login := strings.ToLower(signature.Login)
+		if skipped[login] {
+			continue
+		}
+		skipped[login] = true
+		contacts = append(contacts, signature)
+	}
+	return
+}
+
+// handleResignCampaign reports who a campaign contacted, and who signed the CLA version of the campaign since
+func handleResignCampaign(c echo.Context) (err error) {
+	campaignId, err := getRequiredQueryParameter(c, queryParameterId)
+	if err != nil {
+		return c.String(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	campaign, err := postgresDB.GetResignCampaign(campaignId)
+	if err != nil {
+		logger.Error("failed to get resign campaign", zap.Error(err))
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	if campaign == nil {
+		return c.String(http.StatusNotFound, fmt.Sprintf(msgTemplateResignCampaignNotFound, campaignId))
+	}
+	return c.JSON(http.StatusOK, campaign)
+}
+
+func notifyResignRequest(contact *types.ResignContact, claVersion, signUrl string) (err error) {
+	// the email is entered by the signer, so it must not be able to add headers
+	if strings.ContainsAny(contact.Email, "\r\n") {
+		return fmt.Errorf("invalid email address: %q", contact.Email)
+	}
+
+	msg := []byte("To: " + contact.Email + "\r\n" +
+
+		"Subject: Please sign version " + claVersion + " of the Contributor License Agreement\r\n" +
+
+		"\r\n" +
+
+		"Hi @" + contact.Login + ",\r\n\r\n" +
+
+		"You signed version " + contact.SignedCLAVersion + " of the Contributor License Agreement (CLA). " +
+		"Since then, we published version " + claVersion + " of the CLA. " +
+		"To keep contributing, please sign the new version at:\r\n\r\n" +
+
+		"	" + signUrl + "\r\n\r\n" +
+
+		"Thank you for your contributions!\r\n")
+
+	return sendEmail(contact.Email, msg)
+}
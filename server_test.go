@@ -20,10 +20,12 @@
 package main
 
 import (
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/smtp"
 	"os"
 	"strings"
 	"testing"
@@ -34,8 +36,11 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/sonatype-nexus-community/the-cla/db"
 	ourGithub "github.com/sonatype-nexus-community/the-cla/github"
+	"github.com/sonatype-nexus-community/the-cla/oauth"
+	"github.com/sonatype-nexus-community/the-cla/session"
 	"github.com/sonatype-nexus-community/the-cla/types"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 	webhook "gopkg.in/go-playground/webhooks.v5/github"
 )
@@ -125,6 +130,13 @@ func TestHandleRetrieveCLAText_BadResponseCode(t *testing.T) {
 	}))
 	defer ts.Close()
 
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}))
+
 	assert.NoError(t, os.Setenv(envClsUrl, ts.URL+pathClaText))
 	assert.EqualError(t, handleRetrieveCLAText(setupMockContextCLA(t)), "unexpected cla text response code: 403")
 }
@@ -147,14 +159,33 @@ func TestHandleRetrieveCLAText(t *testing.T) {
 	}))
 	defer ts.Close()
 
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	storedColumns := []string{"Id", "ClaVersion", "ClaTextSha256", "ClaTextUrl", "ClaText", "CreatedAt"}
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WithArgs(getCurrentCLAVersion(), ts.URL+pathClaText).
+		WillReturnRows(sqlmock.NewRows(storedColumns))
+	mock.ExpectQuery("INSERT INTO cla_documents").
+		WithArgs(getCurrentCLAVersion(), "8f98a5c90a9c038873ad848e9cb0d234bc9a9c7932ffdbc0b895c3f619758478", ts.URL+pathClaText, mockClaText, db.AnyTime{}).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "ClaTextUrl", "CreatedAt"}).AddRow(1, ts.URL+pathClaText, time.Now()))
+
 	assert.NoError(t, os.Setenv(envClsUrl, ts.URL+pathClaText))
 	assert.NoError(t, handleRetrieveCLAText(setupMockContextCLA(t)))
 	assert.Equal(t, callCount, 1)
 
-	// Ensure that subsequent calls use the cache
+	// Ensure that subsequent calls use the stored text
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WithArgs(getCurrentCLAVersion(), ts.URL+pathClaText).
+		WillReturnRows(sqlmock.NewRows(storedColumns).
+			AddRow(1, getCurrentCLAVersion(), "someHash", ts.URL+pathClaText, mockClaText, time.Now()))
 
-	assert.NoError(t, handleRetrieveCLAText(setupMockContextCLA(t)))
+	c := setupMockContextCLA(t)
+	assert.NoError(t, handleRetrieveCLAText(c))
 	assert.Equal(t, callCount, 1)
+	assert.Equal(t, mockClaText, c.Response().Writer.(*httptest.ResponseRecorder).Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestHandleRetrieveCLATextWithBadURL(t *testing.T) {
@@ -175,13 +206,21 @@ func TestHandleRetrieveCLATextWithBadURL(t *testing.T) {
 	}))
 	defer ts.Close()
 
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}))
+
 	assert.NoError(t, os.Setenv(envClsUrl, "badURLProtocol"+ts.URL+pathClaText))
 	assert.Error(t, handleRetrieveCLAText(setupMockContextCLA(t)), `unsupported protocol scheme "badurlprotocolhttp"`)
 	assert.Equal(t, callCount, 0)
 }
 
-func setupMockContextOAuth(t *testing.T, queryParams map[string]string) (c echo.Context, rec *httptest.ResponseRecorder) {
+func setupMockContextOAuth(t *testing.T, queryParams map[string]string, cookies ...*http.Cookie) (c echo.Context, rec *httptest.ResponseRecorder) {
 	logger = zaptest.NewLogger(t)
+	sessionManager = session.NewManager([]byte("myTestKey"), sessionTTL)
 
 	// Setup
 	e := echo.New()
@@ -193,25 +232,169 @@ func setupMockContextOAuth(t *testing.T, queryParams map[string]string) (c echo.
 	}
 	req.URL.RawQuery = q.Encode()
 
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
 	rec = httptest.NewRecorder()
 	c = e.NewContext(req, rec)
 	return
 }
 
-func TestHandleProcessGitHubOAuthMissingQueryParamState(t *testing.T) {
+// mockOAuth embeds the interface to satisfy its unexported method, and only implements what the handlers call
+type mockOAuth struct {
+	oauth.OAuthInterface
+	t               *testing.T
+	authState       string
+	authRedirectUrl string
+	getUserCode     string
+	getUserUser     *github.User
+	getUserEmails   []string
+	getUserErr      error
+}
+
+func (m *mockOAuth) AuthCodeURL(state, redirectUrl string) string {
+	m.authState = state
+	m.authRedirectUrl = redirectUrl
+	return "https://github.com/login/oauth/authorize?state=" + state
+}
+
+func (m *mockOAuth) GetOAuthUser(_ *zap.Logger, code string) (user *github.User, verifiedEmails []string, err error) {
+	assert.Equal(m.t, m.getUserCode, code)
+	return m.getUserUser, m.getUserEmails, m.getUserErr
+}
+
+func setupMockOAuth(t *testing.T) (mock *mockOAuth, resetMock func()) {
+	origCreateOAuth := createOAuth
+	mock = &mockOAuth{t: t}
+	createOAuth = func(clientID, clientSecret string) oauth.OAuthInterface {
+		return mock
+	}
+	return mock, func() {
+		createOAuth = origCreateOAuth
+	}
+}
+
+func findCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestHandleGitHubOAuthLogin(t *testing.T) {
+	mock, resetMock := setupMockOAuth(t)
+	defer resetMock()
 	c, rec := setupMockContextOAuth(t, map[string]string{})
+
+	assert.NoError(t, handleGitHubOAuthLogin(c))
+	assert.Equal(t, http.StatusFound, c.Response().Status)
+	assert.Equal(t, "https://github.com/login/oauth/authorize?state="+mock.authState, rec.Header().Get(echo.HeaderLocation))
+	assert.Equal(t, "http://example.com/", mock.authRedirectUrl)
+
+	stateCookie := findCookie(rec, cookieNameOAuthState)
+	assert.NotNil(t, stateCookie)
+	assert.True(t, stateCookie.HttpOnly)
+	assert.NoError(t, sessionManager.VerifyState(stateCookie.Value, mock.authState, time.Now()))
+}
+
+func TestHandleProcessGitHubOAuthMissingQueryParamCode(t *testing.T) {
+	c, rec := setupMockContextOAuth(t, map[string]string{"state": "myState"})
 	assert.NoError(t, handleProcessGitHubOAuth(c))
-	assert.Equal(t, 0, c.Response().Status)
-	assert.Equal(t, "", rec.Body.String())
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "code"), rec.Body.String())
+}
+
+func TestHandleProcessGitHubOAuthMissingQueryParamState(t *testing.T) {
+	c, rec := setupMockContextOAuth(t, map[string]string{"code": "myCode"})
+	assert.NoError(t, handleProcessGitHubOAuth(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "state"), rec.Body.String())
+}
+
+func TestHandleProcessGitHubOAuthMissingStateCookie(t *testing.T) {
+	c, rec := setupMockContextOAuth(t, map[string]string{"code": "myCode", "state": "myState"})
+	assert.NoError(t, handleProcessGitHubOAuth(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, msgInvalidOAuthState, rec.Body.String())
+}
+
+func TestHandleProcessGitHubOAuthStateMismatch(t *testing.T) {
+	sessionManager = session.NewManager([]byte("myTestKey"), sessionTTL)
+	_, token, err := sessionManager.CreateState(time.Now(), oauthStateTTL)
+	assert.NoError(t, err)
+
+	c, rec := setupMockContextOAuth(t, map[string]string{"code": "myCode", "state": "forgedState"},
+		&http.Cookie{Name: cookieNameOAuthState, Value: token})
+	assert.NoError(t, handleProcessGitHubOAuth(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, msgInvalidOAuthState, rec.Body.String())
+	assert.Nil(t, findCookie(rec, cookieNameSession))
+}
+
+func TestHandleProcessGitHubOAuthExchangeFailed(t *testing.T) {
+	mock, resetMock := setupMockOAuth(t)
+	defer resetMock()
+	mock.getUserCode = "myCode"
+	mock.getUserErr = fmt.Errorf("forced exchange error")
+
+	sessionManager = session.NewManager([]byte("myTestKey"), sessionTTL)
+	state, token, err := sessionManager.CreateState(time.Now(), oauthStateTTL)
+	assert.NoError(t, err)
+
+	c, rec := setupMockContextOAuth(t, map[string]string{"code": "myCode", "state": state},
+		&http.Cookie{Name: cookieNameOAuthState, Value: token})
+	assert.NoError(t, handleProcessGitHubOAuth(c))
+	assert.Equal(t, http.StatusUnauthorized, c.Response().Status)
+	assert.Equal(t, msgOAuthLoginFailed, rec.Body.String())
+	assert.Nil(t, findCookie(rec, cookieNameSession))
+}
+
+func TestHandleProcessGitHubOAuth(t *testing.T) {
+	mock, resetMock := setupMockOAuth(t)
+	defer resetMock()
+	mock.getUserCode = "myCode"
+	mock.getUserUser = &github.User{Login: github.String("myLogin"), ID: github.Int64(42), Email: github.String("primary@example.com")}
+	mock.getUserEmails = []string{"primary@example.com", "other@example.com"}
+
+	sessionManager = session.NewManager([]byte("myTestKey"), sessionTTL)
+	state, token, err := sessionManager.CreateState(time.Now(), oauthStateTTL)
+	assert.NoError(t, err)
+
+	c, rec := setupMockContextOAuth(t, map[string]string{"code": "myCode", "state": state},
+		&http.Cookie{Name: cookieNameOAuthState, Value: token})
+	assert.NoError(t, handleProcessGitHubOAuth(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+
+	// the state cookie is removed, so the state can not be used again
+	stateCookie := findCookie(rec, cookieNameOAuthState)
+	assert.NotNil(t, stateCookie)
+	assert.True(t, stateCookie.MaxAge < 0)
+
+	sessionCookie := findCookie(rec, cookieNameSession)
+	assert.NotNil(t, sessionCookie)
+	authenticated, err := sessionManager.Read(sessionCookie.Value, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "myLogin", authenticated.Login)
+	assert.Equal(t, int64(42), authenticated.UserId)
+	assert.Equal(t, mock.getUserEmails, authenticated.VerifiedEmails)
+
+	loggedIn := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &loggedIn))
+	assert.Equal(t, "myLogin", loggedIn["login"])
+	assert.Equal(t, "primary@example.com", loggedIn["email"])
+	assert.Equal(t, []interface{}{"primary@example.com", "other@example.com"}, loggedIn["verifiedEmails"])
 }
 
-func setupMockContextWebhook(t *testing.T, headers map[string]string, prEvent github.PullRequestEvent) (c echo.Context, rec *httptest.ResponseRecorder) {
+func setupMockContextWebhook(t *testing.T, headers map[string]string, event interface{}) (c echo.Context, rec *httptest.ResponseRecorder) {
 	logger = zaptest.NewLogger(t)
 
 	// Setup
 	e := echo.New()
 
-	reqBody, err := json.Marshal(prEvent)
+	reqBody, err := json.Marshal(event)
 	assert.NoError(t, err)
 
 	req := httptest.NewRequest(http.MethodPost, pathWebhook, strings.NewReader(string(reqBody)))
@@ -346,8 +529,12 @@ func verifyActionHandled(t *testing.T, actionText string) {
 	defer closeDbFunc()
 	postgresDB = dbIF
 
-	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectUnsignedUsersForPR)).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentForRepo)).
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlDeleteSignedPRUsers)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
 
 	origGHAppIDEnvVar := os.Getenv(ourGithub.EnvGhAppId)
 	defer func() {
@@ -384,10 +571,101 @@ func verifyActionHandled(t *testing.T, actionText string) {
 	assert.NoError(t, handleProcessWebhook(c))
 	assert.Equal(t, http.StatusAccepted, c.Response().Status)
 	assert.Equal(t, "accepted pull request for processing", rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleProcessWebhookGitHubEventPullRequestClosed(t *testing.T) {
+	actionText := "closed"
+	c, rec := setupMockContextWebhook(t,
+		map[string]string{
+			"X-GitHub-Event": string(webhook.PullRequestEvent),
+		}, github.PullRequestEvent{Action: &actionText})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM unsigned_user").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM unsigned_pr").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM signed_pr_user").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	origGHAppIDEnvVar := os.Getenv(ourGithub.EnvGhAppId)
+	defer func() {
+		resetEnvVariable(t, ourGithub.EnvGhAppId, origGHAppIDEnvVar)
+	}()
+	assert.NoError(t, os.Setenv(ourGithub.EnvGhAppId, "-1"))
+
+	origGHWebhookSecret := clearEnvGHWebhookSecretMadness(t)
+	defer func() {
+		resetEnvVariable(t, envGhWebhookSecret, origGHWebhookSecret)
+	}()
+
+	assert.NoError(t, handleProcessWebhook(c))
+	assert.Equal(t, http.StatusAccepted, c.Response().Status)
+	assert.Equal(t, "accepted closed pull request", rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleProcessWebhookGitHubEventCheckRunActionIgnored(t *testing.T) {
+	actionText := "completed"
+	c, rec := setupMockContextWebhook(t,
+		map[string]string{
+			"X-GitHub-Event": string(webhook.CheckRunEvent),
+		}, github.CheckRunEvent{Action: &actionText})
+
+	origGHAppIDEnvVar := os.Getenv(ourGithub.EnvGhAppId)
+	defer func() {
+		resetEnvVariable(t, ourGithub.EnvGhAppId, origGHAppIDEnvVar)
+	}()
+	assert.NoError(t, os.Setenv(ourGithub.EnvGhAppId, "-1"))
+
+	origGHWebhookSecret := clearEnvGHWebhookSecretMadness(t)
+	defer func() {
+		resetEnvVariable(t, envGhWebhookSecret, origGHWebhookSecret)
+	}()
+
+	assert.NoError(t, handleProcessWebhook(c))
+	assert.Equal(t, http.StatusAccepted, c.Response().Status)
+	assert.Equal(t, "No action taken for check run: completed", rec.Body.String())
+}
+
+func TestHandleProcessWebhookGitHubEventIssueCommentNotOnPR(t *testing.T) {
+	actionText := "created"
+	c, rec := setupMockContextWebhook(t,
+		map[string]string{
+			"X-GitHub-Event": string(webhook.IssueCommentEvent),
+		}, github.IssueCommentEvent{
+			Action:  &actionText,
+			Issue:   &github.Issue{},
+			Comment: &github.IssueComment{Body: github.String("/cla recheck")},
+		})
+
+	origGHAppIDEnvVar := os.Getenv(ourGithub.EnvGhAppId)
+	defer func() {
+		resetEnvVariable(t, ourGithub.EnvGhAppId, origGHAppIDEnvVar)
+	}()
+	assert.NoError(t, os.Setenv(ourGithub.EnvGhAppId, "-1"))
+
+	origGHWebhookSecret := clearEnvGHWebhookSecretMadness(t)
+	defer func() {
+		resetEnvVariable(t, envGhWebhookSecret, origGHWebhookSecret)
+	}()
+
+	assert.NoError(t, handleProcessWebhook(c))
+	assert.Equal(t, http.StatusAccepted, c.Response().Status)
+	assert.Equal(t, "No action taken for comment: created", rec.Body.String())
 }
 
-func setupMockContextSignCla(t *testing.T, headers map[string]string, user types.UserSignature) (c echo.Context, rec *httptest.ResponseRecorder) {
+// setupMockContextSignCla sends the request in the session of the given login (with the given verified emails), or
+// without a session if it is empty
+func setupMockContextSignCla(t *testing.T, headers map[string]string, user types.UserSignature, sessionLogin string, verifiedEmails ...string) (c echo.Context, rec *httptest.ResponseRecorder) {
 	logger = zaptest.NewLogger(t)
+	sessionManager = session.NewManager([]byte("myTestKey"), sessionTTL)
 
 	// Setup
 	e := echo.New()
@@ -400,6 +678,11 @@ func setupMockContextSignCla(t *testing.T, headers map[string]string, user types
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
+	if sessionLogin != "" {
+		_, token, err := sessionManager.Create(sessionLogin, 1, verifiedEmails, time.Now())
+		assert.NoError(t, err)
+		req.AddCookie(&http.Cookie{Name: cookieNameSession, Value: token})
+	}
 
 	rec = httptest.NewRecorder()
 	c = e.NewContext(req, rec)
@@ -407,12 +690,273 @@ func setupMockContextSignCla(t *testing.T, headers map[string]string, user types
 }
 
 func TestHandleProcessSignClaBindError(t *testing.T) {
-	c, rec := setupMockContextSignCla(t, map[string]string{}, types.UserSignature{})
+	c, rec := setupMockContextSignCla(t, map[string]string{}, types.UserSignature{}, "")
 	assert.EqualError(t, handleProcessSignCla(c), "code=415, message=Unsupported Media Type")
 	assert.Equal(t, 0, c.Response().Status)
 	assert.Equal(t, "", rec.Body.String())
 }
 
+func TestHandleProcessSignClaNoSession(t *testing.T) {
+	c, rec := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON},
+		types.UserSignature{User: types.User{Login: "myLogin"}}, "")
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusUnauthorized, c.Response().Status)
+	assert.Equal(t, msgNotAuthenticated, rec.Body.String())
+}
+
+func TestHandleProcessSignClaForgedSession(t *testing.T) {
+	c, rec := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON},
+		types.UserSignature{User: types.User{Login: "myLogin"}}, "")
+	_, token, err := session.NewManager([]byte("notTheServerKey"), sessionTTL).Create("myLogin", 1, nil, time.Now())
+	assert.NoError(t, err)
+	c.Request().AddCookie(&http.Cookie{Name: cookieNameSession, Value: token})
+
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusUnauthorized, c.Response().Status)
+	assert.Equal(t, msgNotAuthenticated, rec.Body.String())
+}
+
+func TestHandleProcessSignClaOAuthStateAsSession(t *testing.T) {
+	c, rec := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON},
+		types.UserSignature{User: types.User{Login: "myLogin"}}, "")
+	_, token, err := sessionManager.CreateState(time.Now(), oauthStateTTL)
+	assert.NoError(t, err)
+	c.Request().AddCookie(&http.Cookie{Name: cookieNameSession, Value: token})
+
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusUnauthorized, c.Response().Status)
+	assert.Equal(t, msgNotAuthenticated, rec.Body.String())
+}
+
+func TestHandleProcessSignClaSignsAsSessionUser(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	origClaUrl := os.Getenv(envClsUrl)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+		resetEnvVariable(t, envClsUrl, origClaUrl)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	assert.NoError(t, os.Setenv(envClsUrl, "https://my.url/text"))
+
+	c, _ := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON, "User-Agent": "myUserAgent"},
+		types.UserSignature{User: types.User{Login: "someoneElse", Email: "me@somewhere.tld"}, EmailVerified: true}, "myLogin",
+		"other@somewhere.tld")
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WithArgs("2.0", "https://my.url/text").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "ClaVersion", "ClaTextSha256", "ClaTextUrl", "ClaText", "CreatedAt"}).
+			AddRow(1, "2.0", "myHash", "https://my.url/text", mockClaText, time.Now()))
+	forcedError := fmt.Errorf("forced insert error")
+	mock.ExpectExec("INSERT INTO signatures").
+		WithArgs("myLogin", "me@somewhere.tld", "", db.AnyTime{}, "2.0", "https://my.url/text", 1, "myHash", false,
+			"192.0.2.1", "myUserAgent", 1).
+		WillReturnError(forcedError)
+
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleProcessSignClaVerifiedEmail(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	origClaUrl := os.Getenv(envClsUrl)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+		resetEnvVariable(t, envClsUrl, origClaUrl)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	assert.NoError(t, os.Setenv(envClsUrl, "https://my.url/text"))
+
+	c, _ := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON, "User-Agent": "myUserAgent"},
+		types.UserSignature{User: types.User{Login: "myLogin", Email: "me@somewhere.tld"}}, "myLogin", "Me@Somewhere.tld")
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WithArgs("2.0", "https://my.url/text").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "ClaVersion", "ClaTextSha256", "ClaTextUrl", "ClaText", "CreatedAt"}).
+			AddRow(1, "2.0", "myHash", "https://my.url/text", mockClaText, time.Now()))
+	forcedError := fmt.Errorf("forced insert error")
+	mock.ExpectExec("INSERT INTO signatures").
+		WithArgs("myLogin", "me@somewhere.tld", "", db.AnyTime{}, "2.0", "https://my.url/text", 1, "myHash", true,
+			"192.0.2.1", "myUserAgent", 1).
+		WillReturnError(forcedError)
+
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// capturedArg matches any argument of a query, and keeps its value
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func TestHandleProcessSignClaVerifiedEmailMatchesUnlinkedCommit(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	origClaUrl := os.Getenv(envClsUrl)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+		resetEnvVariable(t, envClsUrl, origClaUrl)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	assert.NoError(t, os.Setenv(envClsUrl, "https://my.url/text"))
+	_, resetSendMail := setupMockSendMail(t, nil)
+	defer resetSendMail()
+
+	c, _ := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON, "User-Agent": "myUserAgent"},
+		types.UserSignature{User: types.User{Login: "myLogin", Email: "me@somewhere.tld"}}, "myLogin", "me@somewhere.tld")
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WithArgs("2.0", "https://my.url/text").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "ClaVersion", "ClaTextSha256", "ClaTextUrl", "ClaText", "CreatedAt"}).
+			AddRow(1, "2.0", "myHash", "https://my.url/text", mockClaText, time.Now()))
+	// keep what the signing stores, to find it again like the evaluation of a commit without a GitHub author does
+	inserted := make([]capturedArg, 12)
+	insertArgs := make([]driver.Value, len(inserted))
+	for i := range inserted {
+		insertArgs[i] = &inserted[i]
+	}
+	mock.ExpectExec("INSERT INTO signatures").
+		WithArgs(insertArgs...).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT DISTINCT unsigned_pr").
+		WithArgs("myLogin", "2.0").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "RepoOwner", "RepoName", "sha", "PRNumber", "AppID", "InstallID"}))
+
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusCreated, c.Response().Status)
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectEmailSignature)).
+		WithArgs("Me@Somewhere.tld", "2.0").
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText", "ClaDocumentId", "ClaTextSha256", "EmailVerified", "IpAddress", "UserAgent", "GitHubUserId"}).
+			AddRow(inserted[0].value, inserted[1].value, inserted[2].value, inserted[3].value, inserted[4].value,
+				inserted[5].value, mockClaText, inserted[6].value, inserted[7].value, inserted[8].value,
+				inserted[9].value, inserted[10].value, inserted[11].value))
+
+	isSigned, signature, err := postgresDB.HasEmailSignedTheCla("Me@Somewhere.tld", "2.0")
+	assert.NoError(t, err)
+	assert.True(t, isSigned)
+	assert.Equal(t, "myLogin", signature.User.Login)
+	assert.True(t, signature.EmailVerified)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleProcessSignClaForwardedForNotAnIP(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	origClaUrl := os.Getenv(envClsUrl)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+		resetEnvVariable(t, envClsUrl, origClaUrl)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	assert.NoError(t, os.Setenv(envClsUrl, "https://my.url/text"))
+
+	c, _ := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON,
+		"User-Agent": "myUserAgent", echo.HeaderXForwardedFor: strings.Repeat("x", 100) + ", 192.0.2.2"},
+		types.UserSignature{User: types.User{Login: "myLogin"}}, "myLogin")
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectStoredCLADocumentByUrl)).
+		WithArgs("2.0", "https://my.url/text").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "ClaVersion", "ClaTextSha256", "ClaTextUrl", "ClaText", "CreatedAt"}).
+			AddRow(1, "2.0", "myHash", "https://my.url/text", mockClaText, time.Now()))
+	forcedError := fmt.Errorf("forced insert error")
+	mock.ExpectExec("INSERT INTO signatures").
+		WithArgs("myLogin", "", "", db.AnyTime{}, "2.0", "https://my.url/text", 1, "myHash", false,
+			"", "myUserAgent", 1).
+		WillReturnError(forcedError)
+
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNewIPExtractor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, pathSignCla, nil)
+	req.RemoteAddr = "10.1.2.3:1234"
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.7, 10.4.5.6")
+
+	extractor, err := newIPExtractor("")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.2.3", extractor(req))
+
+	extractor, err = newIPExtractor("10.0.0.0/8, 192.168.0.0/16")
+	assert.NoError(t, err)
+	assert.Equal(t, "198.51.100.7", extractor(req))
+
+	// the header is ignored for requests that do not come from a trusted proxy
+	extractor, err = newIPExtractor("192.168.0.0/16")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.2.3", extractor(req))
+}
+
+func TestNewIPExtractorInvalid(t *testing.T) {
+	_, err := newIPExtractor("10.0.0.0/8,notARange")
+	assert.EqualError(t, err, "invalid CIDR address: notARange")
+}
+
+func TestNormalizeIP(t *testing.T) {
+	assert.Equal(t, "192.0.2.1", normalizeIP(" 192.0.2.1 "))
+	assert.Equal(t, "2001:db8::1", normalizeIP("2001:DB8:0:0:0:0:0:1"))
+	assert.Equal(t, "", normalizeIP("unknown"))
+	assert.Equal(t, "", normalizeIP(strings.Repeat("1", 100)))
+}
+
+func TestHandleProcessSignClaMissingClaText(t *testing.T) {
+	origClaUrl := os.Getenv(envClsUrl)
+	defer func() {
+		resetEnvVariable(t, envClsUrl, origClaUrl)
+	}()
+	resetEnvVariable(t, envClsUrl, "")
+
+	c, rec := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON},
+		types.UserSignature{User: types.User{Login: "myLogin"}}, "myLogin")
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusInternalServerError, c.Response().Status)
+	assert.Equal(t, msgMissingClaUrl, rec.Body.String())
+}
+
+func TestHandleProcessSignClaCLADocumentMismatch(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	origClaUrl := os.Getenv(envClsUrl)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+		resetEnvVariable(t, envClsUrl, origClaUrl)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	assert.NoError(t, os.Setenv(envClsUrl, "https://my.url/text"))
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Fail(t, "the url given by the signer must not be fetched")
+	}))
+	defer ts.Close()
+
+	c, rec := setupMockContextSignCla(t, map[string]string{echo.HeaderContentType: echo.MIMEApplicationJSON},
+		types.UserSignature{User: types.User{Login: "myLogin"}, CLAVersion: "2.0", CLATextUrl: ts.URL}, "myLogin")
+	assert.NoError(t, handleProcessSignCla(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateCLADocumentMismatch, "2.0", ts.URL, "2.0", "https://my.url/text"), rec.Body.String())
+}
+
 func setupMockContextSignature(t *testing.T, queryParams map[string]string) (c echo.Context, rec *httptest.ResponseRecorder) {
 	logger = zaptest.NewLogger(t)
 
@@ -501,8 +1045,8 @@ func TestHandleSignatureHasAuthorSignedAndHidesFields(t *testing.T) {
 
 	now := time.Now()
 	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectUserSignature)).
-		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText"}).
-			AddRow(testLogin, "myEmail", "myGivenName", now, testCLAVersion, testCLATextUrl, testCLAText))
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText", "ClaDocumentId", "ClaTextSha256", "EmailVerified", "IpAddress", "UserAgent", "GitHubUserId"}).
+			AddRow(testLogin, "myEmail", "myGivenName", now, testCLAVersion, testCLATextUrl, testCLAText, 7, "myHash", true, "10.0.0.1", "myUserAgent", 42))
 
 	assert.NoError(t, handleSignature(c))
 	assert.Equal(t, http.StatusOK, c.Response().Status)
@@ -513,27 +1057,74 @@ func TestHandleSignatureHasAuthorSignedAndHidesFields(t *testing.T) {
 			Email:     hiddenFieldValue, // hide email
 			GivenName: hiddenFieldValue, // hide given name
 		},
-		CLAVersion: testCLAVersion,
-		TimeSigned: now,
-		CLATextUrl: testCLATextUrl,
-		CLAText:    testCLAText,
+		CLAVersion:    testCLAVersion,
+		TimeSigned:    now,
+		CLATextUrl:    testCLATextUrl,
+		CLAText:       testCLAText,
+		CLADocumentId: 7,
+		CLATextSha256: "myHash",
+		EmailVerified: true,
 	})
 	assert.NoError(t, err)
 	assert.Equal(t, string(expectedJsonSignature)+"\n", rec.Body.String())
 }
 
-func saveEnvInfoCredentials(t *testing.T) (resetInfoCreds func()) {
-	origInfoUsername := os.Getenv(envInfoUsername)
-	origInfoPassword := os.Getenv(envInfoPassword)
-	resetInfoCreds = func() {
-		resetEnvVariable(t, envInfoUsername, origInfoUsername)
-		resetEnvVariable(t, envInfoUsername, origInfoPassword)
-	}
+func TestHandleSignatureEvidence(t *testing.T) {
+	c, rec := setupMockContextSignature(t, map[string]string{
+		queryParameterLogin:      "myLogin",
+		queryParameterCLAVersion: "myCLAVersion",
+	})
 
-	// setup testing logger while we're here
-	logger = zaptest.NewLogger(t)
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
 
-	return
+	now := time.Now()
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectUserSignature)).
+		WithArgs("myLogin", "myCLAVersion", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaText", "ClaDocumentId", "ClaTextSha256", "EmailVerified", "IpAddress", "UserAgent", "GitHubUserId"}).
+			AddRow("myLogin", "myEmail", "myGivenName", now, "myCLAVersion", "https://my.url/text", "myText", 7, "myHash", true, "10.0.0.1", "myUserAgent", 42))
+
+	assert.NoError(t, handleSignatureEvidence(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+
+	found := types.UserSignature{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &found))
+	assert.Equal(t, "myEmail", found.User.Email)
+	assert.Equal(t, "myHash", found.CLATextSha256)
+	assert.Equal(t, &types.SigningEvidence{IPAddress: "10.0.0.1", UserAgent: "myUserAgent", GitHubUserId: 42}, found.Evidence)
+}
+
+func TestHandleSignatureEvidenceNotSigned(t *testing.T) {
+	c, rec := setupMockContextSignature(t, map[string]string{
+		queryParameterLogin:      "myLogin",
+		queryParameterCLAVersion: "myCLAVersion",
+	})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectUserSignature)).
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName"}))
+
+	assert.NoError(t, handleSignatureEvidence(c))
+	assert.Equal(t, http.StatusNotFound, c.Response().Status)
+	assert.Equal(t, "cla version myCLAVersion not signed by myLogin", rec.Body.String())
+}
+
+func saveEnvInfoCredentials(t *testing.T) (resetInfoCreds func()) {
+	origInfoUsername := os.Getenv(envInfoUsername)
+	origInfoPassword := os.Getenv(envInfoPassword)
+	resetInfoCreds = func() {
+		resetEnvVariable(t, envInfoUsername, origInfoUsername)
+		resetEnvVariable(t, envInfoUsername, origInfoPassword)
+	}
+
+	// setup testing logger while we're here
+	logger = zaptest.NewLogger(t)
+
+	return
 }
 
 func TestInfoBasicValidatorMissingEnv(t *testing.T) {
@@ -585,3 +1176,980 @@ func TestNotifySignatureCompleteFails(t *testing.T) {
 
 	assert.EqualError(t, err, "SMTP Host, SMTP Port or Notification Address are empty - cannot send notification")
 }
+
+func setupMockContextInfo(t *testing.T, method, path, body string, queryParams map[string]string) (c echo.Context, rec *httptest.ResponseRecorder) {
+	logger = zaptest.NewLogger(t)
+
+	// Setup
+	e := echo.New()
+
+	req := httptest.NewRequest(method, pathInfo+path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+	q := req.URL.Query()
+	for k, v := range queryParams {
+		q.Add(k, v)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	return
+}
+
+func TestHandleCorporateSignClaMissingCompany(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCorporateSignature, `{"signatory":{"login":"myLogin"}}`, map[string]string{})
+
+	assert.NoError(t, handleCorporateSignCla(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "company"), rec.Body.String())
+}
+
+func TestHandleCorporateSignClaMissingCLAVersion(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCorporateSignature, `{"company":"myCompany","signatory":{"login":"myLogin"}}`, map[string]string{})
+
+	assert.NoError(t, handleCorporateSignCla(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "claVersion"), rec.Body.String())
+}
+
+func TestHandleCorporateSignClaEmptyMember(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCorporateSignature,
+		`{"company":"myCompany","signatory":{"login":"myLogin"},"claVersion":"2.0","members":[{"type":"domain","value":"@"}]}`, map[string]string{})
+
+	assert.NoError(t, handleCorporateSignCla(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "members.value"), rec.Body.String())
+}
+
+func TestHandleCorporateSignClaUnknownCLADocument(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCorporateSignature,
+		`{"company":"myCompany","signatory":{"login":"myLogin"},"claVersion":"9.9","claTextUrl":"https://evil.url/text"}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentsByVersion)).
+		WithArgs("9.9").
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId"}))
+
+	assert.NoError(t, handleCorporateSignCla(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateCLADocumentUnknown, "9.9", "https://evil.url/text"), rec.Body.String())
+}
+
+func TestHandleCorporateSignatureMissingCompany(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathCorporateSignature, "", map[string]string{})
+
+	assert.NoError(t, handleCorporateSignature(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingQueryParam, queryParameterCompany), rec.Body.String())
+}
+
+func TestHandleCorporateSignatureNotSigned(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathCorporateSignature, "", map[string]string{
+		queryParameterCompany:    "myCompany",
+		queryParameterCLAVersion: "myCLAVersion",
+	})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCorporateSignature)).
+		WithArgs("myCompany", "myCLAVersion").
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}))
+
+	assert.NoError(t, handleCorporateSignature(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Equal(t, "cla version myCLAVersion not signed by company myCompany", rec.Body.String())
+}
+
+func TestHandleCorporateMemberMissingValue(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCorporateSignature, `{"type":"login"}`, map[string]string{})
+
+	assert.NoError(t, handleCorporateMember(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "value"), rec.Body.String())
+}
+
+func TestHandleCorporateMemberInvalidType(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodDelete, pathCorporateSignature, `{"type":"bogus","value":"myValue"}`, map[string]string{})
+
+	_, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	assert.NoError(t, handleCorporateMember(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, "invalid corporate member type: bogus", rec.Body.String())
+}
+
+func TestHandleCorporateSignatureActiveInvalidValue(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCorporateSignature, "", map[string]string{queryParameterActive: "maybe"})
+
+	assert.NoError(t, handleCorporateSignatureActive(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, `strconv.ParseBool: parsing "maybe": invalid syntax`, rec.Body.String())
+}
+
+func TestHandleRevokeSignatureMissingReason(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathRevokeSignature, `{"login":"myLogin","claVersion":"myCLAVersion"}`, map[string]string{})
+
+	assert.NoError(t, handleRevokeSignature(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "reason"), rec.Body.String())
+}
+
+func TestHandleRevokeSignatureNotFound(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathRevokeSignature, `{"login":"myLogin","claVersion":"myCLAVersion","reason":"myReason"}`, map[string]string{})
+	c.Request().SetBasicAuth("myInfoUser", "myInfoPassword")
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectExec("UPDATE signatures").
+		WithArgs("myLogin", "myCLAVersion", db.AnyTime{}, "myInfoUser", "myReason").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, handleRevokeSignature(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, "no active signature to revoke. login: myLogin, claVersion: myCLAVersion", rec.Body.String())
+}
+
+func TestHandleRevokeSignature(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathRevokeSignature, `{"login":"myLogin","claVersion":"myCLAVersion","reason":"myReason"}`, map[string]string{})
+	c.Request().SetBasicAuth("myInfoUser", "myInfoPassword")
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectExec("UPDATE signatures").
+		WithArgs("myLogin", "myCLAVersion", db.AnyTime{}, "myInfoUser", "myReason").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	origGHAppIDEnvVar := os.Getenv(ourGithub.EnvGhAppId)
+	defer func() {
+		resetEnvVariable(t, ourGithub.EnvGhAppId, origGHAppIDEnvVar)
+	}()
+	// the review of the open PRs of the author fails in the background, after the response
+	assert.NoError(t, os.Setenv(ourGithub.EnvGhAppId, "notAnAppId"))
+
+	assert.NoError(t, handleRevokeSignature(c))
+	backgroundJobs.Wait()
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Contains(t, rec.Body.String(), `"revokedBy":"myInfoUser"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleCLAVersionExpiryMissingCLAVersion(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCLAVersionExpiry, `{}`, map[string]string{})
+
+	assert.NoError(t, handleCLAVersionExpiry(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "claVersion"), rec.Body.String())
+}
+
+func TestHandleCLAVersionExpiryClear(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCLAVersionExpiry, `{"claVersion":"myCLAVersion"}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectExec("DELETE FROM cla_version_expiry").
+		WithArgs("myCLAVersion").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, handleCLAVersionExpiry(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Equal(t, `{"claVersion":"myCLAVersion","expiresAt":null}`+"\n", rec.Body.String())
+}
+
+func setupMockContextCLADocument(t *testing.T, path string, queryParams map[string]string) (c echo.Context, rec *httptest.ResponseRecorder) {
+	logger = zaptest.NewLogger(t)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	q := req.URL.Query()
+	for k, v := range queryParams {
+		q.Add(k, v)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(req, rec)
+	return
+}
+
+func TestHandleRetrieveCLATextMappedDocument(t *testing.T) {
+	c, rec := setupMockContextCLADocument(t, pathClaText, map[string]string{
+		ourGithub.QueryParameterOwner: "myOwner",
+		ourGithub.QueryParameterRepo:  "myRepo",
+	})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentForRepo)).
+		WithArgs("myOwner", "myRepo", db.AnyTime{}).
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "ClaDocumentId", "ClaTextSha256", "ClaText"}).
+			AddRow("icla", "3.0", "", time.Now(), 3, "someHash", "the stored cla text"))
+
+	assert.NoError(t, handleRetrieveCLAText(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Equal(t, "the stored cla text", rec.Body.String())
+}
+
+func TestHandleRetrieveCLADocumentDefault(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	origClaUrl := os.Getenv(envClsUrl)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+		resetEnvVariable(t, envClsUrl, origClaUrl)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	assert.NoError(t, os.Setenv(envClsUrl, "https://my.url/text"))
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	// the repo is not mapped to a document, so the default document applies
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentForRepo)).
+		WithArgs("myOwner", "", db.AnyTime{}).
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId"}))
+
+	c, rec := setupMockContextCLADocument(t, pathCLADocument, map[string]string{ourGithub.QueryParameterOwner: "myOwner"})
+
+	assert.NoError(t, handleRetrieveCLADocument(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Equal(t, `{"id":"","claVersion":"2.0","claTextUrl":"https://my.url/text","effectiveAt":"0001-01-01T00:00:00Z"}`+"\n", rec.Body.String())
+}
+
+func TestHandleRetrieveCLADocumentByVersion(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	// the version of the repo config selects the document, whatever document the repo is mapped to
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentsByVersion)).
+		WithArgs("3.0").
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "Id", "ClaTextSha256", "ClaText"}).
+			AddRow("icla", "3.0", "https://my.url/icla", time.Time{}, 3, "someHash", "the stored cla text"))
+
+	c, rec := setupMockContextCLADocument(t, pathCLADocument, map[string]string{
+		ourGithub.QueryParameterOwner:      "myOwner",
+		ourGithub.QueryParameterRepo:       "myRepo",
+		ourGithub.QueryParameterCLAVersion: "3.0",
+	})
+
+	assert.NoError(t, handleRetrieveCLADocument(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Contains(t, rec.Body.String(), `"id":"icla","claVersion":"3.0","claTextUrl":"https://my.url/icla"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleRetrieveCLADocumentVersionNotPublished(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentsByVersion)).
+		WithArgs("9.9").
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "Id", "ClaTextSha256", "ClaText"}))
+
+	c, rec := setupMockContextCLADocument(t, pathCLADocument, map[string]string{ourGithub.QueryParameterCLAVersion: "9.9"})
+
+	assert.NoError(t, handleRetrieveCLADocument(c))
+	assert.Equal(t, http.StatusNotFound, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateCLAVersionUnknown, "9.9"), rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleCLADocumentMissingText(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCLADocument, `{"id":"icla","claVersion":"3.0"}`, map[string]string{})
+
+	assert.NoError(t, handleCLADocument(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "claTextUrl or claText"), rec.Body.String())
+}
+
+func TestHandleCLADocument(t *testing.T) {
+	c, _ := setupMockContextInfo(t, http.MethodPut, pathCLADocument, `{"id":"icla","claVersion":"3.0","claTextUrl":"https://my.url/text"}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentsByVersion)).
+		WithArgs("3.0").
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "Id", "ClaTextSha256", "ClaText"}).
+			AddRow("icla", "3.0", "https://my.url/old", time.Now(), 0, "", ""))
+	mock.ExpectExec("INSERT INTO cla_catalog").
+		WithArgs("icla", "3.0", "https://my.url/text", nil, db.AnyTime{}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, handleCLADocument(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleCLADocumentVersionOfOtherDocument(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCLADocument, `{"id":"ccla","claVersion":"3.0","claTextUrl":"https://my.url/text"}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentsByVersion)).
+		WithArgs("3.0").
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "Id", "ClaTextSha256", "ClaText"}).
+			AddRow("icla", "3.0", "https://my.url/icla", time.Now(), 0, "", ""))
+
+	assert.NoError(t, handleCLADocument(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateCLAVersionPublished, "3.0", `CLA document "icla"`), rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleCLADocumentDefaultVersion(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	defer func() {
+		resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+	}()
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "3.0"))
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCLADocument, `{"id":"icla","claVersion":"3.0","claTextUrl":"https://my.url/text"}`, map[string]string{})
+
+	assert.NoError(t, handleCLADocument(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateCLAVersionPublished, "3.0", "the default CLA document"), rec.Body.String())
+}
+
+func TestHandleCLADocumentMappingMissingDocumentId(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathCLADocumentMapping, `{"repoOwner":"myOwner"}`, map[string]string{})
+
+	assert.NoError(t, handleCLADocumentMapping(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "documentId"), rec.Body.String())
+}
+
+func TestHandleCLADocumentMappingDeleteNotFound(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodDelete, pathCLADocumentMapping, `{"repoOwner":"MyOwner","repoName":"MyRepo"}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectExec("DELETE FROM cla_document_mappings").
+		WithArgs("myowner", "myrepo").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, handleCLADocumentMapping(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, "cla document mapping not found. owner: myowner, repo: myrepo", rec.Body.String())
+}
+
+// setupMockSendMail configures SMTP, and collects the messages sent instead of sending them
+func setupMockSendMail(t *testing.T, sendErr error) (sent map[string]string, reset func()) {
+	origSmtpHost := os.Getenv(envSmtpHost)
+	origSmtpPort := os.Getenv(envSmtpPort)
+	assert.NoError(t, os.Setenv(envSmtpHost, "localhost"))
+	assert.NoError(t, os.Setenv(envSmtpPort, "2525"))
+	origSendMail := sendMail
+	sent = map[string]string{}
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Equal(t, "localhost:2525", addr)
+		assert.Equal(t, emailFrom, from)
+		sent[to[0]] = string(msg)
+		return sendErr
+	}
+	reset = func() {
+		sendMail = origSendMail
+		resetEnvVariable(t, envSmtpHost, origSmtpHost)
+		resetEnvVariable(t, envSmtpPort, origSmtpPort)
+	}
+	return
+}
+
+// setupMockGHApp mocks the GitHub app, which has the external url of the signing page
+func setupMockGHApp(t *testing.T) (reset func()) {
+	origGHAppIDEnvVar := os.Getenv(ourGithub.EnvGhAppId)
+	assert.NoError(t, os.Setenv(ourGithub.EnvGhAppId, "-1"))
+	resetPemFileImpl := ourGithub.SetupTestPemFile(t)
+	resetGHJWTImpl := ourGithub.SetupMockGHJWTApp("https://the-cla.example.com/")
+	return func() {
+		resetGHJWTImpl()
+		resetPemFileImpl()
+		resetEnvVariable(t, ourGithub.EnvGhAppId, origGHAppIDEnvVar)
+	}
+}
+
+func TestHandleStartResignCampaignSmtpNotConfigured(t *testing.T) {
+	origSmtpHost := os.Getenv(envSmtpHost)
+	defer resetEnvVariable(t, envSmtpHost, origSmtpHost)
+	assert.NoError(t, os.Unsetenv(envSmtpHost))
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{}`, map[string]string{})
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusInternalServerError, c.Response().Status)
+	assert.Equal(t, msgSmtpNotConfigured, rec.Body.String())
+}
+
+func TestHandleStartResignCampaignCandidatesError(t *testing.T) {
+	_, reset := setupMockSendMail(t, nil)
+	defer reset()
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	forcedError := fmt.Errorf("forced candidates error")
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCandidates)).
+		WillReturnError(forcedError)
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusInternalServerError, c.Response().Status)
+	assert.Equal(t, forcedError.Error(), rec.Body.String())
+}
+
+func TestHandleStartResignCampaign(t *testing.T) {
+	resetGHApp := setupMockGHApp(t)
+	defer resetGHApp()
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	defer resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	sent, reset := setupMockSendMail(t, nil)
+	defer reset()
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{"activeSince":"2022-01-01T00:00:00Z"}`, map[string]string{})
+	c.Request().SetBasicAuth("myInfoUser", "myInfoPassword")
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	activeSince := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCandidates)).
+		WithArgs("2.0", activeSince, "").
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "ClaVersion"}).
+			AddRow("jane", "jane@doe.tld\r\nBcc: all@doe.tld", 0, "1.1").
+			AddRow("jane", "jane@doe.tld", 0, "1.0").
+			AddRow("john", "john@doe.tld", 42, "1.0").
+			// signed a newer version than the campaign asks for
+			AddRow("sam", "sam@doe.tld", 7, "1.0").
+			AddRow("sam", "sam@doe.tld", 7, "3.0"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO resign_campaigns").
+		WithArgs("", "2.0", activeSince, "myInfoUser", db.AnyTime{}).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}).AddRow("myCampaignId"))
+	mock.ExpectExec("INSERT INTO resign_campaign_contacts").
+		WithArgs("myCampaignId", "jane", nil, "jane@doe.tld\r\nBcc: all@doe.tld", "1.1", nil, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO resign_campaign_contacts").
+		WithArgs("myCampaignId", "john", 42, "john@doe.tld", "1.0", nil, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE resign_campaign_contacts").
+		WithArgs("myCampaignId", "jane", nil, `invalid email address: "jane@doe.tld\r\nBcc: all@doe.tld"`).
+		// failing to record a contact does not stop the campaign
+		WillReturnError(fmt.Errorf("forced update contact error"))
+	mock.ExpectExec("UPDATE resign_campaign_contacts").
+		WithArgs("myCampaignId", "john", db.AnyTime{}, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusCreated, c.Response().Status)
+
+	// the emails are sent after the campaign is returned
+	var campaign types.ResignCampaign
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &campaign))
+	assert.Equal(t, "myCampaignId", campaign.Id)
+	assert.Equal(t, "2.0", campaign.CLAVersion)
+	assert.Equal(t, "myInfoUser", campaign.StartedBy)
+	assert.Equal(t, 2, len(campaign.Contacts))
+	assert.Nil(t, campaign.Contacts[0].ContactedAt)
+	assert.Nil(t, campaign.Contacts[1].ContactedAt)
+
+	backgroundJobs.Wait()
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1, len(sent))
+	assert.Contains(t, sent["john@doe.tld"], "Subject: Please sign version 2.0 of the Contributor License Agreement\r\n")
+	assert.Contains(t, sent["john@doe.tld"], "You signed version 1.0 of the Contributor License Agreement (CLA).")
+	// the link selects the document of the campaign on the signing page of the app, not the host of the request
+	assert.Contains(t, sent["john@doe.tld"], "https://the-cla.example.com/?claversion=2.0")
+	assert.NotContains(t, sent["john@doe.tld"], "http://example.com/")
+}
+
+func TestHandleStartResignCampaignAppError(t *testing.T) {
+	origGHAppIDEnvVar := os.Getenv(ourGithub.EnvGhAppId)
+	defer resetEnvVariable(t, ourGithub.EnvGhAppId, origGHAppIDEnvVar)
+	assert.NoError(t, os.Setenv(ourGithub.EnvGhAppId, "notAnAppId"))
+	sent, reset := setupMockSendMail(t, nil)
+	defer reset()
+
+	c, _ := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	// without the link to sign, the campaign is not started
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCandidates)).
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "ClaVersion"}).
+			AddRow("john", "john@doe.tld", 42, "1.0"))
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusInternalServerError, c.Response().Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 0, len(sent))
+}
+
+func TestHandleStartResignCampaignSendError(t *testing.T) {
+	resetGHApp := setupMockGHApp(t)
+	defer resetGHApp()
+	_, reset := setupMockSendMail(t, fmt.Errorf("forced send error"))
+	defer reset()
+
+	c, _ := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCandidates)).
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "ClaVersion"}).
+			AddRow("john", "john@doe.tld", 42, "1.0"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO resign_campaigns").
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}).AddRow("myCampaignId"))
+	mock.ExpectExec("INSERT INTO resign_campaign_contacts").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("UPDATE resign_campaign_contacts").
+		WithArgs("myCampaignId", "john", nil, "forced send error").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusCreated, c.Response().Status)
+	backgroundJobs.Wait()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleStartResignCampaignInsertError(t *testing.T) {
+	resetGHApp := setupMockGHApp(t)
+	defer resetGHApp()
+	sent, reset := setupMockSendMail(t, nil)
+	defer reset()
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCandidates)).
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "ClaVersion"}).
+			AddRow("john", "john@doe.tld", 42, "1.0"))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO resign_campaigns").
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}).AddRow("myCampaignId"))
+	forcedError := fmt.Errorf("forced insert contact error")
+	mock.ExpectExec("INSERT INTO resign_campaign_contacts").
+		WillReturnError(forcedError)
+	mock.ExpectRollback()
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, forcedError.Error(), rec.Body.String())
+	backgroundJobs.Wait()
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 0, len(sent))
+}
+
+func TestHandleStartResignCampaignVersionNotPublished(t *testing.T) {
+	origClaVersion := os.Getenv(envReactAppClaVersion)
+	defer resetEnvVariable(t, envReactAppClaVersion, origClaVersion)
+	assert.NoError(t, os.Setenv(envReactAppClaVersion, "2.0"))
+	_, reset := setupMockSendMail(t, nil)
+	defer reset()
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{"claVersion":"3.0"}`, map[string]string{})
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateResignCampaignDocumentNotFound, "", "3.0"), rec.Body.String())
+}
+
+func TestHandleStartResignCampaignDocumentWithoutVersion(t *testing.T) {
+	_, reset := setupMockSendMail(t, nil)
+	defer reset()
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{"documentId":"icla"}`, map[string]string{})
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingField, "claVersion"), rec.Body.String())
+}
+
+func TestHandleStartResignCampaignCatalogDocument(t *testing.T) {
+	resetGHApp := setupMockGHApp(t)
+	defer resetGHApp()
+	_, reset := setupMockSendMail(t, nil)
+	defer reset()
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign,
+		`{"documentId":"icla","claVersion":"3.0","activeSince":"2022-01-01T00:00:00Z"}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	now := time.Now()
+	activeSince := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentsByVersion)).
+		WithArgs("3.0").
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "Id", "ClaTextSha256", "ClaText"}).
+			AddRow("ccla", "3.0", "https://my.url/ccla", now, 0, "", "").
+			AddRow("icla", "3.0", "https://my.url/icla", now, 0, "", ""))
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCandidates)).
+		WithArgs("3.0", activeSince, "icla").
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "ClaVersion"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO resign_campaigns").
+		WithArgs("icla", "3.0", activeSince, "", db.AnyTime{}).
+		WillReturnRows(sqlmock.NewRows([]string{"Id"}).AddRow("myCampaignId"))
+	mock.ExpectCommit()
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusCreated, c.Response().Status)
+	assert.Contains(t, rec.Body.String(), `"documentId":"icla"`)
+	backgroundJobs.Wait()
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleStartResignCampaignCatalogDocumentNotFound(t *testing.T) {
+	_, reset := setupMockSendMail(t, nil)
+	defer reset()
+
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathResignCampaign, `{"documentId":"icla","claVersion":"3.0"}`, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectCLADocumentsByVersion)).
+		WithArgs("3.0").
+		WillReturnRows(sqlmock.NewRows([]string{"DocumentId", "ClaVersion", "ClaTextUrl", "EffectiveAt", "Id", "ClaTextSha256", "ClaText"}).
+			AddRow("ccla", "3.0", "https://my.url/ccla", time.Now(), 0, "", ""))
+
+	assert.NoError(t, handleStartResignCampaign(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateResignCampaignDocumentNotFound, "icla", "3.0"), rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleResignCampaignMissingId(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathResignCampaign, ``, map[string]string{})
+
+	assert.NoError(t, handleResignCampaign(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateMissingQueryParam, queryParameterId), rec.Body.String())
+}
+
+func TestHandleResignCampaignNotFound(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathResignCampaign, ``, map[string]string{queryParameterId: "myCampaignId"})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCampaign)).
+		WithArgs("myCampaignId").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "DocumentId", "ClaVersion", "ActiveSince", "StartedBy", "StartedAt"}))
+
+	assert.NoError(t, handleResignCampaign(c))
+	assert.Equal(t, http.StatusNotFound, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateResignCampaignNotFound, "myCampaignId"), rec.Body.String())
+}
+
+func TestHandleResignCampaign(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathResignCampaign, ``, map[string]string{queryParameterId: "myCampaignId"})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	now := time.Now()
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignCampaign)).
+		WithArgs("myCampaignId").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "DocumentId", "ClaVersion", "ActiveSince", "StartedBy", "StartedAt"}).
+			AddRow("myCampaignId", "", "2.0", now, "myInfoUser", now))
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlSelectResignContacts)).
+		WithArgs("myCampaignId").
+		WillReturnRows(sqlmock.NewRows([]string{"LoginName", "Email", "GitHubUserId", "SignedClaVersion", "ContactedAt", "Error", "ResignedAt"}).
+			AddRow("john", "john@doe.tld", 42, "1.0", now, "", now).
+			AddRow("jane", "jane@doe.tld", 0, "1.1", now, "", nil))
+
+	assert.NoError(t, handleResignCampaign(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+
+	var campaign types.ResignCampaign
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &campaign))
+	assert.Equal(t, 2, len(campaign.Contacts))
+	assert.NotNil(t, campaign.Contacts[0].ResignedAt)
+	assert.Nil(t, campaign.Contacts[1].ResignedAt)
+}
+
+func TestHandleSignaturesInvalidSort(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignatures, ``, map[string]string{queryParameterSort: "-email"})
+
+	assert.NoError(t, handleSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterSort, "-email"), rec.Body.String())
+}
+
+func TestHandleSignaturesInvalidLimit(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignatures, ``, map[string]string{queryParameterLimit: "1001"})
+
+	assert.NoError(t, handleSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterLimit, "1001"), rec.Body.String())
+}
+
+func TestHandleSignaturesInvalidTime(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignatures, ``, map[string]string{queryParameterSignedBefore: "yesterday"})
+
+	assert.NoError(t, handleSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterSignedBefore, "yesterday"), rec.Body.String())
+}
+
+func TestHandleSignaturesInvalidCursor(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignatures, ``, map[string]string{queryParameterCursor: "myCursor"})
+
+	_, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	assert.NoError(t, handleSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterCursor, "myCursor"), rec.Body.String())
+}
+
+func TestHandleSignatures(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignatures, ``, map[string]string{
+		queryParameterCLAVersion:  "2.0",
+		queryParameterSignedAfter: "2022-01-01T00:00:00Z",
+		queryParameterLoginPrefix: "jo",
+		queryParameterSort:        "-login",
+		queryParameterLimit:       "1",
+	})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	signedAfter := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT signatures.Id").
+		WithArgs("2.0", signedAfter, nil, "jo", "", false, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaDocumentId", "ClaTextSha256", "EmailVerified"}).
+			AddRow("id2", "joe", "joe@doe.tld", "Joe", time.Now(), "2.0", "", 0, "", true).
+			AddRow("id1", "john", "john@doe.tld", "John", time.Now(), "2.0", "", 0, "", true))
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlCountSignatures)).
+		WithArgs("2.0", signedAfter, nil, "jo", "", false).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	assert.NoError(t, handleSignatures(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+
+	var page types.SignaturePage
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	assert.Equal(t, int64(2), page.TotalCount)
+	assert.Equal(t, 1, len(page.Signatures))
+	assert.Equal(t, "joe", page.Signatures[0].User.Login)
+	assert.NotEmpty(t, page.NextCursor)
+}
+
+func TestHandleSignaturesCountError(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignatures, ``, map[string]string{})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery("SELECT signatures.Id").
+		WillReturnRows(sqlmock.NewRows([]string{"Id", "LoginName", "Email", "GivenName", "SignedAt", "ClaVersion", "ClaTextUrl", "ClaDocumentId", "ClaTextSha256", "EmailVerified"}))
+	forcedError := fmt.Errorf("forced count error")
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlCountSignatures)).
+		WillReturnError(forcedError)
+
+	assert.NoError(t, handleSignatures(c))
+	assert.Equal(t, http.StatusInternalServerError, c.Response().Status)
+	assert.Equal(t, forcedError.Error(), rec.Body.String())
+}
+
+func TestHandleExportSignaturesInvalidFormat(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignaturesExport, ``, map[string]string{queryParameterFormat: "xml"})
+
+	assert.NoError(t, handleExportSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, "invalid export format: xml", rec.Body.String())
+}
+
+func TestHandleExportSignatures(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignaturesExport, ``, map[string]string{
+		queryParameterEmailDomain: "doe.tld",
+		queryParameterExcludeText: "true",
+		queryParameterColumns:     "login,email,claVersion,signedAt,revokedAt",
+	})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	signedAt := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	revokedAt := time.Date(2022, 2, 3, 4, 5, 6, 0, time.UTC)
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlExportSignatures)).
+		WithArgs("", nil, nil, "", "doe.tld", true, false).
+		WillReturnRows(sqlmock.NewRows(exportRowColumns).
+			AddRow("id1", "john", "john@doe.tld", "John", signedAt, "1.0", "", "", 0, "", true, "", "", 0, revokedAt).
+			AddRow("id2", "john", "john@doe.tld", "John", signedAt, "2.0", "", "", 0, "", true, "", "", 0, nil))
+
+	assert.NoError(t, handleExportSignatures(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "attachment; filename=signatures.csv", rec.Header().Get(echo.HeaderContentDisposition))
+	assert.Equal(t, "login,email,claVersion,signedAt,revokedAt\n"+
+		"john,john@doe.tld,1.0,2022-01-02T03:04:05Z,2022-02-03T04:05:06Z\n"+
+		"john,john@doe.tld,2.0,2022-01-02T03:04:05Z,\n", rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleExportSignaturesExcludeRevoked(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignaturesExport, ``, map[string]string{
+		queryParameterIncludeRevoked: "false",
+		queryParameterColumns:        "login",
+	})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectQuery(db.ConvertSqlToDbMockExpect(db.SqlExportSignatures)).
+		WithArgs("", nil, nil, "", "", false, false).
+		WillReturnRows(sqlmock.NewRows(exportRowColumns).
+			AddRow("id2", "john", "john@doe.tld", "John", time.Now(), "2.0", "", "", 0, "", true, "", "", 0, nil))
+
+	assert.NoError(t, handleExportSignatures(c))
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Equal(t, "login\njohn\n", rec.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandleExportSignaturesInvalidIncludeRevoked(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodGet, pathSignaturesExport, ``, map[string]string{queryParameterIncludeRevoked: "maybe"})
+
+	assert.NoError(t, handleExportSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateInvalidQueryParam, queryParameterIncludeRevoked, "maybe"), rec.Body.String())
+}
+
+func TestHandleImportSignaturesInvalidDryRun(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathSignaturesImport, ``, map[string]string{queryParameterDryRun: "yes"})
+
+	assert.NoError(t, handleImportSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, "invalid query parameter dryrun: yes", rec.Body.String())
+}
+
+func TestHandleImportSignaturesInvalidBatchSize(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathSignaturesImport, ``, map[string]string{queryParameterBatchSize: "0"})
+
+	assert.NoError(t, handleImportSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, "invalid query parameter batchsize: 0", rec.Body.String())
+}
+
+func TestHandleImportSignaturesInvalidHeader(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathSignaturesImport, "user,version\n", nil)
+
+	assert.NoError(t, handleImportSignatures(c))
+	assert.Equal(t, http.StatusUnprocessableEntity, c.Response().Status)
+	assert.Equal(t, "invalid import column: user", rec.Body.String())
+}
+
+func TestHandleImportSignaturesTooLarge(t *testing.T) {
+	// white space before the first JSON value is read without being kept in memory
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathSignaturesImport, strings.Repeat(" ", maxImportBodySize+1),
+		map[string]string{queryParameterFormat: importFormatJSON})
+
+	assert.NoError(t, handleImportSignatures(c))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, c.Response().Status)
+	assert.Equal(t, fmt.Sprintf(msgTemplateImportTooLarge, maxImportBodySize), rec.Body.String())
+}
+
+func TestHandleImportSignaturesDryRun(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathSignaturesImport,
+		`[{"login": "john", "claVersion": "1.0", "signedAt": "2020-01-02T03:04:05Z"}, {"login": "jane", "claVersion": "1.0"}]`,
+		map[string]string{queryParameterFormat: importFormatJSON, queryParameterDryRun: "true"})
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	mock.ExpectBegin()
+	mock.ExpectExec(db.ConvertSqlToDbMockExpect(db.SqlImportSignature)).
+		WithArgs("john", "", "", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), "1.0", "", nil, nil, false, "", "", nil).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	assert.NoError(t, handleImportSignatures(c))
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, http.StatusOK, c.Response().Status)
+	assert.Equal(t, `{"dryRun":true,"rows":2,"imported":0,"conflicts":1,"invalid":1,"problems":[`+
+		`{"row":1,"login":"john","claVersion":"1.0","status":"conflict","error":"john already signed CLA version 1.0"},`+
+		`{"row":2,"login":"jane","claVersion":"1.0","status":"invalid","error":"missing required field: signedAt"}]}`+"\n",
+		rec.Body.String())
+}
+
+func TestHandleImportSignaturesError(t *testing.T) {
+	c, rec := setupMockContextInfo(t, http.MethodPut, pathSignaturesImport,
+		"login,claVersion,signedAt\njohn,1.0,2020-01-02T03:04:05Z\n", nil)
+
+	mock, dbIF, closeDbFunc := db.SetupMockDB(t)
+	defer closeDbFunc()
+	postgresDB = dbIF
+
+	forcedError := fmt.Errorf("forced begin error")
+	mock.ExpectBegin().WillReturnError(forcedError)
+
+	assert.NoError(t, handleImportSignatures(c))
+	assert.Equal(t, http.StatusBadRequest, c.Response().Status)
+	assert.Equal(t, `{"dryRun":false,"rows":1,"imported":0,"conflicts":0,"invalid":0,"problems":[],"error":"forced begin error"}`+"\n", rec.Body.String())
+}
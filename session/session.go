@@ -0,0 +1,189 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Session identifies the GitHub user that logged in via OAuth
+type Session struct {
+	Login  string `json:"login"`
+	UserId int64  `json:"userId"`
+	// VerifiedEmails are the emails GitHub verified belong to the user
+	VerifiedEmails []string  `json:"verifiedEmails,omitempty"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+}
+
+// IsVerifiedEmail returns true if GitHub verified the email belongs to the user
+func (s *Session) IsVerifiedEmail(email string) bool {
+	for _, verifiedEmail := range s.VerifiedEmails {
+		if strings.EqualFold(verifiedEmail, email) {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrInvalidToken = errors.New("invalid session token")
+var ErrExpiredToken = errors.New("expired session token")
+var ErrStateMismatch = errors.New("oauth state does not match")
+
+// the purpose of a token is signed with its payload, so a token of one kind can not be used as another
+const purposeSession = "session"
+const purposeOAuthState = "oauthState"
+
+// sessionToken is the payload of a session token
+type sessionToken struct {
+	Purpose string `json:"purpose"`
+	Session
+}
+
+// oauthState is the random state of an OAuth login flow, held by a token until the OAuth provider returns the state
+type oauthState struct {
+	Purpose   string    `json:"purpose"`
+	State     string    `json:"state"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Manager creates and verifies tokens signed with a secret key, so a client can hold the session (e.g. in a cookie)
+// without being able to change it.
+type Manager struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewManager(key []byte, ttl time.Duration) *Manager {
+	return &Manager{key: key, ttl: ttl}
+}
+
+// NewRandomKey returns a key for a Manager. Tokens signed with a random key are not valid after a restart, or on
+// another instance of the server.
+func NewRandomKey() (key []byte, err error) {
+	key = make([]byte, 32)
+	_, err = rand.Read(key)
+	return
+}
+
+// Create returns a new session (with its token) for the given user, valid from now until the ttl of the Manager expires
+func (m *Manager) Create(login string, userId int64, verifiedEmails []string, now time.Time) (session *Session, token string, err error) {
+	session = &Session{Login: login, UserId: userId, VerifiedEmails: verifiedEmails, ExpiresAt: now.Add(m.ttl)}
+	payload, err := json.Marshal(sessionToken{Purpose: purposeSession, Session: *session})
+	if err != nil {
+		return
+	}
+	token = m.Sign(payload)
+	return
+}
+
+// Read returns the session of a session token created by this Manager, if the token is not expired at the given time
+func (m *Manager) Read(token string, now time.Time) (session *Session, err error) {
+	payload, err := m.Verify(token)
+	if err != nil {
+		return
+	}
+	found := &sessionToken{}
+	if err = json.Unmarshal(payload, found); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if found.Purpose != purposeSession || found.Login == "" {
+		return nil, ErrInvalidToken
+	}
+	if !now.Before(found.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+	session = &found.Session
+	return
+}
+
+// CreateState returns a random state for an OAuth login flow, and a token holding the state until the ttl expires
+func (m *Manager) CreateState(now time.Time, ttl time.Duration) (state, token string, err error) {
+	random := make([]byte, 32)
+	if _, err = rand.Read(random); err != nil {
+		return
+	}
+	state = base64.RawURLEncoding.EncodeToString(random)
+	payload, err := json.Marshal(oauthState{Purpose: purposeOAuthState, State: state, ExpiresAt: now.Add(ttl)})
+	if err != nil {
+		return "", "", err
+	}
+	token = m.Sign(payload)
+	return
+}
+
+// VerifyState returns an error unless the state returned by the OAuth provider is the one held by a token created by
+// this Manager, and the token is not expired at the given time
+func (m *Manager) VerifyState(token, state string, now time.Time) (err error) {
+	payload, err := m.Verify(token)
+	if err != nil {
+		return
+	}
+	found := oauthState{}
+	if err = json.Unmarshal(payload, &found); err != nil {
+		return ErrInvalidToken
+	}
+	if found.Purpose != purposeOAuthState {
+		return ErrInvalidToken
+	}
+	if !now.Before(found.ExpiresAt) {
+		return ErrExpiredToken
+	}
+	if found.State == "" || !hmac.Equal([]byte(found.State), []byte(state)) {
+		return ErrStateMismatch
+	}
+	return
+}
+
+// Sign returns a token holding the payload and its signature
+func (m *Manager) Sign(payload []byte) string {
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(m.mac(payload))
+}
+
+// Verify returns the payload of a token, if the token was signed by this Manager
+func (m *Manager) Verify(token string) (payload []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, ErrInvalidToken
+	}
+	if payload, err = base64.RawURLEncoding.DecodeString(parts[0]); err != nil {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal(signature, m.mac(payload)) {
+		return nil, ErrInvalidToken
+	}
+	return
+}
+
+func (m *Manager) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,167 @@
+//
+// Copyright (c) 2021-present Sonatype, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build go1.16
+// +build go1.16
+
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateAndRead(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	created, token, err := m.Create("myLogin", 42, []string{"me@example.com"}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "myLogin", created.Login)
+	assert.Equal(t, int64(42), created.UserId)
+
+	read, err := m.Read(token, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, "myLogin", read.Login)
+	assert.Equal(t, int64(42), read.UserId)
+	assert.Equal(t, []string{"me@example.com"}, read.VerifiedEmails)
+	assert.True(t, created.ExpiresAt.Equal(read.ExpiresAt))
+}
+
+func TestIsVerifiedEmail(t *testing.T) {
+	session := &Session{VerifiedEmails: []string{"Me@Example.com", "other@example.com"}}
+	assert.True(t, session.IsVerifiedEmail("me@example.com"))
+	assert.True(t, session.IsVerifiedEmail("other@example.com"))
+	assert.False(t, session.IsVerifiedEmail("someone@example.com"))
+	assert.False(t, session.IsVerifiedEmail(""))
+	assert.False(t, (&Session{}).IsVerifiedEmail("me@example.com"))
+}
+
+func TestReadExpired(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	_, token, err := m.Create("myLogin", 42, nil, now)
+	assert.NoError(t, err)
+
+	session, err := m.Read(token, now.Add(time.Hour))
+	assert.Equal(t, ErrExpiredToken, err)
+	assert.Nil(t, session)
+}
+
+func TestReadOtherKey(t *testing.T) {
+	now := time.Now()
+	_, token, err := NewManager([]byte("otherKey"), time.Hour).Create("myLogin", 42, nil, now)
+	assert.NoError(t, err)
+
+	session, err := NewManager([]byte("myKey"), time.Hour).Read(token, now)
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.Nil(t, session)
+}
+
+func TestVerifyTampered(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	token := m.Sign([]byte(`{"login":"myLogin"}`))
+
+	payload, err := m.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"login":"myLogin"}`, string(payload))
+
+	tampered := m.Sign([]byte(`{"login":"otherLogin"}`))
+	_, err = m.Verify(tampered[:strings.Index(tampered, ".")] + token[strings.Index(token, "."):])
+	assert.Equal(t, ErrInvalidToken, err)
+
+	for _, invalid := range []string{"", "noSignature", "a.b.c", "!!!.!!!"} {
+		_, err = m.Verify(invalid)
+		assert.Equal(t, ErrInvalidToken, err, invalid)
+	}
+}
+
+func TestCreateAndVerifyState(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	state, token, err := m.CreateState(now, time.Minute)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "", state)
+	assert.NoError(t, m.VerifyState(token, state, now))
+
+	otherState, _, err := m.CreateState(now, time.Minute)
+	assert.NoError(t, err)
+	assert.NotEqual(t, state, otherState)
+	assert.Equal(t, ErrStateMismatch, m.VerifyState(token, otherState, now))
+	assert.Equal(t, ErrStateMismatch, m.VerifyState(token, "", now))
+}
+
+func TestVerifyStateExpired(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	state, token, err := m.CreateState(now, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrExpiredToken, m.VerifyState(token, state, now.Add(time.Minute)))
+}
+
+func TestVerifyStateOfSessionToken(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	_, token, err := m.Create("myLogin", 42, nil, now)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrInvalidToken, m.VerifyState(token, "", now))
+}
+
+func TestReadStateToken(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	_, token, err := m.CreateState(now, time.Minute)
+	assert.NoError(t, err)
+	session, err := m.Read(token, now)
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.Nil(t, session)
+}
+
+func TestReadWithoutPurpose(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	// a token signed before tokens had a purpose
+	session, err := m.Read(m.Sign([]byte(`{"login":"myLogin","userId":42,"expiresAt":"`+now.Add(time.Hour).Format(time.RFC3339)+`"}`)), now)
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.Nil(t, session)
+}
+
+func TestReadEmptyLogin(t *testing.T) {
+	m := NewManager([]byte("myKey"), time.Hour)
+	now := time.Now()
+
+	_, token, err := m.Create("", 42, nil, now)
+	assert.NoError(t, err)
+	session, err := m.Read(token, now)
+	assert.Equal(t, ErrInvalidToken, err)
+	assert.Nil(t, session)
+}
+
+func TestVerifyStateOtherKey(t *testing.T) {
+	now := time.Now()
+	state, token, err := NewManager([]byte("otherKey"), time.Hour).CreateState(now, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrInvalidToken, NewManager([]byte("myKey"), time.Hour).VerifyState(token, state, now))
+}
@@ -13,13 +13,14 @@
  * See the License for the specific language governing permissions and
  * limitations under the License.
  */
-import { NxButton, NxCheckbox, NxFieldset, NxFormGroup, NxLoadError, NxTextInput, nxTextInputStateHelpers, NxTooltip, useToggle } from "@sonatype/react-shared-components";
-import React, { FormEvent, useContext, useState } from "react";
+import { NxButton, NxCheckbox, NxFieldset, NxFormGroup, NxFormSelect, NxLoadError, NxTextInput, nxTextInputStateHelpers, NxTooltip, useToggle } from "@sonatype/react-shared-components";
+import React, { ChangeEvent, FormEvent, useContext, useState } from "react";
 import { Action, ClientContext } from "react-fetching-library";
 import classnames from 'classnames';
 import { none } from 'ramda';
 import { hasValidationErrors } from '@sonatype/react-shared-components/util/validationUtil';
 import CLABody from "../ClaBody/CLABody";
+import { getOriginalUri, getRepoQuery } from "../repoQuery";
 import { StateProps, Validator } from "@sonatype/react-shared-components/components/NxTextInput/types";
 import './Body.css';
 
@@ -27,6 +28,7 @@ type GitHubUser = {
   login: string
   email?: string
   name?: string
+  verifiedEmails?: string[]
 }
 
 type SignCla = {
@@ -64,7 +66,6 @@ const Body = () => {
     const [loggedIn, setLoggedIn] = useState(false),
           [scrolled, setScrolled] = useState(false),
           [username, setUsername] = useState(initialState('', validator)),
-          [ghState, setGHState] = useState<string>(""),
           [email, setEmail] = useState(initialState('', validator)),
           [fullName, setFullName] = useState(initialState('', validator)),
           [user, setUser] = useState<GitHubUser | undefined>(undefined),
@@ -86,15 +87,10 @@ const Body = () => {
     };
 
     const getGitHubAuthUrl = (): string => {
-      const urlParams = new URLSearchParams(window.location.search);
+      // keep the page to return to, the login redirect only carries the random state the server checks
+      getOriginalUri();
 
-      const originalUri = urlParams.get("original_uri");
-
-      const state: string = (originalUri) ? originalUri : process.env.REACT_APP_COMPANY_WEBSITE!;
-
-      const currentUrl = window.location.href.split('?')[0];
-
-      return `https://github.com/login/oauth/authorize?client_id=${process.env.REACT_APP_GITHUB_CLIENT_ID}&redirect_uri=${currentUrl}&scope=user:email&state=${state}`;
+      return '/oauth-login';
     }
 
     const getUser = async (search: string) => {
@@ -106,7 +102,7 @@ const Body = () => {
   
         const checkOAuthCode: Action = {
           method: 'GET',
-          endpoint: `/oauth-callback?code=${code}&state=${redirectState}`
+          endpoint: `/oauth-callback?code=${encodeURIComponent(code || "")}&state=${encodeURIComponent(redirectState || "")}`
         }
   
         const res = await clientContext.query(checkOAuthCode);
@@ -116,8 +112,6 @@ const Body = () => {
   
           setLoggedIn(true);
   
-          setGHState(redirectState!);
-  
           const user: GitHubUser = res.payload;
 
           setUsername({value: user.login, trimmedValue: user.login.trim(), isPristine: true});
@@ -133,19 +127,31 @@ const Body = () => {
       evt.preventDefault();
 
       if (isSubmittable) {  
+        const getClaDocument: Action = {
+          method: 'GET',
+          endpoint: `/cla-document${getRepoQuery()}`
+        }
+
+        const docRes = await clientContext.query(getClaDocument);
+
+        if (docRes.error) {
+          setQueryError({error: true, errorMessage: docRes.payload});
+          return;
+        }
+
         const signUser: SignCla = { 
           user: { 
             login: user!.login, 
             email: email.value,
             name: fullName.value
           }, 
-          claVersion: (process.env.REACT_APP_CLA_VERSION) ? process.env.REACT_APP_CLA_VERSION : "",
-          claTextUrl: (process.env.CLA_URL) ? process.env.CLA_URL : ""
+          claVersion: docRes.payload.claVersion,
+          claTextUrl: docRes.payload.claTextUrl
         };
   
         const putSignCla: Action = {
           method: 'PUT',
-          endpoint: '/sign-cla',
+          endpoint: `/sign-cla${getRepoQuery()}`,
           body: signUser,
           headers: {
             Accept: 'application/json',
@@ -155,8 +161,7 @@ const Body = () => {
         const res = await clientContext.query(putSignCla);
   
         if (!res.error) {
-          if (ghState !== "")
-          window.location.href = decodeURI(ghState);
+          window.location.href = getOriginalUri();
         } else {
           setQueryError({error: true, errorMessage: res.payload});
         }
@@ -239,14 +244,25 @@ const Body = () => {
 
             <NxFormGroup 
               label="Email Address" 
+              sublabel={user.verifiedEmails && user.verifiedEmails.length ? "One of your emails verified by GitHub" : undefined}
               isRequired={true}>
-              <NxTextInput
-                onChange={setTextInput(setEmail, nonEmptyValidator)} 
-                validatable={true}
-
-                value={email.value}
-                isPristine={email.isPristine}
-              />
+              { user.verifiedEmails && user.verifiedEmails.length ? (
+                <NxFormSelect
+                  onChange={(evt: ChangeEvent<HTMLSelectElement>) => setTextInput(setEmail, nonEmptyValidator)(evt.currentTarget.value)}
+                  value={email.value}>
+                  { user.verifiedEmails.map(verifiedEmail =>
+                    <option key={verifiedEmail} value={verifiedEmail}>{verifiedEmail}</option>
+                  )}
+                </NxFormSelect>
+              ) : (
+                <NxTextInput
+                  onChange={setTextInput(setEmail, nonEmptyValidator)} 
+                  validatable={true}
+
+                  value={email.value}
+                  isPristine={email.isPristine}
+                />
+              )}
             </NxFormGroup>
 
             <NxFormGroup 
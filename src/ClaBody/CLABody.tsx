@@ -16,11 +16,7 @@
 import React from 'react';
 import { NxLoadingSpinner } from '@sonatype/react-shared-components';
 import { Action, useQuery } from 'react-fetching-library';
-
-const fetchCLAText: Action = {
-  method: 'GET',
-  endpoint: '/cla-text'
-};
+import { getRepoQuery } from '../repoQuery';
 
 type CLABodyProps = {
   handleScroll: (event: any) => void;
@@ -28,6 +24,11 @@ type CLABodyProps = {
 
 const CLABody = (props: CLABodyProps) => {
 
+  const fetchCLAText: Action = {
+    method: 'GET',
+    endpoint: `/cla-text${getRepoQuery()}`
+  };
+
   const { loading, payload, error, errorObject } = useQuery(fetchCLAText);
 
   if (error) {
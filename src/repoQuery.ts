@@ -0,0 +1,53 @@
+/*
+ * Copyright (c) 2021-present Sonatype, Inc.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+const repoQueryKey = "claRepoQuery";
+
+/**
+ * Returns the query (e.g. "?owner=org&repo=project&claversion=2.0") selecting the CLA document of the repo a
+ * contributor came from. The GitHub login redirect drops the query of the page, so the query is kept for the rest of
+ * the browser session.
+ */
+export const getRepoQuery = (search: string = window.location.search): string => {
+  const urlParams = new URLSearchParams(search);
+  const owner = urlParams.get("owner");
+  const claVersion = urlParams.get("claversion");
+
+  if (owner || claVersion) {
+    const repoParams = new URLSearchParams({ owner: owner || "", repo: urlParams.get("repo") || "" });
+    if (claVersion) {
+      repoParams.set("claversion", claVersion);
+    }
+    window.sessionStorage.setItem(repoQueryKey, `?${repoParams.toString()}`);
+  }
+
+  return window.sessionStorage.getItem(repoQueryKey) || "";
+}
+
+const originalUriKey = "claOriginalUri";
+
+/**
+ * Returns the page (e.g. the pull request) a contributor is sent back to after signing the CLA. Like the repo query,
+ * the original_uri query parameter is kept for the rest of the browser session.
+ */
+export const getOriginalUri = (search: string = window.location.search): string => {
+  const originalUri = new URLSearchParams(search).get("original_uri");
+
+  if (originalUri) {
+    window.sessionStorage.setItem(originalUriKey, originalUri);
+  }
+
+  return window.sessionStorage.getItem(originalUriKey) || process.env.REACT_APP_COMPANY_WEBSITE!;
+}
@@ -28,11 +28,100 @@ type User struct {
 }
 
 type UserSignature struct {
-	User       User   `json:"user"`
+	// Id is only read by the admin list of signatures
+	Id            string `json:"id,omitempty"`
+	User          User   `json:"user"`
+	CLAVersion    string `json:"claVersion"`
+	TimeSigned    time.Time
+	CLATextUrl    string `json:"claTextUrl"`
+	CLAText       string
+	CLADocumentId int64  `json:"claDocumentId"`
+	CLATextSha256 string `json:"claTextSha256"`
+	// EmailVerified is true if GitHub verified the email of the signer belongs to the signer
+	EmailVerified bool `json:"emailVerified"`
+	// Evidence is only exposed by the authenticated /info endpoints
+	Evidence *SigningEvidence `json:"evidence,omitempty"`
+	// RevokedAt is only read by the export of signatures, nil if the signature is not revoked
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// SigningEvidence records how a signature was made, in case the signature is disputed
+type SigningEvidence struct {
+	IPAddress    string `json:"ipAddress"`
+	UserAgent    string `json:"userAgent"`
+	GitHubUserId int64  `json:"gitHubUserId"`
+}
+
+// SignatureRevocation describes the revocation of a previously stored signature
+type SignatureRevocation struct {
+	Login      string `json:"login"`
 	CLAVersion string `json:"claVersion"`
-	TimeSigned time.Time
-	CLATextUrl string `json:"claTextUrl"`
-	CLAText    string
+	Reason     string `json:"reason"`
+	RevokedBy  string `json:"revokedBy"`
+	RevokedAt  time.Time
+}
+
+// CLAVersionExpiry sets the date after which signatures of a CLA version are no longer accepted.
+// A nil ExpiresAt means signatures of the CLA version never expire.
+type CLAVersionExpiry struct {
+	CLAVersion string     `json:"claVersion"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+}
+
+// CLADocument is a version of a CLA document in the catalog. The text is either stored with the document, or
+// fetched from the CLATextUrl. A version applies from its EffectiveAt date until a later version becomes effective.
+type CLADocument struct {
+	Id               string    `json:"id"`
+	CLAVersion       string    `json:"claVersion"`
+	CLATextUrl       string    `json:"claTextUrl"`
+	CLAText          string    `json:"claText,omitempty"`
+	EffectiveAt      time.Time `json:"effectiveAt"`
+	StoredDocumentId int64     `json:"storedDocumentId,omitempty"`
+	CLATextSha256    string    `json:"claTextSha256,omitempty"`
+}
+
+// StoredCLADocument is the text of a CLA version, stored once and identified by the SHA-256 hash of the text, so a
+// signature can prove exactly which text was agreed to.
+type StoredCLADocument struct {
+	Id            int64     `json:"id"`
+	CLAVersion    string    `json:"claVersion"`
+	CLATextSha256 string    `json:"claTextSha256"`
+	CLATextUrl    string    `json:"claTextUrl"`
+	CLAText       string    `json:"-"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// CLADocumentMapping maps all repos of an owner (organization), or only the given repo, to a CLA document.
+type CLADocumentMapping struct {
+	RepoOwner  string `json:"repoOwner"`
+	RepoName   string `json:"repoName"`
+	DocumentId string `json:"documentId"`
+}
+
+const CorporateMemberTypeLogin = "login"
+const CorporateMemberTypeDomain = "domain"
+
+// CorporateMember is an entry on the allow-list of a corporate signature, either a GitHub login or an
+// email domain of the company.
+type CorporateMember struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// CorporateSignature is a CLA signed by an authorized signatory on behalf of a company (entity), covering
+// every contributor on its allow-list.
+type CorporateSignature struct {
+	Id            string `json:"id"`
+	Company       string `json:"company"`
+	Signatory     User   `json:"signatory"`
+	CLAVersion    string `json:"claVersion"`
+	TimeSigned    time.Time
+	CLATextUrl    string `json:"claTextUrl"`
+	CLAText       string
+	CLADocumentId int64             `json:"claDocumentId"`
+	CLATextSha256 string            `json:"claTextSha256"`
+	Active        bool              `json:"active"`
+	Members       []CorporateMember `json:"members"`
 }
 
 // EvaluationInfo holds all the stuff we need to (re)validate a PR/user has the CLA signed,
@@ -47,3 +136,92 @@ type EvaluationInfo struct {
 	InstallId      int64
 	UserSignatures []UserSignature
 }
+
+// ResignCampaign asks the signers of prior versions of a CLA document to sign CLAVersion. A signer is contacted if they
+// have an open PR waiting for a signature, or if they signed since ActiveSince.
+type ResignCampaign struct {
+	Id string `json:"id"`
+	// the document of the catalog, empty for the default CLA document
+	DocumentId  string          `json:"documentId,omitempty"`
+	CLAVersion  string          `json:"claVersion"`
+	ActiveSince time.Time       `json:"activeSince"`
+	StartedBy   string          `json:"startedBy"`
+	StartedAt   time.Time       `json:"startedAt"`
+	Contacts    []ResignContact `json:"contacts"`
+}
+
+// ResignContact is a signer of a prior CLA version contacted by a ResignCampaign
+type ResignContact struct {
+	Login            string `json:"login"`
+	Email            string `json:"email"`
+	GitHubUserId     int64  `json:"-"`
+	SignedCLAVersion string `json:"signedClaVersion"`
+	// nil until the email is sent, and if the email could not be sent, see Error
+	ContactedAt *time.Time `json:"contactedAt"`
+	Error       string     `json:"error,omitempty"`
+	// when the contact signed the CLA version of the campaign, nil if they did not sign it yet
+	ResignedAt *time.Time `json:"resignedAt"`
+}
+
+const SignatureSortSignedAt = "signedAt"
+const SignatureSortLogin = "login"
+const SignatureSortCLAVersion = "claVersion"
+
+// SignatureFilter selects active (not revoked) signatures, and revoked signatures too if IncludeRevoked is set. Fields
+// that are not set do not filter.
+type SignatureFilter struct {
+	CLAVersion string
+	// inclusive
+	SignedAfter *time.Time
+	// exclusive
+	SignedBefore *time.Time
+	// logins are matched case-insensitively
+	LoginPrefix string
+	// the domain of the email of the signer, e.g. "sonatype.com"
+	EmailDomain    string
+	IncludeRevoked bool
+}
+
+// SignatureQuery reads a page of the signatures matching the filter. Cursor is the NextCursor of the previous page,
+// and must be used with the same sort order.
+type SignatureQuery struct {
+	SignatureFilter
+	SortBy     string
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+// SignaturePage is a page of signatures. NextCursor is empty on the last page.
+type SignaturePage struct {
+	Signatures []UserSignature `json:"signatures"`
+	TotalCount int64           `json:"totalCount"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+}
+
+const ImportStatusInvalid = "invalid"
+const ImportStatusConflict = "conflict"
+
+// ImportProblem is a signature of an import that was not imported
+type ImportProblem struct {
+	// the 1-based position of the signature in the import, not counting the header of a CSV
+	Row        int    `json:"row"`
+	Login      string `json:"login"`
+	CLAVersion string `json:"claVersion"`
+	// ImportStatusInvalid, or ImportStatusConflict if the login already signed the CLA version
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the outcome of an import of signatures. Each batch of signatures is imported in its own
+// transaction, so if a batch fails, the batches before it stay imported, and Error tells why the import stopped.
+type ImportReport struct {
+	DryRun bool `json:"dryRun"`
+	Rows   int  `json:"rows"`
+	// in a dry run, the signatures that would be imported
+	Imported  int             `json:"imported"`
+	Conflicts int             `json:"conflicts"`
+	Invalid   int             `json:"invalid"`
+	Problems  []ImportProblem `json:"problems"`
+	Error     string          `json:"error,omitempty"`
+}
@@ -107,6 +107,12 @@ variable "env_smtp_port" {
   type = string
 }
 
+variable "env_trusted_proxies" {
+  description = "See TRUSTED_PROXIES"
+  type = string
+  default = ""
+}
+
 variable "env_smtp_username" {
   description = "See SMTP_USERNAME"
   type = string